@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// With adaptivePublish (ADAPTIVE_PUBLISH) each game publishes as often as its
+// odds are moving: after a publish the game waits between adaptiveMinInterval
+// (ADAPTIVE_MIN_INTERVAL) and adaptiveMaxInterval (ADAPTIVE_MAX_INTERVAL),
+// shorter the more its odds changed since the previous publish.
+// adaptiveSensitivity (ADAPTIVE_SENSITIVITY) is how much change counts as a
+// lot: the minimum interval is reached once the average relative move times
+// the sensitivity reaches 1, e.g. a 5% move at the default 20.
+var (
+	adaptivePublish     bool
+	adaptiveMinInterval = defaultPublishInterval
+	adaptiveMaxInterval = 2 * time.Second
+	adaptiveSensitivity = 20.0
+)
+
+// adaptiveDue reports whether a game's adaptive wait is over
+func adaptiveDue(game *Game, now int64) bool {
+	return !adaptivePublish || now >= game.nextAdaptiveAt
+}
+
+// scheduleAdaptive sets a game's next adaptive publish from how far its odds
+// moved since the last one. Call under the registry lock after publishing.
+func (g *Game) scheduleAdaptive() {
+	if !adaptivePublish {
+		return
+	}
+
+	var change float64
+	if g.publishedOdds != nil && len(g.Markets) > 0 {
+		for name, m := range g.Markets {
+			if prev := g.publishedOdds[name]; prev > 0 {
+				change += math.Abs(m.Odds-prev) / prev
+			}
+		}
+		change /= float64(len(g.Markets))
+	} else {
+		g.publishedOdds = make(map[string]float64, len(g.Markets))
+	}
+	for name, m := range g.Markets {
+		g.publishedOdds[name] = m.Odds
+	}
+
+	speed := min(1, change*adaptiveSensitivity)
+	delay := adaptiveMaxInterval - time.Duration(speed*float64(adaptiveMaxInterval-adaptiveMinInterval))
+	g.nextAdaptiveAt = g.LastUpdated + delay.Milliseconds()
+}
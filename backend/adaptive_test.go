@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptivePublishFollowsVolatility(t *testing.T) {
+	previousPublish, previousMin, previousMax, previousSensitivity := adaptivePublish, adaptiveMinInterval, adaptiveMaxInterval, adaptiveSensitivity
+	adaptivePublish, adaptiveMinInterval, adaptiveMaxInterval, adaptiveSensitivity = true, 100*time.Millisecond, 2*time.Second, 20
+	t.Cleanup(func() {
+		adaptivePublish, adaptiveMinInterval, adaptiveMaxInterval, adaptiveSensitivity = previousPublish, previousMin, previousMax, previousSensitivity
+	})
+
+	// Publishes over 20s of 100ms ticks, moving every market by move each time
+	publishes := func(move float64) int {
+		game := testGame()
+		n := 0
+		for now := int64(0); now < 20000; now += 100 {
+			if !adaptiveDue(game, now) {
+				continue
+			}
+			for name, m := range game.Markets {
+				game.setOdds(name, m.Odds*(1+move), now)
+			}
+			game.LastUpdated = now
+			game.scheduleAdaptive()
+			n++
+		}
+		return n
+	}
+
+	calm, volatile := publishes(0), publishes(0.05)
+	if volatile <= calm {
+		t.Errorf("volatile game published %d times, calm one %d, want the volatile one more often", volatile, calm)
+	}
+	// Each waits its interval: max with no change, min once the move saturates
+	if calm > 11 {
+		t.Errorf("calm game published %d times in 20s at a 2s max interval", calm)
+	}
+	if volatile < 150 {
+		t.Errorf("volatile game published %d times in 20s at a 100ms min interval", volatile)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// aggregateChannel, when set, carries a snapshot of every publishing game
+// each tick any of them updates (AGGREGATE_CHANNEL)
+var aggregateChannel string
+
+// Aggregate modes (AGGREGATE_MODE): every publishing game, or only the games
+// that changed since the previous aggregate publish
+const (
+	aggregateFull = "full"
+	aggregateDiff = "diff"
+)
+
+var aggregateMode = aggregateFull
+
+func validateAggregateMode(mode string) error {
+	switch mode {
+	case aggregateFull, aggregateDiff:
+		return nil
+	default:
+		return fmt.Errorf("unknown mode %q (expected full or diff)", mode)
+	}
+}
+
+// AggregateSnapshot is the all-games snapshot, featured games first. In diff
+// mode Games only holds the games that changed and Diff is set. Like every
+// payload it's tagged with the instance's provider and region, so a dashboard
+// merging several regions' aggregates can group games by where they came from.
+type AggregateSnapshot struct {
+	Games       []GameState `json:"games"`
+	Diff        bool        `json:"diff,omitempty"`
+	LastUpdated int64       `json:"lastUpdated"`
+}
+
+// publishAggregate publishes the all-games snapshot when anything updated
+func publishAggregate(updated []string) {
+	if aggregateChannel == "" || len(updated) == 0 {
+		return
+	}
+
+	changed := make(map[string]bool, len(updated))
+	for _, id := range updated {
+		changed[id] = true
+	}
+
+	list := []GameState{}
+	for _, game := range registry.Snapshot() {
+		if aggregateMode == aggregateDiff && !changed[game.ID] {
+			continue
+		}
+		if publishFilter.Allows(game.ID) {
+			list = append(list, game)
+		}
+	}
+	sortGames(list)
+
+	snapshot := AggregateSnapshot{Games: list, Diff: aggregateMode == aggregateDiff, LastUpdated: time.Now().UnixMilli()}
+	data, ok := encodeForPublish("aggregate snapshot", snapshot)
+	if !ok {
+		return
+	}
+	publishQueue.Enqueue(outbound{kind: kindAggregate, channel: aggregateChannel, data: data})
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// publishedAggregate publishes an aggregate for updated and decodes it
+func publishedAggregate(t *testing.T, queue *PublishQueue, updated []string) AggregateSnapshot {
+	t.Helper()
+	publishAggregate(updated)
+	messages := queue.drain()
+	if len(messages) != 1 || messages[0].kind != kindAggregate {
+		t.Fatalf("publishAggregate queued %d messages, want one aggregate", len(messages))
+	}
+	var env struct {
+		Type string            `json:"type"`
+		Data AggregateSnapshot `json:"data"`
+	}
+	if err := json.Unmarshal(messages[0].data, &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Type != typeAggregate {
+		t.Errorf("aggregate envelope type = %q, want %q", env.Type, typeAggregate)
+	}
+	return env.Data
+}
+
+func TestAggregateDiffModeOnlyHasChangedGames(t *testing.T) {
+	useGames(t)
+	queue := captureQueue(t)
+	aggregateChannel = "all-games"
+	defer func() { aggregateChannel, aggregateMode = "", aggregateFull }()
+
+	if full := publishedAggregate(t, queue, []string{"game2"}); len(full.Games) != 3 || full.Diff {
+		t.Errorf("full aggregate has %d games diff=%v, want all 3 and no diff flag", len(full.Games), full.Diff)
+	}
+
+	aggregateMode = aggregateDiff
+	diff := publishedAggregate(t, queue, []string{"game2"})
+	if len(diff.Games) != 1 || diff.Games[0].ID != "game2" || !diff.Diff {
+		t.Errorf("diff aggregate = %d games diff=%v, want just game2 flagged as a diff", len(diff.Games), diff.Diff)
+	}
+
+	publishAggregate(nil)
+	if messages := queue.drain(); len(messages) != 0 {
+		t.Errorf("a tick with no updates queued %d aggregates, want none", len(messages))
+	}
+}
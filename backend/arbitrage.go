@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+// With preventArbitrage (PREVENT_ARBITRAGE) a book that drifts below 100%
+// implied probability is repriced to arbitrageMinMargin (ARBITRAGE_MIN_MARGIN)
+// before it's published, so betting UIs never see a sure bet
+var (
+	preventArbitrage   bool
+	arbitrageMinMargin = 0.02
+)
+
+// restoreMargin shortens a game's 1X2 odds in proportion when they offer
+// arbitrage, reporting whether it had to. Prices are rounded down to the
+// tick, so the ladder never takes the book back under the margin. Call under
+// the registry lock.
+func restoreMargin(game *Game, now int64) bool {
+	if !preventArbitrage {
+		return false
+	}
+	implied := game.impliedProbability()
+	if implied >= 1 {
+		return false
+	}
+
+	// Scaling every price by the same factor keeps the outcomes' relative
+	// likelihoods and lands the book exactly on the target margin
+	factor := implied / (1 + arbitrageMinMargin)
+	for _, market := range marketNames {
+		if m, ok := game.Markets[market]; ok {
+			game.setOdds(market, snapDownToTick(m.Odds*factor, oddsTickSize), now)
+		}
+	}
+	atomic.AddInt64(&metrics.arbitragePrevented, 1)
+	if logEvents {
+		log.Printf("⚠️  %s: book at %.3f implied probability, repriced to %.3f", game.ID, implied, game.impliedProbability())
+	}
+	return true
+}
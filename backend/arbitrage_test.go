@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestRestoreMarginSnapsAndKeepsMargin(t *testing.T) {
+	preventArbitrage = true
+	oddsTickSize = 0.05
+	defer func() { preventArbitrage, oddsTickSize = false, 0 }()
+
+	// 1/3.0 + 1/3.1 + 1/3.3 is a 96% book, a sure bet
+	home, away, draw := 3.0, 3.1, 3.3
+	state := defaultGames()[0]
+	state.applyDefaults()
+	state.initMarkets()
+	GamePatch{HomeOdds: &home, AwayOdds: &away, DrawOdds: &draw}.apply(&state, 0)
+	game := newGame(state)
+
+	if !restoreMargin(game, 0) {
+		t.Fatal("restoreMargin = false for an arbitrage book")
+	}
+	checkOnTick(t, "restoreMargin", &game.GameState)
+	if p := game.impliedProbability(); p < 1+arbitrageMinMargin-1e-9 {
+		t.Errorf("implied probability %.4f after repricing, want at least %.2f", p, 1+arbitrageMinMargin)
+	}
+	if restoreMargin(game, 0) {
+		t.Error("restoreMargin repriced a book already at the margin")
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import "math/rand"
+
+// maxAttendance bounds configured crowds to something a stadium could hold
+const maxAttendance = 200000
+
+// With attendanceDrift (ATTENDANCE_DRIFT) late arrivals keep adding to a
+// game's configured attendance over the first lateArrivalMinutes, by at most
+// lateArrivalShare of the crowd in total
+var attendanceDrift bool
+
+const (
+	lateArrivalMinutes     = 20
+	lateArrivalShare       = 0.05
+	lateArrivalProbability = 0.05 // per tick, at real time
+)
+
+// attendanceCap is the most a game's crowd can grow to through late arrivals
+func attendanceCap(configured int) int {
+	return configured + int(float64(configured)*lateArrivalShare)
+}
+
+// attendanceGenerator lets late arrivals trickle in early in the match. It
+// produces no events of its own.
+type attendanceGenerator struct{}
+
+func (attendanceGenerator) Generate(game *Game, r *rand.Rand) []MatchEvent {
+	if game.Attendance == 0 || game.Minute >= lateArrivalMinutes || game.Attendance >= game.crowdCap {
+		return nil
+	}
+	if r.Float64() >= scaledProbability(lateArrivalProbability) {
+		return nil
+	}
+	arrivals := 1 + r.Intn(max(1, game.crowdCap/3000))
+	game.Attendance = min(game.crowdCap, game.Attendance+arrivals)
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestAttendanceDriftStaysWithinBounds(t *testing.T) {
+	state := defaultGames()[0]
+	state.Attendance, state.Minute = 40000, 0
+	state.applyDefaults()
+	state.initMarkets()
+	game := newGame(state)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 5000; i++ {
+		attendanceGenerator{}.Generate(game, r)
+		if game.Attendance < 40000 || game.Attendance > attendanceCap(40000) {
+			t.Fatalf("attendance %d outside [40000, %d]", game.Attendance, attendanceCap(40000))
+		}
+	}
+	if game.Attendance == 40000 {
+		t.Error("no late arrivals in the first minutes")
+	}
+
+	game.Minute = lateArrivalMinutes
+	settled := game.Attendance
+	for i := 0; i < 1000; i++ {
+		attendanceGenerator{}.Generate(game, r)
+	}
+	if game.Attendance != settled {
+		t.Errorf("attendance moved %d -> %d after minute %d", settled, game.Attendance, lateArrivalMinutes)
+	}
+
+	for _, attendance := range []int{-1, maxAttendance + 1} {
+		bad := defaultGames()[0]
+		bad.Attendance = attendance
+		if err := validateGame(&bad); err == nil {
+			t.Errorf("validateGame accepts attendance %d", attendance)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbesBypassAdminToken(t *testing.T) {
+	adminToken = "secret"
+	defer func() { adminToken = "" }()
+	handler := requireToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range []struct {
+		path, auth string
+		want       int
+	}{
+		{"/livez", "", http.StatusOK},
+		{"/readyz", "", http.StatusOK},
+		{"/health", "", http.StatusOK},
+		{"/games", "", http.StatusUnauthorized},
+		{"/games", "Bearer wrong", http.StatusUnauthorized},
+		{"/games", "Bearer secret", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("GET %s with %q = %d, want %d", tt.path, tt.auth, rec.Code, tt.want)
+		}
+	}
+}
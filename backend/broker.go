@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Publisher is the broker the feed publishes to. The publish path only
+// depends on this, so it doesn't care whether Redis is a single node or a
+// cluster.
+type Publisher interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	Ping(ctx context.Context) error
+}
+
+// LatestStore caches each game's most recently published payload, so late
+// joiners can start from the current state
+type LatestStore interface {
+	SetLatest(ctx context.Context, gameID string, data []byte) error
+	// GetLatest returns errNoLatest when nothing has been published yet
+	GetLatest(ctx context.Context, gameID string) ([]byte, error)
+}
+
+// Subscriber hands out live feeds of published channels, for /ws
+type Subscriber interface {
+	// Subscribe delivers messages on channels until close is called
+	Subscribe(ctx context.Context, channels []string) (messages <-chan brokerMessage, close func())
+	// Open starts a subscription to no channels, for Add and Remove to change
+	Open(ctx context.Context) Subscription
+}
+
+// Subscription is a live feed whose channels change while it runs, so one
+// broker subscription can serve every /ws connection
+type Subscription interface {
+	Messages() <-chan brokerMessage
+	Add(channels ...string) error
+	Remove(channels ...string) error
+	Close()
+}
+
+// brokerMessage is one payload received on a channel
+type brokerMessage struct {
+	Channel string
+	Payload []byte
+}
+
+var errNoLatest = errors.New("no published state")
+
+// redisPublisher publishes through a single-node or cluster Redis client
+type redisPublisher struct {
+	client redis.UniversalClient
+}
+
+func (p *redisPublisher) Publish(ctx context.Context, channel string, payload []byte) error {
+	return p.client.Publish(ctx, channel, payload).Err()
+}
+
+// Ping checks the connection; on a cluster every shard must answer
+func (p *redisPublisher) Ping(ctx context.Context) error {
+	if cluster, ok := p.client.(*redis.ClusterClient); ok {
+		return cluster.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+			return shard.Ping(ctx).Err()
+		})
+	}
+	return p.client.Ping(ctx).Err()
+}
+
+func (p *redisPublisher) SetLatest(ctx context.Context, gameID string, data []byte) error {
+	return p.client.Set(ctx, latestKey(gameID), data, 0).Err()
+}
+
+func (p *redisPublisher) GetLatest(ctx context.Context, gameID string) ([]byte, error) {
+	data, err := p.client.Get(ctx, latestKey(gameID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, errNoLatest
+	}
+	return data, err
+}
+
+func (p *redisPublisher) Subscribe(ctx context.Context, channels []string) (<-chan brokerMessage, func()) {
+	sub := newRedisSubscription(ctx, p.client.Subscribe(ctx, channels...))
+	return sub.messages, sub.Close
+}
+
+func (p *redisPublisher) Open(ctx context.Context) Subscription {
+	return newRedisSubscription(ctx, p.client.Subscribe(ctx))
+}
+
+// redisSubscription is one Pub/Sub connection, its channels changed with
+// SUBSCRIBE and UNSUBSCRIBE as it runs
+type redisSubscription struct {
+	ctx      context.Context
+	pubsub   *redis.PubSub
+	messages chan brokerMessage
+	done     chan struct{}
+	once     sync.Once
+}
+
+func newRedisSubscription(ctx context.Context, pubsub *redis.PubSub) *redisSubscription {
+	sub := &redisSubscription{ctx: ctx, pubsub: pubsub, messages: make(chan brokerMessage, 64), done: make(chan struct{})}
+	// done unblocks the forwarder when the reader has gone away with the
+	// buffer full, so closing never leaves it stuck on the send. It then
+	// drains the client's channel until Close ends it, or go-redis's own
+	// reader would sit on a full buffer for its send timeout.
+	go func() {
+		defer close(sub.messages)
+		incoming := pubsub.Channel()
+		for msg := range incoming {
+			select {
+			case sub.messages <- brokerMessage{Channel: msg.Channel, Payload: []byte(msg.Payload)}:
+			case <-sub.done:
+				for range incoming {
+				}
+				return
+			}
+		}
+	}()
+	return sub
+}
+
+func (s *redisSubscription) Messages() <-chan brokerMessage { return s.messages }
+
+func (s *redisSubscription) Add(channels ...string) error {
+	return s.pubsub.Subscribe(s.ctx, channels...)
+}
+
+func (s *redisSubscription) Remove(channels ...string) error {
+	return s.pubsub.Unsubscribe(s.ctx, channels...)
+}
+
+func (s *redisSubscription) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.pubsub.Close()
+	})
+}
+
+// newRedisClient builds a single-node client from REDIS_URL, or a cluster
+// client from REDIS_ADDRS when REDIS_CLUSTER=true
+func newRedisClient() (redis.UniversalClient, string) {
+	// Pool settings (defaults match go-redis)
+	poolSize := envInt("REDIS_POOL_SIZE", 10*runtime.GOMAXPROCS(0))
+	minIdleConns := envInt("REDIS_MIN_IDLE_CONNS", 0)
+	dialTimeout := envDuration("REDIS_DIAL_TIMEOUT", 5*time.Second)
+	if poolSize <= 0 || minIdleConns < 0 || dialTimeout <= 0 {
+		log.Fatalf("Invalid Redis pool settings: poolSize=%d minIdleConns=%d dialTimeout=%s", poolSize, minIdleConns, dialTimeout)
+	}
+	log.Printf("Redis pool: size=%d minIdleConns=%d dialTimeout=%s", poolSize, minIdleConns, dialTimeout)
+
+	if envBool("REDIS_CLUSTER", false) {
+		addrs := envList("REDIS_ADDRS")
+		if len(addrs) == 0 {
+			log.Fatal("REDIS_CLUSTER=true requires REDIS_ADDRS (comma-separated host:port list)")
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        addrs,
+			Password:     "", // no password
+			PoolSize:     poolSize,
+			MinIdleConns: minIdleConns,
+			DialTimeout:  dialTimeout,
+		}), "cluster " + strings.Join(addrs, ",")
+	}
+
+	redisAddr := envString("REDIS_URL", "localhost:6379")
+	return redis.NewClient(&redis.Options{
+		Addr:         redisAddr,
+		Password:     "", // no password
+		DB:           0,  // default DB
+		PoolSize:     poolSize,
+		MinIdleConns: minIdleConns,
+		DialTimeout:  dialTimeout,
+	}), redisAddr
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// waitFor polls cond until it holds, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<20)
+			t.Fatalf("timed out waiting for %s\n%s", what, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// waitForGoroutines waits for the goroutine count to drop back to baseline
+func waitForGoroutines(t *testing.T, baseline int) {
+	t.Helper()
+	waitFor(t, fmt.Sprintf("goroutines to return to %d", baseline), func() bool {
+		return runtime.NumGoroutine() <= baseline
+	})
+}
+
+// redisBroker is a redisPublisher on a fresh miniredis server
+func redisBroker(t *testing.T) (*miniredis.Miniredis, *redisPublisher) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return server, &redisPublisher{client: client}
+}
+
+func TestInprocSubscribeReleasesSubscriptions(t *testing.T) {
+	broker := newInprocBroker()
+	baseline := runtime.NumGoroutine()
+
+	for i := 0; i < 50; i++ {
+		_, closeSub := broker.Subscribe(context.Background(), []string{"game1", "league:la-liga"})
+		if got := broker.Subscriptions(); got != 2 {
+			t.Fatalf("Subscriptions() = %d while subscribed, want 2", got)
+		}
+		for j := 0; j < 100; j++ {
+			broker.Publish(context.Background(), "game1", []byte("{}"))
+		}
+		closeSub()
+		closeSub()
+	}
+
+	if got := broker.Subscriptions(); got != 0 {
+		t.Errorf("Subscriptions() = %d after closing, want 0", got)
+	}
+	waitForGoroutines(t, baseline)
+}
+
+// subscribeAndAbandon subscribes, fills every buffer on the way without
+// reading, then closes: the forwarders are parked on a full send at that point
+func subscribeAndAbandon(t *testing.T, server *miniredis.Miniredis, sub Subscriber, channels []string) {
+	t.Helper()
+	messages, closeSub := sub.Subscribe(context.Background(), channels)
+	for _, channel := range channels {
+		channel := channel
+		waitFor(t, channel+" subscription", func() bool {
+			return server.PubSubNumSub(channel)[channel] == 1
+		})
+	}
+	for j := 0; j < 300; j++ {
+		for _, channel := range channels {
+			server.Publish(channel, "{}")
+		}
+	}
+	waitFor(t, "a full subscriber buffer", func() bool { return len(messages) == cap(messages) })
+	closeSub()
+
+	for _, channel := range channels {
+		channel := channel
+		waitFor(t, channel+" unsubscribe", func() bool {
+			return server.PubSubNumSub(channel)[channel] == 0
+		})
+	}
+}
+
+func TestRedisSubscribeCloseReleasesGoroutines(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	pub := &redisPublisher{client: client}
+	if err := pub.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	baseline := runtime.NumGoroutine()
+
+	for i := 0; i < 20; i++ {
+		subscribeAndAbandon(t, server, pub, []string{"game1"})
+	}
+	waitForGoroutines(t, baseline)
+}
+
+func TestRedisSubscriptionChangesChannelsAsItRuns(t *testing.T) {
+	server, pub := redisBroker(t)
+	sub := pub.Open(context.Background())
+	defer sub.Close()
+
+	if err := sub.Add("game1", "game2"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the added channels", func() bool { return len(server.PubSubChannels("")) == 2 })
+	if err := sub.Remove("game2"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the removed channel to drop", func() bool { return len(server.PubSubChannels("")) == 1 })
+
+	server.Publish("game2", "{}")
+	server.Publish("game1", `{"n":1}`)
+	select {
+	case msg := <-sub.Messages():
+		if msg.Channel != "game1" || string(msg.Payload) != `{"n":1}` {
+			t.Errorf("got %s %s, want the game1 message only", msg.Channel, msg.Payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message on the added channel")
+	}
+}
+
+// routedBroker is a routedPublisher sending la-liga to DB 1 on a fresh
+// miniredis server
+func routedBroker(t *testing.T) (*miniredis.Miniredis, *routedPublisher) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	base := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	routes, err := parseDBRoutes([]string{"league:la-liga=1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := newRoutedPublisher(base, routes)
+	t.Cleanup(func() { base.Close(); pub.byDB[1].client.Close() })
+	return server, pub
+}
+
+func TestRoutedPublisherRoutesOnlyKeyedData(t *testing.T) {
+	useGames(t)
+	server, pub := routedBroker(t)
+	game3, _ := registry.Get("game3")
+	channel := gameChannel(&game3)
+
+	messages, closeSub := pub.Subscribe(context.Background(), []string{channel})
+	defer closeSub()
+	waitFor(t, "the subscription", func() bool { return server.PubSubNumSub(channel)[channel] == 1 })
+
+	batch := []outbound{{kind: kindState, gameID: "game3", channel: channel, data: []byte(`{"id":"game3"}`)}}
+	for _, err := range pub.PublishBatch(context.Background(), batch) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case msg := <-messages:
+		if string(msg.Payload) != `{"id":"game3"}` {
+			t.Errorf("got %s on %s", msg.Payload, channel)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the la-liga publish didn't reach a subscriber on the default client")
+	}
+
+	server.Select(1)
+	if got, _ := server.Get(latestKey("game3")); got != `{"id":"game3"}` {
+		t.Errorf("DB 1 %s = %q, want the published state", latestKey("game3"), got)
+	}
+	server.Select(0)
+	if server.Exists(latestKey("game3")) {
+		t.Errorf("%s also written to the default DB", latestKey("game3"))
+	}
+
+	counts, err := pub.NumSub(context.Background(), []string{channel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[channel] != 1 {
+		t.Errorf("NumSub(%s) = %d, want 1", channel, counts[channel])
+	}
+}
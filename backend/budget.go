@@ -0,0 +1,39 @@
+package main
+
+import "log"
+
+// maxPublishesPerGame caps the states each game publishes
+// (MAX_PUBLISHES_PER_GAME), so a bounded test run emits a known number of
+// messages; 0 is unlimited. A game's last allowed publish is its final state.
+var maxPublishesPerGame int
+
+// remainingPublishes is how many more states a game may publish, -1 when
+// there's no budget. Call under the registry lock.
+func (g *Game) remainingPublishes() int {
+	if maxPublishesPerGame <= 0 {
+		return -1
+	}
+	return max(0, maxPublishesPerGame-g.publishes)
+}
+
+// endOnBudget ends a game whose next publish is the last its budget allows,
+// returning the ended event. Call under the registry lock.
+func endOnBudget(game *Game, now int64) (MatchEvent, bool) {
+	if maxPublishesPerGame <= 0 || game.remainingPublishes() > 1 {
+		return MatchEvent{}, false
+	}
+
+	game.Status = statusEnded
+	game.endedAt = now
+	game.LastUpdated = now
+	log.Printf("🏁 %s: publish budget of %d spent, ending at %d-%d", game.ID, maxPublishesPerGame, game.HomeScore, game.AwayScore)
+
+	return MatchEvent{
+		GameID:    game.ID,
+		Type:      eventEnded,
+		HomeScore: game.HomeScore,
+		AwayScore: game.AwayScore,
+		Minute:    game.Minute,
+		Timestamp: now,
+	}, true
+}
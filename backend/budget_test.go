@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestPublishBudgetEndsEachGame(t *testing.T) {
+	useGames(t)
+	queue := captureQueue(t)
+	previousBudget, previousProb := maxPublishesPerGame, gameUpdateProb
+	maxPublishesPerGame, gameUpdateProb = 3, 1
+	t.Cleanup(func() { maxPublishesPerGame, gameUpdateProb = previousBudget, previousProb })
+
+	states := map[string]int{}
+	for i := 0; i < 10; i++ {
+		publishTick()
+		for _, m := range queue.drain() {
+			if m.kind == kindState {
+				states[m.gameID]++
+			}
+		}
+	}
+
+	for _, state := range registry.Snapshot() {
+		if got := states[state.ID]; got != 3 {
+			t.Errorf("%s published %d states with a budget of 3", state.ID, got)
+		}
+		if state.Status != statusEnded {
+			t.Errorf("%s status = %s after spending its budget, want %s", state.ID, state.Status, statusEnded)
+		}
+	}
+}
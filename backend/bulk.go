@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Bulk create modes (?mode=): strict creates every game or none, besteffort
+// creates the valid ones and reports the rest
+const (
+	bulkStrict     = "strict"
+	bulkBestEffort = "besteffort"
+)
+
+// BulkError reports why one game of a bulk create was rejected
+type BulkError struct {
+	Index int    `json:"index"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// BulkResult is the response of a bulk create
+type BulkResult struct {
+	Created []GameState `json:"created"`
+	Errors  []BulkError `json:"errors"`
+}
+
+// POST /games/bulk registers an array of games. In strict mode (the default)
+// any invalid game fails the whole batch with 400; with ?mode=besteffort the
+// valid games are created and the invalid ones reported, with 207 when the
+// batch was only partly created.
+func handleBulkCreateGames(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = bulkStrict
+	}
+	if mode != bulkStrict && mode != bulkBestEffort {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown mode %q (expected strict or besteffort)", mode))
+		return
+	}
+
+	var states []GameState
+	if err := decodeJSONBody(w, r, &states); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(states) == 0 {
+		writeError(w, http.StatusBadRequest, "request body must be a non-empty array of games")
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	result := BulkResult{Created: []GameState{}, Errors: []BulkError{}}
+	seen := make(map[string]bool, len(states))
+	valid := make([]int, 0, len(states))
+	for i := range states {
+		game := &states[i]
+		if err := validateGame(game); err != nil {
+			result.Errors = append(result.Errors, BulkError{Index: i, ID: game.ID, Error: err.Error()})
+			continue
+		}
+		if seen[game.ID] {
+			result.Errors = append(result.Errors, BulkError{Index: i, ID: game.ID, Error: "duplicate id in batch"})
+			continue
+		}
+		seen[game.ID] = true
+
+		game.LastUpdated = now
+		game.applyDefaults()
+		game.initMarkets()
+		valid = append(valid, i)
+	}
+
+	// Existing IDs are checked under the same lock the games are created in
+	registry.Batch(func(games map[string]*Game) {
+		accepted := valid[:0]
+		matchups := map[string]string{} // accepted so far in this batch
+		for _, i := range valid {
+			if _, exists := games[states[i].ID]; exists {
+				result.Errors = append(result.Errors, BulkError{Index: i, ID: states[i].ID, Error: "game already exists: " + states[i].ID})
+				continue
+			}
+			id, ok := matchups[matchupKey(&states[i])]
+			if !ok {
+				id, ok = existingMatchup(games, &states[i])
+			}
+			if ok {
+				if strictConfig {
+					result.Errors = append(result.Errors, BulkError{Index: i, ID: states[i].ID, Error: "same matchup as " + id})
+					continue
+				}
+				log.Printf("⚠️  WARN: duplicate matchup for %s: same as %s (%s v %s)", states[i].ID, id, states[i].HomeTeam, states[i].AwayTeam)
+			}
+			matchups[matchupKey(&states[i])] = states[i].ID
+			accepted = append(accepted, i)
+		}
+		sort.Slice(result.Errors, func(i, j int) bool { return result.Errors[i].Index < result.Errors[j].Index })
+		if mode == bulkStrict && len(result.Errors) > 0 {
+			return
+		}
+		for _, i := range accepted {
+			games[states[i].ID] = newGame(states[i])
+			result.Created = append(result.Created, states[i].clone())
+		}
+	})
+	if mode == bulkStrict && len(result.Errors) > 0 {
+		writeJSON(w, http.StatusBadRequest, result)
+		return
+	}
+
+	status := http.StatusCreated
+	switch {
+	case len(result.Created) == 0:
+		status = http.StatusBadRequest
+	case len(result.Errors) > 0:
+		status = http.StatusMultiStatus
+	}
+	writeJSON(w, status, result)
+}
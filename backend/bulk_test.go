@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestBulkCreateReportsPartialFailures(t *testing.T) {
+	body := `[
+		{"id":"new1","homeTeam":"Ajax","awayTeam":"PSV","homeOdds":2,"awayOdds":3,"drawOdds":3.2},
+		{"id":"new2","homeTeam":"Porto","awayTeam":""},
+		{"id":"game1","homeTeam":"Lazio","awayTeam":"Roma","homeOdds":2,"awayOdds":3,"drawOdds":3.2}
+	]`
+	tests := []struct {
+		mode       string
+		wantStatus int
+		wantGames  int
+	}{
+		{"", http.StatusBadRequest, 3},
+		{bulkBestEffort, http.StatusMultiStatus, 4},
+	}
+	for _, tt := range tests {
+		t.Run("mode="+tt.mode, func(t *testing.T) {
+			useGames(t)
+			rec := serve(handleGameRoutes, http.MethodPost, "/games/bulk?mode="+tt.mode, body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("POST /games/bulk = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			var result BulkResult
+			if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Errors) != 2 || result.Errors[0].Index != 1 || result.Errors[1].Index != 2 {
+				t.Errorf("errors = %+v, want entries 1 and 2 in order", result.Errors)
+			}
+			if n := registry.Len(); n != tt.wantGames {
+				t.Errorf("registry has %d games, want %d", n, tt.wantGames)
+			}
+		})
+	}
+}
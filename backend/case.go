@@ -0,0 +1,78 @@
+package main
+
+import "fmt"
+
+// Output cases (OUTPUT_CASE) for the field names of published payloads and
+// REST responses. Requests are always read in camelCase.
+const (
+	caseCamel = "camel"
+	caseSnake = "snake"
+)
+
+var outputCase = caseCamel
+
+func validateOutputCase(c string) error {
+	switch c {
+	case caseCamel, caseSnake:
+		return nil
+	default:
+		return fmt.Errorf("unknown case %q (expected camel or snake)", c)
+	}
+}
+
+// withOutputCase rewrites the object keys of encoded JSON into outputCase,
+// e.g. "homeOdds" to "home_odds" for snake. Values are left alone, and so is
+// anything that isn't valid JSON.
+func withOutputCase(data []byte) []byte {
+	if outputCase == caseCamel {
+		return data
+	}
+
+	out := make([]byte, 0, len(data)+len(data)/8)
+	for i := 0; i < len(data); i++ {
+		if data[i] != '"' {
+			out = append(out, data[i])
+			continue
+		}
+
+		// Find the end of the string, skipping escapes
+		end := i + 1
+		for end < len(data) && data[end] != '"' {
+			if data[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(data) {
+			return data
+		}
+
+		// Strings followed by a colon are keys
+		next := end + 1
+		for next < len(data) && (data[next] == ' ' || data[next] == '\t' || data[next] == '\n' || data[next] == '\r') {
+			next++
+		}
+		if next < len(data) && data[next] == ':' {
+			out = appendSnake(out, data[i:end+1])
+		} else {
+			out = append(out, data[i:end+1]...)
+		}
+		i = end
+	}
+	return out
+}
+
+// appendSnake appends a camelCase key with an underscore before, and
+// lowercasing, every ASCII capital that follows a lowercase letter or digit
+func appendSnake(out, key []byte) []byte {
+	for j, c := range key {
+		if c >= 'A' && c <= 'Z' {
+			if prev := key[j-1]; (prev >= 'a' && prev <= 'z') || (prev >= '0' && prev <= '9') {
+				out = append(out, '_')
+			}
+			c += 'a' - 'A'
+		}
+		out = append(out, c)
+	}
+	return out
+}
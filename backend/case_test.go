@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestWithOutputCaseRewritesKeysOnly(t *testing.T) {
+	previous := outputCase
+	t.Cleanup(func() { outputCase = previous })
+
+	tests := []struct {
+		in, want string
+	}{
+		{`{"homeOdds":2.5,"ttlMs":300}`, `{"home_odds":2.5,"ttl_ms":300}`},
+		{`{"markets":{"home":{"lastUpdated":1}}}`, `{"markets":{"home":{"last_updated":1}}}`},
+		{`{"homeTeam" : "Man United","awayTeam":"WestHam"}`, `{"home_team" : "Man United","away_team":"WestHam"}`},
+		{`{"note":"say \"homeOdds\": 2","player2Name":"x"}`, `{"note":"say \"homeOdds\": 2","player2_name":"x"}`},
+		{`["homeOdds","awayOdds"]`, `["homeOdds","awayOdds"]`},
+		{`{"homeOdds`, `{"homeOdds`},
+	}
+
+	outputCase = caseSnake
+	for _, tt := range tests {
+		if got := string(withOutputCase([]byte(tt.in))); got != tt.want {
+			t.Errorf("withOutputCase(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	outputCase = caseCamel
+	if got := string(withOutputCase([]byte(tests[0].in))); got != tests[0].in {
+		t.Errorf("camel case rewrote %s to %s", tests[0].in, got)
+	}
+}
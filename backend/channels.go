@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// channelCounts tracks successful publishes per Redis channel
+var channelCounts = struct {
+	sync.Mutex
+	counts map[string]int64
+}{counts: map[string]int64{}}
+
+func countChannelPublish(channel string) {
+	channelCounts.Lock()
+	channelCounts.counts[channel]++
+	channelCounts.Unlock()
+}
+
+func channelPublishes(channel string) int64 {
+	channelCounts.Lock()
+	defer channelCounts.Unlock()
+	return channelCounts.counts[channel]
+}
+
+// ChannelInfo describes a channel a subscriber can listen to
+type ChannelInfo struct {
+	Channel   string `json:"channel"`
+	Kind      string `json:"kind"`
+	GameID    string `json:"gameId,omitempty"`
+	League    string `json:"league,omitempty"`
+	Tier      string `json:"tier,omitempty"`
+	Publishes int64  `json:"publishes"`
+}
+
+// activeChannels lists every channel the publisher currently emits on,
+// derived from the publishing games under the registry lock
+func activeChannels() []ChannelInfo {
+	list := []ChannelInfo{}
+	leagues := map[string]bool{}
+	tiers := map[string]bool{}
+	registry.Read(func(games map[string]*Game) {
+		for _, game := range games {
+			if !publishFilter.Allows(game.ID) {
+				continue
+			}
+			channel := gameChannel(&game.GameState)
+			list = append(list, ChannelInfo{Channel: channel, Kind: "game", GameID: game.ID})
+			if delayedFeed != nil {
+				list = append(list, ChannelInfo{Channel: delayedChannel(channel), Kind: "delayed", GameID: game.ID})
+			}
+			if eventsFanIn == "" {
+				list = append(list, ChannelInfo{Channel: eventsChannel(channel), Kind: "events", GameID: game.ID})
+			}
+			if game.League != "" {
+				leagues[game.League] = true
+			}
+			if game.Tier != "" {
+				tiers[game.Tier] = true
+			}
+		}
+	})
+
+	if eventsFanIn != "" {
+		list = append(list, ChannelInfo{Channel: eventsFanIn, Kind: "events"})
+	}
+	if aggregateChannel != "" {
+		list = append(list, ChannelInfo{Channel: aggregateChannel, Kind: "aggregate"})
+	}
+	for league := range leagues {
+		list = append(list, ChannelInfo{Channel: leagueChannel(league), Kind: "league", League: league})
+	}
+	for tier := range tiers {
+		list = append(list, ChannelInfo{Channel: tierChannel(tier), Kind: "tier", Tier: tier})
+	}
+	for i := range list {
+		list[i].Publishes = channelPublishes(list[i].Channel)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Channel < list[j].Channel })
+	return list
+}
+
+// GET /channels
+func handleChannels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, activeChannels())
+}
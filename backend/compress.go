@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec bytes prefixed to compressed payloads. Uncompressed payloads are sent
+// as plain JSON (always starting with '{'), so subscribers can tell them apart.
+const (
+	codecGzip byte = 0x01
+	codecZstd byte = 0x02
+)
+
+// Compressor encodes outgoing payloads using the configured codec
+type Compressor struct {
+	codec string
+	zstd  *zstd.Encoder
+}
+
+// newCompressor builds a compressor for PUBLISH_COMPRESSION=gzip|zstd|none
+func newCompressor(codec string) (*Compressor, error) {
+	c := &Compressor{codec: strings.ToLower(codec)}
+
+	switch c.codec {
+	case "", "none":
+		c.codec = "none"
+	case "gzip":
+	case "zstd":
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		if err != nil {
+			return nil, err
+		}
+		c.zstd = enc
+	default:
+		return nil, fmt.Errorf("unknown codec %q (expected gzip, zstd or none)", codec)
+	}
+
+	return c, nil
+}
+
+// Encode compresses data and prefixes the codec byte; "none" is a passthrough
+func (c *Compressor) Encode(data []byte) ([]byte, error) {
+	switch c.codec {
+	case "gzip":
+		var buf bytes.Buffer
+		buf.WriteByte(codecGzip)
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case "zstd":
+		return c.zstd.EncodeAll(data, []byte{codecZstd}), nil
+	default:
+		return data, nil
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// encodedState is game1's state as published, before compression
+func encodedState(tb testing.TB) []byte {
+	tb.Helper()
+	game := testGame()
+	game.Tags = []string{"derby", "tv"}
+	game.Stats = map[string]float64{"possessionHome": 55, "possessionAway": 45, "shotsHome": 7, "shotsAway": 4}
+	data, err := encodeMessage(game, 1)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
+
+func TestCompressorRoundTrip(t *testing.T) {
+	data := encodedState(t)
+	for _, codec := range []string{"gzip", "zstd"} {
+		c, err := newCompressor(codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := c.Encode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded []byte
+		switch encoded[0] {
+		case codecGzip:
+			zr, err := gzip.NewReader(bytes.NewReader(encoded[1:]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err = io.ReadAll(zr)
+			if err != nil {
+				t.Fatal(err)
+			}
+		case codecZstd:
+			dec, _ := zstd.NewReader(nil)
+			decoded, err = dec.DecodeAll(encoded[1:], nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+		default:
+			t.Fatalf("%s payload starts with %#x, want its codec byte", codec, encoded[0])
+		}
+		if !bytes.Equal(decoded, data) {
+			t.Errorf("%s round trip changed the payload", codec)
+		}
+	}
+}
+
+func benchmarkCompress(b *testing.B, codec string) {
+	data := encodedState(b)
+	c, err := newCompressor(codec)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var size int
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		encoded, err := c.Encode(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = len(encoded)
+	}
+	b.ReportMetric(float64(size), "bytes/msg")
+	b.ReportMetric(float64(len(data))/float64(size), "ratio")
+}
+
+func BenchmarkCompressGzip(b *testing.B) { benchmarkCompress(b, "gzip") }
+
+func BenchmarkCompressZstd(b *testing.B) { benchmarkCompress(b, "zstd") }
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// profiles are named sets of env var defaults (PROFILE). Anything set in the
+// environment still wins over the profile.
+var profiles = map[string]map[string]string{
+	"demo": {
+		"PUBLISH_INTERVAL": "1s",
+		"TIER_INTERVALS":   "premium=1s,standard=5s",
+		"SEED_DUMMY_DATA":  "true",
+		"LOG_EVENTS":       "true",
+		"STAGGER_START":    "true",
+	},
+	"loadtest": {
+		"PUBLISH_INTERVAL":   "50ms",
+		"SEED_DUMMY_DATA":    "false",
+		"LOG_EVENTS":         "false",
+		"PUBLISH_QUEUE_SIZE": "8192",
+		"BACKPRESSURE":       policyDropOldest,
+		"NUM_GAMES":          "200",
+		"PUBLISH_WORKERS":    "4",
+	},
+	"prod": {
+		"SEED_DUMMY_DATA": "false",
+		"DEDUP_PUBLISHES": "true",
+		"DEBUG_ENDPOINTS": "false",
+	},
+}
+
+// activeProfile holds the defaults of the selected profile, if any
+var (
+	activeProfile     map[string]string
+	activeProfileName string
+)
+
+// loadProfile selects the PROFILE defaults; call before reading any config
+func loadProfile() (string, error) {
+	name := os.Getenv("PROFILE")
+	if name == "" {
+		return "", nil
+	}
+	defaults, ok := profiles[name]
+	if !ok {
+		return "", fmt.Errorf("unknown profile %q (expected demo, loadtest or prod)", name)
+	}
+	activeProfile, activeProfileName = defaults, name
+	return name, nil
+}
+
+// ConfigSetting is one resolved setting and where its value came from
+// ("env", "profile" or "default")
+type ConfigSetting struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// effectiveConfig records every setting read through the env helpers, for
+// the startup log and GET /config
+var effectiveConfig = struct {
+	sync.Mutex
+	values map[string]ConfigSetting
+}{values: map[string]ConfigSetting{}}
+
+// getenv reads key from the environment, then the active profile, and
+// reports which one supplied it ("env", "profile" or "default")
+func getenv(key string) (string, string) {
+	if v := os.Getenv(key); v != "" {
+		return v, "env"
+	}
+	if v := activeProfile[key]; v != "" {
+		return v, "profile"
+	}
+	return "", "default"
+}
+
+func noteConfig(key string, value interface{}, source string) {
+	effectiveConfig.Lock()
+	effectiveConfig.values[key] = ConfigSetting{Value: fmt.Sprint(value), Source: source}
+	effectiveConfig.Unlock()
+}
+
+// redactedSettings is every resolved setting with secrets hidden: keys,
+// tokens and passwords, and the password in a URL's credentials
+func redactedSettings() map[string]ConfigSetting {
+	effectiveConfig.Lock()
+	defer effectiveConfig.Unlock()
+
+	settings := make(map[string]ConfigSetting, len(effectiveConfig.values))
+	for key, setting := range effectiveConfig.values {
+		if strings.Contains(key, "KEY") || strings.Contains(key, "TOKEN") || strings.Contains(key, "PASSWORD") {
+			setting.Value = "[redacted]"
+		} else if u, err := url.Parse(setting.Value); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), "redacted")
+				setting.Value = u.String()
+			}
+		}
+		settings[key] = setting
+	}
+	return settings
+}
+
+// logEffectiveConfig logs every resolved setting, sorted, hiding secrets
+func logEffectiveConfig() {
+	settings := redactedSettings()
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	log.Println("Effective configuration:")
+	for _, key := range keys {
+		log.Printf("  %s=%s (%s)", key, settings[key].Value, settings[key].Source)
+	}
+}
+
+// GET /config returns the resolved settings, secrets redacted, and the
+// tunables' current values
+func handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"profile":  activeProfileName,
+		"settings": redactedSettings(),
+		"tunables": currentTunables(),
+	})
+}
+
+// envInt reads an integer env var, falling back to def when unset
+func envInt(key string, def int) int {
+	raw, source := getenv(key)
+	if raw == "" {
+		noteConfig(key, def, source)
+		return def
+	}
+
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Fatalf("Invalid %s=%q: expected an integer", key, raw)
+	}
+	noteConfig(key, v, source)
+	return v
+}
+
+// envFloat reads a float env var, falling back to def when unset
+func envFloat(key string, def float64) float64 {
+	raw, source := getenv(key)
+	if raw == "" {
+		noteConfig(key, def, source)
+		return def
+	}
+
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		log.Fatalf("Invalid %s=%q: expected a number", key, raw)
+	}
+	noteConfig(key, v, source)
+	return v
+}
+
+// envBool reads a boolean env var ("true", "1", "false", ...), falling back to
+// def when unset
+func envBool(key string, def bool) bool {
+	raw, source := getenv(key)
+	if raw == "" {
+		noteConfig(key, def, source)
+		return def
+	}
+
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		log.Fatalf("Invalid %s=%q: expected true or false", key, raw)
+	}
+	noteConfig(key, v, source)
+	return v
+}
+
+// envDuration reads a Go duration (e.g. "5s", "250ms") env var, falling back
+// to def when unset
+func envDuration(key string, def time.Duration) time.Duration {
+	raw, source := getenv(key)
+	if raw == "" {
+		noteConfig(key, def, source)
+		return def
+	}
+
+	v, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Fatalf("Invalid %s=%q: expected a duration like 5s or 250ms", key, raw)
+	}
+	noteConfig(key, v, source)
+	return v
+}
+
+// envString reads an env var, falling back to def when unset
+func envString(key, def string) string {
+	v, source := getenv(key)
+	if v == "" {
+		v = def
+	}
+	noteConfig(key, v, source)
+	return v
+}
+
+// envList reads a comma-separated env var into a list, dropping blanks
+func envList(key string) []string {
+	raw, source := getenv(key)
+	noteConfig(key, raw, source)
+	if raw == "" {
+		return nil
+	}
+
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// channelTemplate maps a game to its Redis channel (CHANNEL_TEMPLATE). The
+// default "{id}" keeps the original one-channel-per-game-ID naming.
+var channelTemplate = "{id}"
+
+var placeholderPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+// channelPlaceholders are the fields a channel template can reference; {tags}
+// joins a game's tags with dots, in the order they were given
+var channelPlaceholders = map[string]func(g *GameState) string{
+	"{id}":     func(g *GameState) string { return g.ID },
+	"{sport}":  func(g *GameState) string { return g.Sport },
+	"{league}": func(g *GameState) string { return g.League },
+	"{tags}":   func(g *GameState) string { return strings.Join(g.Tags, ".") },
+}
+
+// validateChannelTemplate requires an {id} placeholder, so every game gets
+// its own channel, and rejects placeholders that don't exist
+func validateChannelTemplate(tmpl string) error {
+	if !strings.Contains(tmpl, "{id}") {
+		return fmt.Errorf("template %q must contain {id}", tmpl)
+	}
+	for _, ph := range placeholderPattern.FindAllString(tmpl, -1) {
+		if _, ok := channelPlaceholders[ph]; !ok {
+			return fmt.Errorf("template %q has unknown placeholder %s", tmpl, ph)
+		}
+	}
+	return nil
+}
+
+// gameChannel renders the channel template for a game. Call under the
+// registry lock.
+func gameChannel(g *GameState) string {
+	return placeholderPattern.ReplaceAllStringFunc(channelTemplate, func(ph string) string {
+		return channelPlaceholders[ph](g)
+	})
+}
+
+// PublishFilter decides which games the publish loop emits updates for.
+// Filtered games stay in state, they just don't publish.
+type PublishFilter struct {
+	only   map[string]bool
+	except map[string]bool
+}
+
+// loadPublishFilter builds the filter from PUBLISH_ONLY / PUBLISH_EXCEPT and
+// rejects IDs that don't match a known game
+func loadPublishFilter() (PublishFilter, error) {
+	filter := PublishFilter{}
+
+	for _, v := range []struct {
+		key string
+		set *map[string]bool
+	}{
+		{"PUBLISH_ONLY", &filter.only},
+		{"PUBLISH_EXCEPT", &filter.except},
+	} {
+		ids := envList(v.key)
+		if len(ids) == 0 {
+			continue
+		}
+		*v.set = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			if !registry.Has(id) {
+				return filter, fmt.Errorf("%s references unknown game %q", v.key, id)
+			}
+			(*v.set)[id] = true
+		}
+	}
+
+	return filter, nil
+}
+
+// Allows reports whether updates for gameID should be published
+func (f PublishFilter) Allows(gameID string) bool {
+	if f.only != nil && !f.only[gameID] {
+		return false
+	}
+	return !f.except[gameID]
+}
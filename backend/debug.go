@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+var errSimulatedPartition = errors.New("redis unreachable (simulated partition)")
+
+// partitionPublisher wraps a Publisher and fails every call while a simulated
+// network partition is active, so reconnection handling can be exercised
+// without touching the real network
+type partitionPublisher struct {
+	Publisher
+	downUntil atomic.Int64 // unix millis; zero when healthy
+}
+
+func (p *partitionPublisher) partitioned() bool {
+	return time.Now().UnixMilli() < p.downUntil.Load()
+}
+
+func (p *partitionPublisher) Publish(ctx context.Context, channel string, payload []byte) error {
+	if p.partitioned() {
+		return errSimulatedPartition
+	}
+	return p.Publisher.Publish(ctx, channel, payload)
+}
+
+func (p *partitionPublisher) Ping(ctx context.Context) error {
+	if p.partitioned() {
+		return errSimulatedPartition
+	}
+	return p.Publisher.Ping(ctx)
+}
+
+// partition is the active partition simulator (debug mode only)
+var partition *partitionPublisher
+
+// redisConnected reports the broker connection as /health sees it
+func redisConnected() bool {
+	return partition == nil || !partition.partitioned()
+}
+
+// staleRate is the chance each published state is followed by a duplicate
+// with lastUpdated moved staleOffset into the past, for testing that clients
+// discard out-of-order updates (DEBUG_STALE_RATE, DEBUG_STALE_OFFSET_MS;
+// debug mode only)
+var (
+	staleRate   float64
+	staleOffset = time.Second
+)
+
+// staleCopy encodes a stale duplicate of a just-published state, when the
+// stale rate says so
+func staleCopy(update gameUpdate) ([]byte, bool) {
+	if staleRate <= 0 || rand.Float64() >= staleRate {
+		return nil, false
+	}
+	state := update.state
+	state.LastUpdated -= staleOffset.Milliseconds()
+	return encodeSequenced("stale state for "+state.ID, state, update.seq)
+}
+
+// wsDropRate is the chance each message to a /ws client is skipped, as if
+// lost on the way, for testing that clients recover from gaps using seq
+// (WS_DROP_RATE; debug mode only)
+var wsDropRate float64
+
+// dropForTesting rolls for a simulated loss, counting it in wsDropped
+func dropForTesting() bool {
+	if wsDropRate <= 0 || rand.Float64() >= wsDropRate {
+		return false
+	}
+	atomic.AddInt64(&metrics.wsDropped, 1)
+	return true
+}
+
+// registerDebugEndpoints mounts the debug-only endpoints
+func registerDebugEndpoints() {
+	http.HandleFunc("/debug/redis-partition", handleRedisPartition)
+	log.Println("⚠️  DEBUG endpoints enabled: /debug/redis-partition")
+}
+
+// POST /debug/redis-partition?durationMs=5000
+func handleRedisPartition(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ms, err := strconv.Atoi(r.URL.Query().Get("durationMs"))
+	if err != nil || ms <= 0 {
+		writeError(w, http.StatusBadRequest, "durationMs must be a positive integer")
+		return
+	}
+
+	until := time.Now().Add(time.Duration(ms) * time.Millisecond)
+	partition.downUntil.Store(until.UnixMilli())
+	log.Printf("⚠️  DEBUG: simulating Redis partition for %dms", ms)
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"partitionedUntil": until.UnixMilli(),
+	})
+}
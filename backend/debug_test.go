@@ -0,0 +1,34 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestDropForTestingDropsTheConfiguredFraction(t *testing.T) {
+	previous := wsDropRate
+	t.Cleanup(func() { wsDropRate = previous })
+
+	wsDropRate = 0
+	for i := 0; i < 1000; i++ {
+		if dropForTesting() {
+			t.Fatal("message dropped with WS_DROP_RATE off")
+		}
+	}
+
+	wsDropRate = 0.25
+	before := atomic.LoadInt64(&metrics.wsDropped)
+	dropped := 0
+	const sample = 20000
+	for i := 0; i < sample; i++ {
+		if dropForTesting() {
+			dropped++
+		}
+	}
+	if rate := float64(dropped) / sample; rate < 0.23 || rate > 0.27 {
+		t.Errorf("dropped %.1f%% of messages at WS_DROP_RATE=0.25", rate*100)
+	}
+	if got := atomic.LoadInt64(&metrics.wsDropped) - before; got != int64(dropped) {
+		t.Errorf("wsDropped grew by %d for %d drops", got, dropped)
+	}
+}
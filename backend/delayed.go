@@ -0,0 +1,104 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// DelayedFeed republishes game states on their delayed channels a fixed
+// time after the live publish (DELAYED_FEED_DELAY), for consumers only
+// licensed for delayed data. Messages wait in order in one queue, so each
+// delayed channel sees its states in the same order and spacing as live.
+type DelayedFeed struct {
+	delay time.Duration
+
+	mu      sync.Mutex
+	pending []delayedMessage
+
+	wake chan struct{} // signalled when pending gains a message
+	stop chan struct{}
+	done chan struct{} // closed once run has returned
+}
+
+type delayedMessage struct {
+	due time.Time
+	m   outbound
+}
+
+// delayedFeed is nil unless DELAYED_FEED_DELAY is set
+var delayedFeed *DelayedFeed
+
+// delayedChannel is the channel a game's delayed states go to, next to its
+// live state channel
+func delayedChannel(channel string) string {
+	return channel + ":delayed"
+}
+
+func newDelayedFeed(delay time.Duration) *DelayedFeed {
+	d := &DelayedFeed{
+		delay: delay,
+		wake:  make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// Add schedules a live game state for its delayed channel
+func (d *DelayedFeed) Add(gameID, channel string, data []byte) {
+	d.mu.Lock()
+	d.pending = append(d.pending, delayedMessage{
+		due: time.Now().Add(d.delay),
+		m:   outbound{kind: kindDelayed, gameID: gameID, channel: delayedChannel(channel), data: data},
+	})
+	d.mu.Unlock()
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run hands each message to the publish queue once it's due
+func (d *DelayedFeed) run() {
+	defer close(d.done)
+	for {
+		d.mu.Lock()
+		if len(d.pending) == 0 {
+			d.mu.Unlock()
+			select {
+			case <-d.wake:
+				continue
+			case <-d.stop:
+				return
+			}
+		}
+		next := d.pending[0]
+		d.mu.Unlock()
+
+		select {
+		case <-time.After(time.Until(next.due)):
+		case <-d.stop:
+			return
+		}
+
+		// Add only appends, so the head is still next
+		d.mu.Lock()
+		d.pending = d.pending[1:]
+		d.mu.Unlock()
+		publishQueue.Enqueue(next.m)
+	}
+}
+
+// Close stops the feed before the publish queue closes. Messages not yet due
+// are dropped rather than published early, which would break the delay.
+func (d *DelayedFeed) Close() {
+	close(d.stop)
+	<-d.done
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	log.Printf("Delayed feed stopped, dropping %d states not yet due", len(d.pending))
+}
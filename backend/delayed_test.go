@@ -0,0 +1,50 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDelayedFeedRepublishesAfterTheDelay(t *testing.T) {
+	pub := &fakePublisher{}
+	queue, err := newPublishQueue(16, policyBlock, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousPublisher, previousQueue := publisher, publishQueue
+	publisher, publishQueue = pub, queue
+	queue.run()
+	t.Cleanup(func() {
+		queue.Close()
+		publisher, publishQueue = previousPublisher, previousQueue
+	})
+
+	const delay = 200 * time.Millisecond
+	feed := newDelayedFeed(delay)
+	before := atomic.LoadInt64(&metrics.delayedPublishes)
+	start := time.Now()
+	feed.Add("game1", "game1", []byte(`{"n":1}`))
+	feed.Add("game1", "game1", []byte(`{"n":2}`))
+
+	waitFor(t, "both delayed states", func() bool { return len(pub.messages()) == 2 })
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("delayed states arrived after %s, want at least %s", elapsed, delay)
+	}
+	for i, msg := range pub.messages() {
+		if want := []string{`{"n":1}`, `{"n":2}`}[i]; msg.Channel != "game1:delayed" || string(msg.Payload) != want {
+			t.Errorf("delayed message %d = %s on %s, want %s on game1:delayed", i, msg.Payload, msg.Channel, want)
+		}
+	}
+	if got := atomic.LoadInt64(&metrics.delayedPublishes) - before; got != 2 {
+		t.Errorf("delayedPublishes grew by %d, want 2", got)
+	}
+
+	// States not yet due at shutdown are dropped, not sent early
+	feed.Add("game1", "game1", []byte(`{"n":3}`))
+	feed.Close()
+	time.Sleep(delay + 50*time.Millisecond)
+	if n := len(pub.messages()); n != 2 {
+		t.Errorf("%d messages published after Close, want the 2 that were due", n)
+	}
+}
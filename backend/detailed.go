@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// DetailedMetrics is everything a simple dashboard needs from one poll
+type DetailedMetrics struct {
+	Timestamp int64                  `json:"timestamp"`
+	Uptime    string                 `json:"uptime"`
+	Counters  map[string]interface{} `json:"counters"` // /metrics, less rates and latency
+	Rates     interface{}            `json:"rates"`
+	LatencyMs map[string]float64     `json:"latencyMs"`
+	Games     map[string]GameCounts  `json:"games"`
+	Memory    MemoryStats            `json:"memory"`
+}
+
+// GameCounts are a game's successful publishes, per channel kind
+type GameCounts struct {
+	States int64 `json:"states"`
+	Events int64 `json:"events"`
+}
+
+// MemoryStats is the useful subset of runtime.MemStats
+type MemoryStats struct {
+	HeapAllocBytes uint64 `json:"heapAllocBytes"`
+	HeapInuseBytes uint64 `json:"heapInuseBytes"`
+	SysBytes       uint64 `json:"sysBytes"`
+	NumGC          uint32 `json:"numGC"`
+	Goroutines     int    `json:"goroutines"`
+}
+
+// GET /metrics/detailed returns counters, rates, latency percentiles,
+// per-game publish counts and memory stats in one payload
+func handleDetailedMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	perGame := map[string]GameCounts{}
+	for _, info := range activeChannels() {
+		if info.GameID == "" {
+			continue
+		}
+		counts := perGame[info.GameID]
+		switch info.Kind {
+		case "game":
+			counts.States = info.Publishes
+		case "events":
+			counts.Events = info.Publishes
+		}
+		perGame[info.GameID] = counts
+	}
+
+	// Rates and latency get their own, fuller sections
+	counters := metricsSnapshot()
+	delete(counters, "rates")
+	delete(counters, "producerLatencyMs")
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	writeJSON(w, http.StatusOK, DetailedMetrics{
+		Timestamp: time.Now().UnixMilli(),
+		Uptime:    time.Since(startedAt).Round(time.Second).String(),
+		Counters:  counters,
+		Rates:     currentRates.Load(),
+		LatencyMs: map[string]float64{
+			"p50": producerLatency.Percentile(50),
+			"p90": producerLatency.Percentile(90),
+			"p95": producerLatency.Percentile(95),
+			"p99": producerLatency.Percentile(99),
+		},
+		Games: perGame,
+		Memory: MemoryStats{
+			HeapAllocBytes: mem.HeapAlloc,
+			HeapInuseBytes: mem.HeapInuse,
+			SysBytes:       mem.Sys,
+			NumGC:          mem.NumGC,
+			Goroutines:     runtime.NumGoroutine(),
+		},
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// detailedMetrics fetches and decodes GET /metrics/detailed
+func detailedMetrics(t *testing.T) DetailedMetrics {
+	t.Helper()
+	rec := serve(handleDetailedMetrics, http.MethodGet, "/metrics/detailed", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics/detailed = %d", rec.Code)
+	}
+	var detailed DetailedMetrics
+	if err := json.Unmarshal(rec.Body.Bytes(), &detailed); err != nil {
+		t.Fatal(err)
+	}
+	return detailed
+}
+
+func TestDetailedMetricsCountsPerGame(t *testing.T) {
+	useGames(t)
+	captureQueue(t)
+	game, _ := registry.Get("game1")
+	channel := gameChannel(&game)
+	before := detailedMetrics(t).Games["game1"]
+
+	for i := 0; i < 2; i++ {
+		delivered(outbound{kind: kindState, gameID: "game1", channel: channel, computed: time.Now()})
+	}
+	delivered(outbound{kind: kindEvent, gameID: "game1", channel: eventsChannel(channel)})
+
+	detailed := detailedMetrics(t)
+	got := detailed.Games["game1"]
+	if got.States-before.States != 2 || got.Events-before.Events != 1 {
+		t.Errorf("game1 counts went %+v -> %+v, want 2 more states and 1 more event", before, got)
+	}
+	for _, p := range []string{"p50", "p90", "p95", "p99"} {
+		if _, ok := detailed.LatencyMs[p]; !ok {
+			t.Errorf("latencyMs has no %s", p)
+		}
+	}
+	if _, ok := detailed.Counters["rates"]; ok {
+		t.Error("counters repeat the rates section")
+	}
+	if detailed.Memory.Goroutines == 0 {
+		t.Error("memory section has no goroutine count")
+	}
+
+	if rec := serve(handleDetailedMetrics, http.MethodPost, "/metrics/detailed", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /metrics/detailed = %d, want 405", rec.Code)
+	}
+}
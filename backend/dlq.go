@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// publishRetries is how many times a failed publish is retried, waiting
+// publishRetryBackoff longer before each attempt (PUBLISH_RETRIES,
+// PUBLISH_RETRY_BACKOFF). A full Redis is never retried, the OOM backoff
+// handles it.
+var (
+	publishRetries      = 2
+	publishRetryBackoff = 50 * time.Millisecond
+)
+
+// dlqChannel receives messages that still failed after every retry
+// (DLQ_CHANNEL); empty drops them as before
+var dlqChannel string
+
+// withRetries calls send until it succeeds or the retries run out
+func withRetries(send func() error) error {
+	err := send()
+	for attempt := 1; err != nil && !isOOM(err) && !isCanceled(err) && attempt <= publishRetries; attempt++ {
+		time.Sleep(time.Duration(attempt) * publishRetryBackoff)
+		err = send()
+	}
+	return err
+}
+
+// DeadLetter is an undeliverable message as written to the DLQ channel.
+// Payload is the original message when it is JSON, otherwise PayloadBinary
+// holds it (base64 in JSON), e.g. for compressed payloads.
+type DeadLetter struct {
+	Kind          string          `json:"kind"`
+	Channel       string          `json:"channel"`
+	GameID        string          `json:"gameId,omitempty"`
+	Error         string          `json:"error"`
+	Attempts      int             `json:"attempts"`
+	FailedAt      int64           `json:"failedAt"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	PayloadBinary []byte          `json:"payloadBinary,omitempty"`
+}
+
+// deadLetter writes a message that failed every retry to the DLQ channel.
+// OOM failures are skipped, the write would be refused just the same.
+func deadLetter(m outbound, err error) {
+	if dlqChannel == "" || isOOM(err) {
+		return
+	}
+
+	letter := DeadLetter{
+		Kind:     m.kind.String(),
+		Channel:  m.channel,
+		GameID:   m.gameID,
+		Error:    err.Error(),
+		Attempts: publishRetries + 1,
+		FailedAt: time.Now().UnixMilli(),
+	}
+	if json.Valid(m.data) {
+		letter.Payload = m.data
+	} else {
+		letter.PayloadBinary = m.data
+	}
+
+	data, err := json.Marshal(letter)
+	if err == nil {
+		data, err = wrapEnvelope(typeDeadLetter, withOutputCase(data), 0)
+	}
+	if err != nil {
+		log.Printf("Error encoding dead letter for %s: %v", m.channel, err)
+		return
+	}
+	if err := publisher.Publish(ctx, dlqChannel, data); err != nil {
+		log.Printf("Error publishing dead letter for %s to %s: %v", m.channel, dlqChannel, err)
+		return
+	}
+	atomic.AddInt64(&metrics.dlqPublishes, 1)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Scheduled downtime (DOWNTIME_SCHEDULE=interval/duration, e.g. "5m/30s")
+// takes the feed offline for downtimeDuration once per downtimeInterval, so
+// clients can practise failing over to another provider. While it's down the
+// publish loop skips its ticks, clocks included, and /health reports
+// degraded. The first window opens one interval after the feed starts.
+var (
+	downtimeInterval time.Duration
+	downtimeDuration time.Duration
+)
+
+// inScheduledDowntime is set while a downtime window is open
+var inScheduledDowntime atomic.Bool
+
+// parseDowntimeSchedule parses "interval/duration"
+func parseDowntimeSchedule(schedule string) (time.Duration, time.Duration, error) {
+	rawInterval, rawDuration, ok := strings.Cut(schedule, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("%q: expected interval/duration", schedule)
+	}
+	interval, err := time.ParseDuration(strings.TrimSpace(rawInterval))
+	if err != nil {
+		return 0, 0, fmt.Errorf("interval: %v", err)
+	}
+	duration, err := time.ParseDuration(strings.TrimSpace(rawDuration))
+	if err != nil {
+		return 0, 0, fmt.Errorf("duration: %v", err)
+	}
+	if duration <= 0 || duration >= interval {
+		return 0, 0, fmt.Errorf("%q: duration must be positive and shorter than the interval", schedule)
+	}
+	return interval, duration, nil
+}
+
+// downtimeDue reports whether now falls inside a downtime window
+func downtimeDue(now int64) bool {
+	if downtimeInterval <= 0 {
+		return false
+	}
+	elapsed := now - feedStartedAt.Load()
+	if elapsed < downtimeInterval.Milliseconds() {
+		return false
+	}
+	return elapsed%downtimeInterval.Milliseconds() < downtimeDuration.Milliseconds()
+}
+
+// updateDowntime opens or closes the downtime window as the schedule says,
+// reporting whether the feed is down
+func updateDowntime(now int64) bool {
+	down := downtimeDue(now)
+	switch {
+	case down && !inScheduledDowntime.Load():
+		atomic.AddInt64(&metrics.scheduledDowntimes, 1)
+		log.Printf("⏸️  Scheduled downtime: publishing paused for %s", downtimeDuration)
+	case !down && inScheduledDowntime.Load():
+		log.Println("▶️  Scheduled downtime over, publishing resumed")
+	}
+	inScheduledDowntime.Store(down)
+	return down
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDowntimeWindowsFollowTheSchedule(t *testing.T) {
+	useGames(t)
+	interval, duration, err := parseDowntimeSchedule("5m/30s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousInterval, previousDuration, started := downtimeInterval, downtimeDuration, feedStartedAt.Load()
+	downtimeInterval, downtimeDuration = interval, duration
+	feedStartedAt.Store(0)
+	t.Cleanup(func() {
+		downtimeInterval, downtimeDuration = previousInterval, previousDuration
+		feedStartedAt.Store(started)
+		inScheduledDowntime.Store(false)
+	})
+
+	minutes := func(m float64) int64 { return int64(m * float64(time.Minute/time.Millisecond)) }
+	for _, tt := range []struct {
+		at   int64
+		down bool
+	}{
+		{0, false},
+		{minutes(4.9), false},
+		{minutes(5), true},
+		{minutes(5.4), true},
+		{minutes(5.5), false},
+		{minutes(10.2), true},
+		{minutes(12), false},
+	} {
+		if got := updateDowntime(tt.at); got != tt.down {
+			t.Errorf("updateDowntime at %dms = %v, want %v", tt.at, got, tt.down)
+		}
+	}
+
+	health := func() string {
+		var body struct{ Status string }
+		json.Unmarshal(serve(handleHealth, http.MethodGet, "/health", "").Body.Bytes(), &body)
+		return body.Status
+	}
+	updateDowntime(minutes(5))
+	if got := health(); got != "degraded" {
+		t.Errorf("/health status = %q during downtime, want degraded", got)
+	}
+	updateDowntime(minutes(6))
+	if got := health(); got != "healthy" {
+		t.Errorf("/health status = %q after downtime, want healthy", got)
+	}
+
+	for _, schedule := range []string{"5m", "5m/5m", "5m/0s", "soon/1s"} {
+		if _, _, err := parseDowntimeSchedule(schedule); err == nil {
+			t.Errorf("parseDowntimeSchedule(%q) = nil error, want one", schedule)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"hash/fnv"
+	"log"
+	"sync/atomic"
+)
+
+// skipDuplicatePayloads drops a game state whose payload is byte for byte the
+// one the game last published, rather than only counting it
+// (SKIP_DUPLICATE_PAYLOADS). States carry lastUpdated and usually seq, so a
+// repeat points at a bug republishing stale data.
+var skipDuplicatePayloads bool
+
+// duplicatePayload reports whether data should be skipped as a repeat of the
+// game's last payload, counting every repeat in duplicatePayloads. Call under
+// the registry lock.
+func (g *Game) duplicatePayload(what string, data []byte) bool {
+	h := fnv.New64a()
+	h.Write(data)
+	sum := h.Sum64()
+	if sum != g.payloadHash {
+		g.payloadHash = sum
+		return false
+	}
+
+	atomic.AddInt64(&metrics.duplicatePayloads, 1)
+	if skipDuplicatePayloads {
+		log.Printf("⚠️  WARN: skipping %s: identical to the last payload published", what)
+		return true
+	}
+	log.Printf("⚠️  WARN: %s is identical to the last payload published", what)
+	return false
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestDuplicatePayloadsAreCountedAndSkipped(t *testing.T) {
+	previousSeq, previousSkip := sequenceNumbers, skipDuplicatePayloads
+	t.Cleanup(func() { sequenceNumbers, skipDuplicatePayloads = previousSeq, previousSkip })
+	game := testGame()
+
+	// Nothing changes between the publishes, so the payloads match
+	sequenceNumbers, skipDuplicatePayloads = false, false
+	before := atomic.LoadInt64(&metrics.duplicatePayloads)
+	game.encodeState("state for game1")
+	if _, ok := game.encodeState("state for game1"); !ok {
+		t.Error("a repeated payload was skipped without SKIP_DUPLICATE_PAYLOADS")
+	}
+	if got := atomic.LoadInt64(&metrics.duplicatePayloads) - before; got != 1 {
+		t.Errorf("duplicatePayloads grew by %d for one repeat, want 1", got)
+	}
+
+	skipDuplicatePayloads = true
+	if _, ok := game.encodeState("state for game1"); ok {
+		t.Error("a repeated payload was published with SKIP_DUPLICATE_PAYLOADS")
+	}
+	game.HomeScore++
+	if _, ok := game.encodeState("state for game1"); !ok {
+		t.Error("a changed payload was skipped as a duplicate")
+	}
+
+}
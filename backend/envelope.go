@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync/atomic"
+)
+
+// Envelope wraps every JSON payload published: type says what data is,
+// version is the schema version and seq the game's sequence number. Sig is
+// only set when signing is on.
+type Envelope struct {
+	Type     string          `json:"type"`
+	Version  int             `json:"version"`
+	Seq      uint64          `json:"seq,omitempty"`
+	Provider string          `json:"provider,omitempty"`
+	Region   string          `json:"region,omitempty"`
+	Data     json.RawMessage `json:"data"`
+	Sig      string          `json:"sig,omitempty"`
+}
+
+// Envelope types
+const (
+	typeState      = "state"
+	typeEvent      = "event"
+	typeLeague     = "league"
+	typeAggregate  = "aggregate"
+	typeDeadLetter = "dead_letter"
+	typeMessage    = "message"
+
+	// A scripted game's typed messages, on its events channel
+	typeOddsUpdate  = "odds_update"
+	typeScoreUpdate = "score_update"
+	typeMatchEvent  = "match_event"
+	typeSettlement  = "settlement"
+)
+
+// messageType is the envelope type of a value being published
+func messageType(v interface{}) string {
+	switch v.(type) {
+	case *Game, *GameState, GameState, rawPayload, templateError:
+		return typeState
+	case MatchEvent:
+		return typeEvent
+	case LeagueSnapshot:
+		return typeLeague
+	case AggregateSnapshot:
+		return typeAggregate
+	case OddsUpdate:
+		return typeOddsUpdate
+	case ScoreUpdate:
+		return typeScoreUpdate
+	case scenarioEvent:
+		return typeMatchEvent
+	case Settlement:
+		return typeSettlement
+	default:
+		return typeMessage
+	}
+}
+
+// providerID identifies this backend instance in every published message, so
+// consumers merging several feeds can tell sources apart (PROVIDER_ID, the
+// hostname by default)
+var providerID string
+
+// region tags every published message with where the instance runs, e.g. for
+// a global dashboard merging several regions' feeds (REGION, default local)
+var region = "local"
+
+// sequenceNumbers numbers each game's state publishes 1, 2, 3... in a "seq"
+// field, so clients can spot a missed message by a gap (PUBLISH_SEQ). A
+// recreated game starts again from 1.
+var sequenceNumbers = true
+
+// wrapEnvelope nests encoded JSON in an Envelope of the given type, with
+// the provider, region and, when non-zero, seq
+func wrapEnvelope(kind string, data []byte, seq uint64) ([]byte, error) {
+	env := Envelope{Type: kind, Version: wireVersion, Seq: seq, Provider: providerID, Region: region, Data: data}
+	if len(hmacKey) > 0 {
+		env.Sig = signPayload(hmacKey, data)
+	}
+	return json.Marshal(env)
+}
+
+// hmacKey signs every payload when set (PUBLISH_HMAC_KEY)
+var hmacKey []byte
+
+// signPayload returns the hex HMAC-SHA256 of data under key
+func signPayload(key, data []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifySignature reports whether sig is a valid signature of data under key.
+// Subscribers verify the raw bytes of the envelope's data field.
+func verifySignature(key, data []byte, sig string) bool {
+	expected, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// encodeMessage turns a payload into the bytes published on a channel: JSON
+// nested in an Envelope carrying the provider and any sequence number, signed
+// when signing is on, then compressed. Game states are MessagePack or Protocol
+// Buffers instead under ENCODING=msgpack or proto.
+func encodeMessage(v interface{}, seq uint64) ([]byte, error) {
+	if game, ok := v.(*Game); ok {
+		switch publishFormat {
+		case formatMsgpack:
+			return encodeMsgpack(&game.GameState, seq)
+		case formatProto:
+			return encodeProto(&game.GameState, seq)
+		}
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	data, err = wrapEnvelope(messageType(v), withOutputCase(data), seq)
+	if err != nil {
+		return nil, err
+	}
+
+	return compressor.Encode(data)
+}
+
+// maxPayloadBytes caps the size of an encoded message (MAX_PAYLOAD_BYTES)
+var maxPayloadBytes = 1 << 20
+
+// encodeForPublish encodes a message and applies the payload size guard,
+// accounting for failures in the metrics. what names the message in logs.
+func encodeForPublish(what string, v interface{}) ([]byte, bool) {
+	return encodeSequenced(what, v, 0)
+}
+
+// encodeState encodes the game's state for publishing under its next
+// sequence number, which is only used up when encoding succeeds. Call under
+// the registry lock.
+func (g *Game) encodeState(what string) ([]byte, bool) {
+	var next uint64
+	if sequenceNumbers {
+		next = g.seq + 1
+	}
+	var v interface{} = g
+	if payloadTemplate != nil {
+		v = renderPayload(&g.GameState)
+	}
+	data, ok := encodeSequenced(what, v, next)
+	if !ok || g.duplicatePayload(what, data) {
+		return nil, false
+	}
+	g.seq = next
+	return data, true
+}
+
+// encodeSequenced is encodeForPublish with a sequence number; 0 is none
+func encodeSequenced(what string, v interface{}, seq uint64) ([]byte, bool) {
+	data, err := encodeMessage(v, seq)
+	if err != nil {
+		atomic.AddInt64(&metrics.publishErrors, 1)
+		log.Printf("Error encoding %s: %v", what, err)
+		return nil, false
+	}
+
+	// Oversized payloads are skipped rather than flooding the broker
+	if len(data) > maxPayloadBytes {
+		atomic.AddInt64(&metrics.oversizePayloads, 1)
+		log.Printf("⚠️  WARN: skipping %s: payload is %d bytes (MAX_PAYLOAD_BYTES=%d)", what, len(data), maxPayloadBytes)
+		return nil, false
+	}
+
+	return data, true
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// testGame is game1 of the default games, ready to publish
+func testGame() *Game {
+	state := defaultGames()[0]
+	state.applyDefaults()
+	state.initMarkets()
+	return newGame(state)
+}
+
+func TestEncodeMessageNestsJSONInEnvelope(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		seq  uint64
+		want string
+	}{
+		{"state", testGame(), 7, typeState},
+		{"event", MatchEvent{GameID: "game1", Type: "goal"}, 0, typeEvent},
+		{"league", LeagueSnapshot{League: "la-liga"}, 0, typeLeague},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := encodeMessage(tt.v, tt.seq)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var env Envelope
+			if err := json.Unmarshal(data, &env); err != nil {
+				t.Fatalf("payload is not an envelope: %v", err)
+			}
+			if env.Type != tt.want || env.Version != wireVersion || env.Seq != tt.seq {
+				t.Errorf("envelope type=%q version=%d seq=%d, want %q %d %d", env.Type, env.Version, env.Seq, tt.want, wireVersion, tt.seq)
+			}
+			// The payload's own "type" (an event's) stays inside data
+			var inner map[string]interface{}
+			if err := json.Unmarshal(env.Data, &inner); err != nil {
+				t.Fatalf("data is not an object: %v", err)
+			}
+			if tt.want == typeEvent && inner["type"] != "goal" {
+				t.Errorf("event data type = %v, want goal", inner["type"])
+			}
+			if tt.want == typeState && inner["id"] != "game1" {
+				t.Errorf("state data id = %v, want game1", inner["id"])
+			}
+		})
+	}
+}
+
+func TestEncodeMessageSignsEnvelopeData(t *testing.T) {
+	hmacKey = []byte("secret")
+	defer func() { hmacKey = nil }()
+
+	data, err := encodeMessage(testGame(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Type != typeState || env.Seq != 3 {
+		t.Errorf("signed envelope type=%q seq=%d, want state 3", env.Type, env.Seq)
+	}
+	if !verifySignature(hmacKey, env.Data, env.Sig) {
+		t.Error("signature doesn't verify over the envelope's data")
+	}
+}
+
+func TestVerifySignatureRejectsTampering(t *testing.T) {
+	key, data := []byte("secret"), []byte(`{"id":"game1","homeOdds":2.1}`)
+	sig := signPayload(key, data)
+
+	if !verifySignature(key, data, sig) {
+		t.Fatal("signature doesn't verify over the bytes it signed")
+	}
+	if verifySignature(key, []byte(`{"id":"game1","homeOdds":9.9}`), sig) {
+		t.Error("signature verifies over tampered data")
+	}
+	if verifySignature([]byte("other"), data, sig) {
+		t.Error("signature verifies under the wrong key")
+	}
+	if verifySignature(key, data, "not-hex") {
+		t.Error("a malformed signature verifies")
+	}
+}
+
+func TestEncodeMsgpackNestsStateInEnvelope(t *testing.T) {
+	game := testGame()
+	data, err := encodeMsgpack(&game.GameState, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != formatMsgpackByte {
+		t.Fatalf("first byte = %#x, want %#x", data[0], formatMsgpackByte)
+	}
+	var env struct {
+		Type    string                 `msgpack:"type"`
+		Version int                    `msgpack:"version"`
+		Seq     uint64                 `msgpack:"seq"`
+		Data    map[string]interface{} `msgpack:"data"`
+	}
+	if err := msgpack.Unmarshal(data[1:], &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Type != typeState || env.Version != wireVersion || env.Seq != 5 {
+		t.Errorf("envelope type=%q version=%d seq=%d, want state %d 5", env.Type, env.Version, env.Seq, wireVersion)
+	}
+	if env.Data["id"] != "game1" || env.Data["homeTeam"] != "Arsenal" {
+		t.Errorf("data = %v, want game1's state", env.Data)
+	}
+}
+
+func TestPublishSeqNumbersEachGameInOrder(t *testing.T) {
+	useGames(t)
+	queue := captureQueue(t)
+	previousSeq, previousProb := sequenceNumbers, gameUpdateProb
+	sequenceNumbers, gameUpdateProb = true, 1
+	t.Cleanup(func() { sequenceNumbers, gameUpdateProb = previousSeq, previousProb })
+
+	last := map[string]uint64{}
+	for tick := 0; tick < 5; tick++ {
+		publishTick()
+		for _, m := range queue.drain() {
+			if m.kind != kindState {
+				continue
+			}
+			var env Envelope
+			if err := json.Unmarshal(m.data, &env); err != nil {
+				t.Fatal(err)
+			}
+			if env.Seq != last[m.gameID]+1 || env.Seq != m.seq {
+				t.Errorf("%s published seq %d (queued as %d) after %d, want %d", m.gameID, env.Seq, m.seq, last[m.gameID], last[m.gameID]+1)
+			}
+			last[m.gameID] = env.Seq
+		}
+	}
+	if len(last) != 3 {
+		t.Errorf("sequenced games %v, want game1-3", last)
+	}
+}
+
+func TestEnvelopeCarriesProviderAndRegion(t *testing.T) {
+	previousProvider, previousRegion := providerID, region
+	providerID, region = "odds-1", "eu-west-1"
+	t.Cleanup(func() { providerID, region = previousProvider, previousRegion })
+
+	for _, v := range []interface{}{testGame(), MatchEvent{GameID: "game1", Type: "goal"}} {
+		data, err := encodeMessage(v, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var env Envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			t.Fatal(err)
+		}
+		if env.Provider != "odds-1" || env.Region != "eu-west-1" {
+			t.Errorf("%s envelope provider=%q region=%q, want odds-1 eu-west-1", env.Type, env.Provider, env.Region)
+		}
+	}
+
+	region = ""
+	data, err := encodeMessage(testGame(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"region"`) {
+		t.Errorf("envelope has a region field with REGION empty: %.200s", data)
+	}
+}
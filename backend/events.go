@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// MatchEvent is a discrete in-match incident, published on the game's events
+// channel alongside the regular state updates
+type MatchEvent struct {
+	GameID    string `json:"gameId"`
+	Type      string `json:"type"`
+	Team      string `json:"team,omitempty"`
+	Player    string `json:"player,omitempty"`
+	AssistBy  string `json:"assistBy,omitempty"`
+	Market    string `json:"market,omitempty"` // marketVoided events
+	HomeScore int    `json:"homeScore"`
+	AwayScore int    `json:"awayScore"`
+	Minute    int    `json:"minute"`
+	Timestamp int64  `json:"timestamp"`
+
+	KickoffTime int64 `json:"kickoffTime,omitempty"` // scheduled and kickoff events
+}
+
+const (
+	eventGoal       = "goal"
+	eventYellowCard = "yellowCard"
+	eventRedCard    = "redCard"
+	eventEnded      = "ended"
+
+	teamHome = "home"
+	teamAway = "away"
+)
+
+var (
+	// goalProbability is the per-tick chance of a goal in each game
+	goalProbability = 0.002
+	// goalCooldown is the minimum match time between two goals in a game
+	goalCooldown = 60 * time.Second
+	// assistProbability is the chance a goal has a credited assist
+	assistProbability = 0.7
+	// logEvents logs every published match event (LOG_EVENTS)
+	logEvents = true
+	// cardProbability is the per-tick chance of a booking in each game
+	cardProbability = 0.004
+	// redCardShare is the fraction of bookings that are straight reds
+	redCardShare = 0.1
+)
+
+// teamSquads are the built-in players a game's scorers and assists are
+// drawn from, looked up by team name for games created without squads. Teams
+// without a squad still score, just without named players.
+var teamSquads = map[string][]string{
+	"Arsenal":     {"Saka", "Ødegaard", "Havertz", "Martinelli", "Rice", "Trossard"},
+	"Chelsea":     {"Palmer", "Jackson", "Madueke", "Enzo", "Nkunku", "Mudryk"},
+	"Liverpool":   {"Salah", "Núñez", "Díaz", "Szoboszlai", "Mac Allister", "Gakpo"},
+	"Man United":  {"Fernandes", "Rashford", "Højlund", "Garnacho", "Mainoo", "Mount"},
+	"Barcelona":   {"Lewandowski", "Yamal", "Raphinha", "Pedri", "Gavi", "Olmo"},
+	"Real Madrid": {"Vinícius Jr", "Bellingham", "Mbappé", "Rodrygo", "Valverde", "Modrić"},
+}
+
+// eventsFanIn, when set, is the single channel every game's match events go
+// to instead of their per-game events channels (EVENTS_CHANNEL). Events carry
+// their gameId either way.
+var eventsFanIn string
+
+// eventsChannel is the Redis channel carrying a game's match events, next to
+// the game's state channel, or the shared fan-in channel
+func eventsChannel(channel string) string {
+	if eventsFanIn != "" {
+		return eventsFanIn
+	}
+	return channel + ":events"
+}
+
+// shortenOdds moves the market after a goal: the scoring side shortens and
+// the opposing side and the draw drift out
+func shortenOdds(game *GameState, scorer, opponent string, now int64) {
+	shiftOdds(game, scorer, opponent, defaultGoalShift, now)
+}
+
+// shiftOdds applies an OddsShift in favour of one side to the open markets;
+// suspended, voided and settled ones hold their price
+func shiftOdds(game *GameState, favoured, opponent string, shift OddsShift, now int64) {
+	if m, ok := game.Markets[favoured]; ok && m.Status == marketOpen {
+		if odds := m.Odds * shift.Shorten; odds > oddsFloor {
+			game.setOdds(favoured, odds, now)
+		}
+	}
+	for _, market := range []string{opponent, marketDraw} {
+		if m, ok := game.Markets[market]; ok && m.Status == marketOpen {
+			game.setOdds(market, m.Odds*shift.Lengthen, now)
+		}
+	}
+}
+
+// Squads are a game's players, given when it's created; scorers, assists and
+// bookings name one of them
+type Squads struct {
+	Home []string `json:"home"`
+	Away []string `json:"away"`
+}
+
+// validateSquads rejects given squads that leave a side without players
+func validateSquads(squads *Squads) error {
+	if squads == nil {
+		return nil
+	}
+	for _, side := range []struct {
+		name    string
+		players []string
+	}{{"home", squads.Home}, {"away", squads.Away}} {
+		if len(side.players) == 0 {
+			return fmt.Errorf("squads.%s must name at least one player", side.name)
+		}
+		for _, player := range side.players {
+			if strings.TrimSpace(player) == "" {
+				return fmt.Errorf("squads.%s has an empty player name", side.name)
+			}
+		}
+	}
+	return nil
+}
+
+// squad returns the players of a side (teamHome or teamAway): the game's
+// own squads when it has them, otherwise the built-in squad for the team's
+// name, or nil when none is known
+func (g *GameState) squad(side string) []string {
+	if g.Squads != nil {
+		if side == teamHome {
+			return g.Squads.Home
+		}
+		return g.Squads.Away
+	}
+	if side == teamHome {
+		return teamSquads[g.HomeTeam]
+	}
+	return teamSquads[g.AwayTeam]
+}
+
+// publishEvents publishes match events on their game's events channel
+func publishEvents(channel string, events []MatchEvent) {
+	for _, event := range events {
+		// Scripted games type their match events among their other messages
+		var v interface{} = event
+		if scenarios[event.GameID] != nil {
+			v = scenarioEvent(event)
+		}
+		data, ok := encodeForPublish("match event for "+event.GameID, v)
+		if !ok {
+			continue
+		}
+
+		publishQueue.Enqueue(outbound{kind: kindEvent, gameID: event.GameID, channel: eventsChannel(channel), data: data})
+		mirrorToWebhook(event)
+
+		if !logEvents {
+			continue
+		}
+		switch {
+		case event.Type == eventEnded:
+			log.Printf("🏁 %s: full time %d-%d", event.GameID, event.HomeScore, event.AwayScore)
+		case event.Type == eventMarketsSuspended:
+			log.Printf("⏸️  %s: markets suspended for price review", event.GameID)
+		case event.Type == eventMarketsResumed:
+			log.Printf("▶️  %s: markets resumed after price review", event.GameID)
+		case event.Type == eventGoal && event.Player != "":
+			log.Printf("⚽ %s: goal for %s (%s, assist %s) %d-%d", event.GameID, event.Team, event.Player, orNone(event.AssistBy), event.HomeScore, event.AwayScore)
+		case event.Type == eventGoal:
+			log.Printf("⚽ %s: goal for %s %d-%d", event.GameID, event.Team, event.HomeScore, event.AwayScore)
+		case event.Type == eventGoalDisallowed:
+			log.Printf("📺 %s: goal for %s (%s) disallowed after VAR review %d-%d", event.GameID, event.Team, orNone(event.Player), event.HomeScore, event.AwayScore)
+		case event.Type == eventHalfTime:
+			log.Printf("⏸️  %s: half time %d-%d", event.GameID, event.HomeScore, event.AwayScore)
+		case event.Type == eventSecondHalf:
+			log.Printf("▶️  %s: second half under way %d-%d", event.GameID, event.HomeScore, event.AwayScore)
+		case event.Type == eventYellowCard || event.Type == eventRedCard:
+			log.Printf("🟨 %s: %s for %s (%s)", event.GameID, event.Type, event.Team, orNone(event.Player))
+		default:
+			log.Printf("📣 %s: %s for %s", event.GameID, event.Type, orNone(event.Team))
+		}
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// logSquads reports which games will publish named scorers
+func logSquads() {
+	for _, game := range registry.Snapshot() {
+		if len(game.squad(teamHome)) == 0 || len(game.squad(teamAway)) == 0 {
+			log.Printf("⚠️  %s: missing squad list for %s or %s, goals will be published without named scorers", game.ID, game.HomeTeam, game.AwayTeam)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateGameRejectsEmptySquads(t *testing.T) {
+	tests := []struct {
+		name   string
+		squads *Squads
+		want   string
+	}{
+		{"no home players", &Squads{Away: []string{"Kane"}}, "squads.home"},
+		{"no away players", &Squads{Home: []string{"Kane"}, Away: []string{}}, "squads.away"},
+		{"blank name", &Squads{Home: []string{"Kane"}, Away: []string{" "}}, "squads.away"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := defaultGames()[0]
+			state.Squads = tt.squads
+			err := validateGame(&state)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("validateGame = %v, want an error about %s", err, tt.want)
+			}
+		})
+	}
+
+	state := defaultGames()[0]
+	state.Squads = &Squads{Home: []string{"Kane"}, Away: []string{"Son"}}
+	if err := validateGame(&state); err != nil {
+		t.Errorf("validateGame with both squads = %v, want nil", err)
+	}
+}
+
+func TestGoalScorersComeFromGameSquads(t *testing.T) {
+	previous := goalProbability
+	goalProbability = 1
+	defer func() { goalProbability = previous }()
+
+	state := defaultGames()[0]
+	state.Squads = &Squads{Home: []string{"Kane", "Musiala"}, Away: []string{"Son"}}
+	state.applyDefaults()
+	state.initMarkets()
+	game := newGame(state)
+	r := rand.New(rand.NewSource(1))
+
+	scorers := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		game.nextGoalClock = 0
+		for _, event := range (goalGenerator{}).Generate(game, r) {
+			want := state.Squads.Home
+			if event.Team == teamAway {
+				want = state.Squads.Away
+			}
+			if !slices.Contains(want, event.Player) {
+				t.Fatalf("%s goal scored by %q, not in its squad %v", event.Team, event.Player, want)
+			}
+			scorers[event.Player] = true
+		}
+	}
+	if len(scorers) != 3 {
+		t.Errorf("scorers %v, want every player in the squads to score in 50 goals", scorers)
+	}
+
+	// Without squads the built-in ones for the team names are used
+	if got := defaultGames()[0].squad(teamHome); len(got) == 0 || got[0] != teamSquads["Arsenal"][0] {
+		t.Errorf("squad(home) without squads = %v, want Arsenal's", got)
+	}
+}
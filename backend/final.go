@@ -0,0 +1,61 @@
+package main
+
+import "log"
+
+// finalPublish makes deleted games, and every game still running at
+// shutdown, publish one last ended state so clients get a clean close
+// signal (FINAL_PUBLISH)
+var finalPublish = true
+
+// finalUpdate ends a game and builds its final update, with the ended event.
+// Games that already ended published their final state at full time. The
+// caller must hold the registry lock.
+func finalUpdate(game *Game) (gameUpdate, bool) {
+	if !finalPublish || game.Status == statusEnded || !publishFilter.Allows(game.ID) {
+		return gameUpdate{}, false
+	}
+
+	now := game.now()
+	game.Status = statusEnded
+	game.endedAt = now
+	game.LastUpdated = now
+
+	ended := MatchEvent{
+		GameID:    game.ID,
+		Type:      eventEnded,
+		HomeScore: game.HomeScore,
+		AwayScore: game.AwayScore,
+		Minute:    game.Minute,
+		Timestamp: now,
+	}
+	return forcedUpdate(game, "final state for "+game.ID, ended)
+}
+
+// publishFinal queues a final update built by finalUpdate. It must not be
+// called with the registry lock held, since enqueueing may block.
+func publishFinal(gameID string, update gameUpdate) {
+	publishQueue.Enqueue(outbound{kind: kindFinal, gameID: gameID, channel: update.channel, data: update.data, hash: stateHash(update.state), seq: update.seq, computed: update.computed})
+	recordState(update.state, update.seq)
+	if update.state.Tier != "" {
+		publishQueue.Enqueue(outbound{kind: kindTier, gameID: gameID, channel: tierChannel(update.state.Tier), data: update.data})
+	}
+	publishEvents(update.channel, update.events)
+}
+
+// publishFinalStates ends every game on shutdown, once the feed has stopped
+func publishFinalStates() {
+	published := 0
+	for _, game := range registry.Games() {
+		var update gameUpdate
+		var ok bool
+		registry.UpdateGame(game, func(game *Game) { update, ok = finalUpdate(game) })
+
+		if ok {
+			publishFinal(game.ID, update)
+			published++
+		}
+	}
+	if published > 0 {
+		log.Printf("🏁 Published final state for %d games", published)
+	}
+}
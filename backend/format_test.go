@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestMsgpackRoundTripsGameState(t *testing.T) {
+	previous := publishFormat
+	t.Cleanup(func() { publishFormat = previous })
+	game := testGame()
+	game.LastUpdated = time.Now().UnixMilli()
+	game.setOdds(marketHome, 2.537, game.LastUpdated)
+
+	publishFormat = formatMsgpack
+	data, err := encodeMessage(game, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isPlainJSON(data) {
+		t.Error("a MessagePack state reads as plain JSON")
+	}
+
+	var env msgpackEnvelope
+	dec := msgpack.NewDecoder(bytes.NewReader(data[1:]))
+	dec.SetCustomStructTag("json")
+	if err := dec.Decode(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Seq != 4 || env.Data == nil {
+		t.Fatalf("decoded envelope %+v, want seq 4 and a state", env)
+	}
+	// Compare as JSON, which is what both encodings carry
+	want, _ := json.Marshal(&game.GameState)
+	got, _ := json.Marshal(env.Data)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round-tripped state\n%s\nwant\n%s", got, want)
+	}
+
+	publishFormat = formatJSON
+	plain, err := encodeMessage(game, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) >= len(plain) {
+		t.Errorf("MessagePack state is %d bytes, JSON %d", len(data), len(plain))
+	}
+}
+
+func TestEncodingsPublishTheSameState(t *testing.T) {
+	previous := publishFormat
+	includeRawOdds = true
+	t.Cleanup(func() { publishFormat, includeRawOdds = previous, false })
+	game := testGame()
+	game.Tags = []string{"derby"}
+	game.Stats = map[string]float64{"possessionHome": 55}
+	game.Squads = &Squads{Home: []string{"Saka", "Rice"}, Away: []string{"Salah"}}
+	game.setOdds(marketHome, 2.34567, 1700000000000)
+
+	decoded := map[string]wireState{}
+	for _, format := range []string{formatJSON, formatMsgpack, formatProto} {
+		publishFormat = format
+		data, err := encodeMessage(game, 3)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		var state wireState
+		switch format {
+		case formatJSON:
+			var env struct {
+				Data wireState `json:"data"`
+			}
+			err = json.Unmarshal(data, &env)
+			state = env.Data
+		case formatMsgpack:
+			var env msgpackEnvelope
+			dec := msgpack.NewDecoder(bytes.NewReader(data[1:]))
+			dec.SetCustomStructTag("json")
+			err = dec.Decode(&env)
+			state = *env.Data
+		case formatProto:
+			state = protoWireState(field(decodeProto(t, data), "state").Message())
+		}
+		if err != nil {
+			t.Fatalf("%s: decoding: %v", format, err)
+		}
+		decoded[format] = state
+	}
+
+	want := game.wire()
+	if want.HomeOdds != 2.35 || want.HomeOddsRaw != 2.34567 {
+		t.Fatalf("projected homeOdds, homeOddsRaw = %v, %v, want 2.35, 2.34567", want.HomeOdds, want.HomeOddsRaw)
+	}
+	for format, got := range decoded {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s state\n%+v\nwant\n%+v", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,577 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"maps"
+	"math"
+	"math/rand"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type GameState struct {
+	ID          string             `json:"id"`
+	HomeTeam    string             `json:"homeTeam"`
+	AwayTeam    string             `json:"awayTeam"`
+	League      string             `json:"league,omitempty"`
+	Tier        string             `json:"tier,omitempty"`
+	Featured    bool               `json:"featured,omitempty"`
+	Tags        []string           `json:"tags,omitempty"`
+	Shock       bool               `json:"shock,omitempty"` // set only on the publish a price shock caused
+	Stopped     bool               `json:"clockStopped,omitempty"`
+	Suspended   bool               `json:"suspended,omitempty"` // set through POST /games/{id}/suspend
+	Sport       string             `json:"sport"`
+	Status      string             `json:"status"`
+	KickoffTime int64              `json:"kickoffTime,omitempty"` // unix ms; scheduled games go live then
+	Attendance  int                `json:"attendance,omitempty"`
+	Importance  float64            `json:"importance,omitempty"` // scales event rates; 1 is a regular match
+	HomeScore   int                `json:"homeScore"`
+	AwayScore   int                `json:"awayScore"`
+	Minute      int                `json:"minute"`
+	HomeOdds    float64            `json:"homeOdds"`
+	AwayOdds    float64            `json:"awayOdds"`
+	DrawOdds    float64            `json:"drawOdds"`
+	Markets     map[string]*Market `json:"markets"`
+	Stats       map[string]float64 `json:"stats,omitempty"`
+	Squads      *Squads            `json:"squads,omitempty"`
+	LastUpdated int64              `json:"lastUpdated"`
+	TTLMs       int64              `json:"ttlMs,omitempty"`              // how long clients treat the top-level odds as fresh
+	Remaining   *int               `json:"remainingPublishes,omitempty"` // only on GET /games/{id} with a publish budget
+}
+
+// includeRawOdds publishes the top-level odds rounded to their sport's display precision,
+// with the full-precision values alongside as *OddsRaw (INCLUDE_RAW_ODDS)
+var includeRawOdds bool
+
+// plainState is GameState without its MarshalJSON, for embedding
+type plainState GameState
+
+// wireState is a game state as every encoding publishes it: as-is, or with
+// rounded and raw odds when includeRawOdds is set
+type wireState struct {
+	plainState
+	HomeOddsRaw float64 `json:"homeOddsRaw,omitempty"`
+	AwayOddsRaw float64 `json:"awayOddsRaw,omitempty"`
+	DrawOddsRaw float64 `json:"drawOddsRaw,omitempty"`
+}
+
+// wire projects the state for publishing. JSON, MessagePack and Protobuf all
+// encode this, so none of them skips a rule the others apply.
+func (g *GameState) wire() wireState {
+	w := wireState{plainState: plainState(*g)}
+	if !includeRawOdds {
+		return w
+	}
+
+	precision := precisionFor(g.Sport)
+	w.HomeOdds = roundOdds(g.HomeOdds, precision)
+	w.AwayOdds = roundOdds(g.AwayOdds, precision)
+	w.DrawOdds = roundOdds(g.DrawOdds, precision)
+	w.HomeOddsRaw, w.AwayOddsRaw, w.DrawOddsRaw = g.HomeOdds, g.AwayOdds, g.DrawOdds
+	return w
+}
+
+// MarshalJSON encodes the state's wire projection
+func (g GameState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(g.wire())
+}
+
+// Game wraps the published GameState with simulation internals that never go
+// on the wire. Embedding keeps the JSON encoding identical to GameState.
+type Game struct {
+	GameState
+
+	rng     *rand.Rand
+	lastKey string  // displayKey as of the last publish, for dedup
+	clock   float64 // elapsed match time in seconds, Minute is derived from it
+	endedAt int64   // unix millis the game reached full time, 0 while live
+
+	nextPublishAt int64              // unix millis a tiered game is next due to publish
+	baseOdds      map[string]float64 // per-market odds at creation, for mean reversion
+	reviewed      []string           // markets suspended by the running price review, nil outside one
+	clockSkewed   bool               // wall clock is behind LastUpdated, already warned about
+
+	homeRate, awayRate float64 // expected goals per full match, for DRIFT_MODEL=poisson
+	nextGoalClock      float64 // match clock (seconds) before which the game can't score again
+	nextShockAt        int64   // unix millis before which the game can't take another price shock
+	leagueShocked      bool    // a league shock moved the odds since the last publish
+
+	lastTriggers map[string]string // triggerKeys as of the last publish, for PUBLISH_TRIGGERS
+	announced    bool              // the scheduled marker went out
+	seq          uint64            // sequence number of the last published state
+	stoppageLeft float64           // match seconds until a stoppage ends
+	addedTime    float64           // match seconds lost to stoppages, played on after full time
+
+	lastOdds           string // odds as of the last publish, for the stuck check
+	unchangedPublishes int    // consecutive publishes with lastOdds
+	publishes          int    // states published, for MAX_PUBLISHES_PER_GAME
+
+	publishedOdds  map[string]float64 // per-market odds as of the last publish, for ADAPTIVE_PUBLISH
+	nextAdaptiveAt int64              // unix millis before which an adaptive game doesn't publish
+
+	crowdCap    int    // the crowd late arrivals can grow Attendance to
+	payloadHash uint64 // FNV-1a of the last encoded state payload, for duplicatePayloads
+
+	pendingVAR         *varReview // a goal that will be disallowed, for VAR_PROBABILITY
+	publishedSuspended bool       // Suspended as of the last publish
+	scenarioStep       int        // the next scripted event of the game's scenario
+	halfTimeLeft       float64    // match seconds left of a scripted half-time break
+	halfTimeDone       bool       // the half has been reached, break or not
+}
+
+// simSeed is the base seed every per-game RNG is derived from
+var simSeed int64
+
+// newGame wraps state with its own RNG, seeded from simSeed plus an offset
+// derived from the game ID so games move independently but reproducibly
+func newGame(state GameState) *Game {
+	h := fnv.New64a()
+	h.Write([]byte(state.ID))
+
+	baseOdds := make(map[string]float64, len(state.Markets))
+	for name, m := range state.Markets {
+		baseOdds[name] = m.Odds
+	}
+
+	homeRate, awayRate := scoringRates(baseOdds)
+
+	game := &Game{
+		GameState: state,
+		rng:       rand.New(rand.NewSource(simSeed + int64(h.Sum64()))),
+		clock:     float64(state.Minute) * 60,
+		baseOdds:  baseOdds,
+		homeRate:  homeRate,
+		awayRate:  awayRate,
+
+		crowdCap: attendanceCap(state.Attendance),
+	}
+	attachScenario(game)
+	return game
+}
+
+// now is the game's current timestamp, clamped so LastUpdated never moves
+// backwards when the wall clock jumps back. Call under the registry lock.
+func (g *Game) now() int64 {
+	now := time.Now().UnixMilli()
+	if now >= g.LastUpdated {
+		g.clockSkewed = false
+		return now
+	}
+
+	if !g.clockSkewed {
+		g.clockSkewed = true
+		log.Printf("⚠️  WARN: clock went back %dms for %s, holding lastUpdated until it catches up", g.LastUpdated-now, g.ID)
+	}
+	return g.LastUpdated
+}
+
+// Market is a single priced outcome of a game. The 1X2 markets are mirrored
+// into the top-level odds fields for existing clients.
+type Market struct {
+	Odds        float64 `json:"odds"`
+	Status      string  `json:"status"`
+	Volatility  float64 `json:"volatility"`
+	LastUpdated int64   `json:"lastUpdated"`
+	TTLMs       int64   `json:"ttlMs,omitempty"` // how long clients treat the odds as fresh
+	Voided      bool    `json:"voided,omitempty"`
+}
+
+const (
+	marketHome = "home"
+	marketAway = "away"
+	marketDraw = "draw"
+
+	marketOpen      = "open"
+	marketSuspended = "suspended"
+	marketVoided    = "voided"
+	marketSettled   = "settled" // a scripted game's markets at full time
+
+	sportFootball = "football"
+
+	statusScheduled = "scheduled"
+	statusLive      = "live"
+	statusEnded     = "ended"
+)
+
+// marketNames is the fixed drift order of the 1X2 markets
+var marketNames = []string{marketHome, marketAway, marketDraw}
+
+// oddsPrecision is the number of decimals odds are displayed with, unless
+// the game's sport has its own in sportPrecision
+const oddsPrecision = 2
+
+// maxOddsPrecision bounds configured precisions to something displayable
+const maxOddsPrecision = 6
+
+// sportPrecision maps sports to their display precision (SPORT_PRECISION)
+var sportPrecision = map[string]int{}
+
+// parseSportPrecision parses "sport=decimals,..." (e.g. "horse-racing=1")
+func parseSportPrecision(items []string) (map[string]int, error) {
+	precisions := make(map[string]int, len(items))
+	for _, item := range items {
+		sport, raw, ok := strings.Cut(item, "=")
+		sport = strings.TrimSpace(sport)
+		if !ok || sport == "" {
+			return nil, fmt.Errorf("%q: expected sport=decimals", item)
+		}
+		decimals, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || decimals < 0 || decimals > maxOddsPrecision {
+			return nil, fmt.Errorf("%q: decimals must be an integer between 0 and %d", item, maxOddsPrecision)
+		}
+		precisions[sport] = decimals
+	}
+	return precisions, nil
+}
+
+// precisionFor is the display precision of a sport's odds
+func precisionFor(sport string) int {
+	if decimals, ok := sportPrecision[sport]; ok {
+		return decimals
+	}
+	return oddsPrecision
+}
+
+// roundOdds rounds odds to a display precision
+func roundOdds(odds float64, precision int) float64 {
+	scale := math.Pow(10, float64(precision))
+	return math.Round(odds*scale) / scale
+}
+
+// displayKey fingerprints the game as clients would display it, so updates
+// that don't change anything visible can be told apart
+func (g *GameState) displayKey() string {
+	precision := precisionFor(g.Sport)
+	key := fmt.Sprintf("%d-%d@%d", g.HomeScore, g.AwayScore, g.Minute)
+	for _, name := range marketNames {
+		if m, ok := g.Markets[name]; ok {
+			key += fmt.Sprintf("|%s:%.*f:%s", name, precision, roundOdds(m.Odds, precision), m.Status)
+		}
+	}
+	return key
+}
+
+// sortGames orders games for listings: featured games first, then by ID
+func sortGames(list []GameState) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Featured != list[j].Featured {
+			return list[i].Featured
+		}
+		return list[i].ID < list[j].ID
+	})
+}
+
+// defaultGames are the built-in fixtures used when no GAMES_CONFIG is given
+func defaultGames() []GameState {
+	return []GameState{
+		{ID: "game1", HomeTeam: "Arsenal", AwayTeam: "Chelsea", League: "premier-league", HomeScore: 1, AwayScore: 1, Minute: 34, HomeOdds: 2.5, AwayOdds: 2.8, DrawOdds: 3.2},
+		{ID: "game2", HomeTeam: "Liverpool", AwayTeam: "Man United", League: "premier-league", HomeScore: 2, AwayScore: 0, Minute: 58, HomeOdds: 1.8, AwayOdds: 4.2, DrawOdds: 3.5},
+		{ID: "game3", HomeTeam: "Barcelona", AwayTeam: "Real Madrid", League: "la-liga", HomeScore: 0, AwayScore: 0, Minute: 12, HomeOdds: 2.1, AwayOdds: 3.3, DrawOdds: 3.0},
+	}
+}
+
+// loadGamesConfig reads a JSON array of games from path, rejecting the whole
+// file if any game is invalid or IDs repeat
+func loadGamesConfig(path string) ([]GameState, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var states []GameState
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&states); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(states) == 0 {
+		return nil, fmt.Errorf("%s defines no games", path)
+	}
+
+	seen := make(map[string]bool, len(states))
+	for i := range states {
+		if err := validateGame(&states[i]); err != nil {
+			return nil, fmt.Errorf("game #%d (%s): %w", i, states[i].ID, err)
+		}
+		if seen[states[i].ID] {
+			return nil, fmt.Errorf("game #%d: duplicate id %q", i, states[i].ID)
+		}
+		seen[states[i].ID] = true
+	}
+	return states, nil
+}
+
+// initializeGames registers the starting games, mid-match if they were given
+// a score and minute
+func initializeGames(states []GameState) {
+	registry.Batch(func(games map[string]*Game) {
+		clear(games)
+		for _, state := range states {
+			state.LastUpdated = time.Now().UnixMilli()
+			state.applyDefaults()
+			state.initMarkets()
+			games[state.ID] = newGame(state)
+		}
+	})
+}
+
+// applyDefaults fills in optional fields left empty at creation
+func (g *GameState) applyDefaults() {
+	if g.Sport == "" {
+		g.Sport = sportFootball
+	}
+	if g.Status == "" {
+		g.Status = statusLive
+		if g.KickoffTime > time.Now().UnixMilli() {
+			g.Status = statusScheduled
+		}
+	}
+	if g.Importance == 0 {
+		g.Importance = 1
+	}
+	g.initStats()
+	g.TTLMs = oddsTTL.Milliseconds()
+}
+
+// initMarkets builds the 1X2 markets from the top-level odds fields. A
+// market's volatility can be preset (e.g. from the games config); it defaults
+// to 1.
+func (g *GameState) initMarkets() {
+	odds := map[string]float64{marketHome: g.HomeOdds, marketAway: g.AwayOdds, marketDraw: g.DrawOdds}
+
+	markets := make(map[string]*Market, len(odds))
+	for name, price := range odds {
+		volatility := 1.0
+		if preset, ok := g.Markets[name]; ok && preset.Volatility > 0 {
+			volatility = preset.Volatility
+		}
+		markets[name] = &Market{Odds: price, Status: marketOpen, Volatility: volatility, LastUpdated: g.LastUpdated, TTLMs: marketTTL(name)}
+	}
+	g.Markets = markets
+}
+
+// clone deep-copies the game so it can be used outside the registry lock
+func (g *GameState) clone() GameState {
+	c := *g
+	c.Tags = slices.Clone(g.Tags)
+	c.Stats = maps.Clone(g.Stats)
+	c.Markets = make(map[string]*Market, len(g.Markets))
+	for name, m := range g.Markets {
+		copied := *m
+		c.Markets[name] = &copied
+	}
+	return c
+}
+
+// setOdds updates a market and keeps the top-level 1X2 fields in sync. A NaN
+// or infinite price would fail JSON encoding and drop the update, so it is
+// rejected and the market keeps its last valid odds. Voided markets never
+// move again. Every price is snapped to ODDS_TICK_SIZE here, whatever moved
+// it, without going under the floor.
+func (g *GameState) setOdds(market string, odds float64, now int64) {
+	m, ok := g.Markets[market]
+	if !ok || m.Voided {
+		return
+	}
+	if math.IsNaN(odds) || math.IsInf(odds, 0) {
+		atomic.AddInt64(&metrics.invalidOdds, 1)
+		log.Printf("⚠️  WARN: %s %s odds computed as %v, keeping %v", g.ID, market, odds, m.Odds)
+		return
+	}
+	odds = snapToTick(odds, oddsTickSize)
+	if oddsTickSize > 0 && odds < oddsFloor {
+		odds = cleanTick(math.Ceil(oddsFloor/oddsTickSize) * oddsTickSize)
+	}
+	m.Odds = odds
+	m.LastUpdated = now
+
+	switch market {
+	case marketHome:
+		g.HomeOdds = odds
+	case marketAway:
+		g.AwayOdds = odds
+	case marketDraw:
+		g.DrawOdds = odds
+	}
+}
+
+// maxStartMinute is the latest minute a game can be joined at
+const maxStartMinute = 120
+
+// impliedProbability is the 1X2 book's total implied probability. Below 1
+// the book offers arbitrage; well above 1 the margin is implausibly fat.
+func (g *GameState) impliedProbability() float64 {
+	return 1/g.HomeOdds + 1/g.AwayOdds + 1/g.DrawOdds
+}
+
+// bookMin and bookMax bound a game's total implied probability
+// (BOOK_MARGIN_MIN, BOOK_MARGIN_MAX), at startup and on every reload
+var bookMin, bookMax = 1.00, 1.15
+
+// checkBooks returns a message for every game whose book falls outside
+// [min, max] total implied probability
+func checkBooks(states []GameState, min, max float64) []string {
+	var problems []string
+	for i := range states {
+		if p := states[i].impliedProbability(); p < min || p > max {
+			problems = append(problems, fmt.Sprintf("%s: implied probability %.3f outside %.2f-%.2f", states[i].ID, p, min, max))
+		}
+	}
+	return problems
+}
+
+// maxImportance bounds how much busier than a regular match a game can be
+const maxImportance = 10.0
+
+// validateGame checks a game submitted through the API before it's registered
+func validateGame(g *GameState) error {
+	switch {
+	case g.ID == "":
+		return errors.New("id is required")
+	case g.HomeTeam == "" || g.AwayTeam == "":
+		return errors.New("homeTeam and awayTeam are required")
+	case g.HomeOdds <= 1.01 || g.AwayOdds <= 1.01 || g.DrawOdds <= 1.01:
+		return errors.New("homeOdds, awayOdds and drawOdds must be greater than 1.01")
+	case g.HomeScore < 0 || g.AwayScore < 0:
+		return errors.New("scores must not be negative")
+	case g.Attendance < 0 || g.Attendance > maxAttendance:
+		return fmt.Errorf("attendance must be between 0 and %d", maxAttendance)
+	case g.Importance < 0 || g.Importance > maxImportance:
+		return fmt.Errorf("importance must be positive and at most %v", maxImportance)
+	case g.Minute < 0 || g.Minute > maxStartMinute:
+		return fmt.Errorf("minute must be between 0 and %d", maxStartMinute)
+	case g.Status == statusScheduled && g.KickoffTime <= 0:
+		return errors.New("scheduled games need a kickoffTime")
+	case g.KickoffTime < 0:
+		return errors.New("kickoffTime must not be negative")
+	case g.Shock:
+		return errors.New("shock is set by the feed and can't be given")
+	case g.Stopped:
+		return errors.New("clockStopped is set by the feed and can't be given")
+	case g.Suspended:
+		return errors.New("suspended is set through POST /games/{id}/suspend and can't be given")
+	case g.Remaining != nil:
+		return errors.New("remainingPublishes is set by the feed and can't be given")
+	case g.Tier != "" && tierIntervals[g.Tier] == 0:
+		return fmt.Errorf("unknown tier %q: expected one of %v", g.Tier, tierNames())
+	}
+	if err := validateSquads(g.Squads); err != nil {
+		return err
+	}
+	if err := validateStats(g.Stats); err != nil {
+		return err
+	}
+	for _, tag := range g.Tags {
+		if tag == "" || strings.ContainsAny(tag, " \t\n{}.") {
+			return fmt.Errorf("tag %q must be non-empty without whitespace, braces or dots", tag)
+		}
+	}
+	for name, m := range g.Markets {
+		if !isMarket(name) {
+			return fmt.Errorf("unknown market %q", name)
+		}
+		if m == nil || m.Volatility < 0 {
+			return fmt.Errorf("market %q: volatility must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// hasTag reports whether the game carries tag, ignoring case
+func (g *GameState) hasTag(tag string) bool {
+	for _, t := range g.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func isMarket(name string) bool {
+	for _, m := range marketNames {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GamePatch is a partial update to a running game; nil fields are unchanged
+type GamePatch struct {
+	HomeScore *int                   `json:"homeScore"`
+	AwayScore *int                   `json:"awayScore"`
+	HomeOdds  *float64               `json:"homeOdds"`
+	AwayOdds  *float64               `json:"awayOdds"`
+	DrawOdds  *float64               `json:"drawOdds"`
+	Featured  *bool                  `json:"featured"`
+	Markets   map[string]MarketPatch `json:"markets"`
+}
+
+// MarketPatch is a partial update to one market
+type MarketPatch struct {
+	Odds       *float64 `json:"odds"`
+	Volatility *float64 `json:"volatility"`
+}
+
+// validate checks the patch on its own, before it's applied to any game
+func (p GamePatch) validate() error {
+	for _, score := range []*int{p.HomeScore, p.AwayScore} {
+		if score != nil && *score < 0 {
+			return errors.New("scores must not be negative")
+		}
+	}
+	for _, odds := range []*float64{p.HomeOdds, p.AwayOdds, p.DrawOdds} {
+		if odds != nil && *odds <= 1.01 {
+			return errors.New("odds must be greater than 1.01")
+		}
+	}
+	for name, m := range p.Markets {
+		if !isMarket(name) {
+			return fmt.Errorf("unknown market %q", name)
+		}
+		if m.Odds != nil && *m.Odds <= 1.01 {
+			return fmt.Errorf("market %q: odds must be greater than 1.01", name)
+		}
+		if m.Volatility != nil && *m.Volatility < 0 {
+			return fmt.Errorf("market %q: volatility must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// apply writes a validated patch to the game. Call under the registry lock.
+func (p GamePatch) apply(g *GameState, now int64) {
+	if p.HomeScore != nil {
+		g.HomeScore = *p.HomeScore
+	}
+	if p.AwayScore != nil {
+		g.AwayScore = *p.AwayScore
+	}
+	if p.Featured != nil {
+		g.Featured = *p.Featured
+	}
+	for market, odds := range map[string]*float64{marketHome: p.HomeOdds, marketAway: p.AwayOdds, marketDraw: p.DrawOdds} {
+		if odds != nil {
+			g.setOdds(market, *odds, now)
+		}
+	}
+	for name, m := range p.Markets {
+		if m.Odds != nil {
+			g.setOdds(name, *m.Odds, now)
+		}
+		if m.Volatility != nil {
+			g.Markets[name].Volatility = *m.Volatility
+		}
+	}
+	g.LastUpdated = now
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// onTick reports whether odds sit on the ladder, allowing for float error
+func onTick(odds, tick float64) bool {
+	steps := odds / tick
+	return math.Abs(steps-math.Round(steps)) < 1e-6
+}
+
+func checkOnTick(t *testing.T, where string, state *GameState) {
+	t.Helper()
+	for name, m := range state.Markets {
+		if !onTick(m.Odds, oddsTickSize) {
+			t.Fatalf("%s: %s market %s at %v, off the %v tick", where, state.ID, name, m.Odds, oddsTickSize)
+		}
+	}
+	for _, odds := range []float64{state.HomeOdds, state.AwayOdds, state.DrawOdds} {
+		if !onTick(odds, oddsTickSize) {
+			t.Fatalf("%s: %s top-level odds %v off the %v tick", where, state.ID, odds, oddsTickSize)
+		}
+	}
+}
+
+func TestPublishedOddsStayOnTick(t *testing.T) {
+	oddsTickSize = 0.05
+	defer func() { oddsTickSize = 0 }()
+	previous := goalProbability
+	goalProbability = 0.2
+	defer func() { goalProbability = previous }()
+	useGames(t)
+
+	for i := 0; i < 300; i++ {
+		for _, game := range registry.Games() {
+			registry.UpdateGame(game, func(game *Game) {
+				if update, ok := stepGame(game); ok {
+					checkOnTick(t, "tick", &update.state)
+				}
+			})
+		}
+	}
+
+	// Goals and admin patches move odds outside the drift step
+	registry.Update("game1", func(game *Game) {
+		shortenOdds(&game.GameState, marketHome, marketAway, game.now())
+		checkOnTick(t, "goal", &game.GameState)
+
+		odds := 2.013
+		GamePatch{HomeOdds: &odds, Markets: map[string]MarketPatch{marketDraw: {Odds: &odds}}}.apply(&game.GameState, game.now())
+		checkOnTick(t, "patch", &game.GameState)
+		if game.HomeOdds != 2 {
+			t.Errorf("patched homeOdds = %v, want 2", game.HomeOdds)
+		}
+	})
+}
+
+func TestSetOddsSnapsAboveFloor(t *testing.T) {
+	oddsTickSize = 0.05
+	defer func() { oddsTickSize = 0 }()
+
+	state := defaultGames()[0]
+	state.applyDefaults()
+	state.initMarkets()
+	state.setOdds(marketHome, 1.011, 0)
+	if state.HomeOdds < oddsFloor || !onTick(state.HomeOdds, oddsTickSize) {
+		t.Errorf("homeOdds = %v, want the first tick above %v", state.HomeOdds, oddsFloor)
+	}
+}
+
+func TestSnapToTickDropsFloatError(t *testing.T) {
+	if got := snapToTick(2.81, 0.05); got != 2.8 {
+		t.Errorf("snapToTick(2.81, 0.05) = %v, want 2.8", got)
+	}
+	if got := snapDownToTick(2.8, 0.05); got != 2.8 {
+		t.Errorf("snapDownToTick(2.8, 0.05) = %v, want 2.8", got)
+	}
+}
+
+// draws takes the first few numbers from a game's RNG
+func draws(game *Game) [5]int64 {
+	var out [5]int64
+	for i := range out {
+		out[i] = game.rng.Int63()
+	}
+	return out
+}
+
+func TestGameRNGIsSeededPerGame(t *testing.T) {
+	previous := simSeed
+	defer func() { simSeed = previous }()
+	simSeed = 42
+	states := defaultGames()
+
+	first, again := draws(newGame(states[0])), draws(newGame(states[0]))
+	if first != again {
+		t.Errorf("same seed and ID drew %v then %v, want the same sequence", first, again)
+	}
+	if other := draws(newGame(states[1])); other == first {
+		t.Errorf("%s and %s drew the same sequence %v", states[0].ID, states[1].ID, first)
+	}
+	simSeed = 43
+	if reseeded := draws(newGame(states[0])); reseeded == first {
+		t.Errorf("SIM_SEED 42 and 43 drew the same sequence %v", first)
+	}
+}
+
+func TestLoadGamesConfigStartsMidMatch(t *testing.T) {
+	useGames(t)
+	state := defaultGames()[0]
+	state.HomeScore, state.AwayScore, state.Minute = 2, 1, 60
+	states, err := loadGamesConfig(writeGamesConfig(t, []GameState{state}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	initializeGames(states)
+
+	registry.View(state.ID, func(game *Game) {
+		if game.HomeScore != 2 || game.AwayScore != 1 || game.Minute != 60 || game.clock != 3600 {
+			t.Errorf("started at %d-%d minute %d clock %vs, want 2-1 minute 60 clock 3600s", game.HomeScore, game.AwayScore, game.Minute, game.clock)
+		}
+	})
+
+	for _, bad := range []GameState{
+		{ID: "neg", HomeTeam: "A", AwayTeam: "B", HomeOdds: 2, AwayOdds: 2, DrawOdds: 3, HomeScore: -1},
+		{ID: "late", HomeTeam: "A", AwayTeam: "B", HomeOdds: 2, AwayOdds: 2, DrawOdds: 3, Minute: maxStartMinute + 1},
+	} {
+		if _, err := loadGamesConfig(writeGamesConfig(t, []GameState{bad})); err == nil {
+			t.Errorf("loadGamesConfig accepted %s (score %d, minute %d)", bad.ID, bad.HomeScore, bad.Minute)
+		}
+	}
+}
+
+func TestNowHoldsWhenClockGoesBack(t *testing.T) {
+	game := testGame()
+	ahead := time.Now().Add(time.Hour).UnixMilli()
+	game.LastUpdated = ahead
+
+	if got := game.now(); got != ahead || !game.clockSkewed {
+		t.Errorf("now() = %d skewed=%v with LastUpdated an hour ahead, want %d and skewed", got, game.clockSkewed, ahead)
+	}
+	game.LastUpdated = time.Now().Add(-time.Second).UnixMilli()
+	if got := game.now(); got < game.LastUpdated || game.clockSkewed {
+		t.Errorf("now() = %d skewed=%v once the clock caught up, want the wall clock and not skewed", got, game.clockSkewed)
+	}
+}
+
+func TestIncludeRawOddsPublishesBoth(t *testing.T) {
+	includeRawOdds = true
+	defer func() { includeRawOdds = false }()
+	state := testGame().GameState
+	state.HomeOdds = 2.34567
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["homeOdds"] != 2.35 || got["homeOddsRaw"] != 2.34567 {
+		t.Errorf("homeOdds=%v homeOddsRaw=%v, want 2.35 and 2.34567", got["homeOdds"], got["homeOddsRaw"])
+	}
+	if got["id"] != state.ID || got["markets"] == nil {
+		t.Errorf("the rest of the state is missing: id=%v markets=%v", got["id"], got["markets"])
+	}
+
+	includeRawOdds = false
+	data, _ = json.Marshal(state)
+	if strings.Contains(string(data), "OddsRaw") {
+		t.Errorf("raw odds published without INCLUDE_RAW_ODDS: %s", data)
+	}
+}
+
+func TestSportPrecision(t *testing.T) {
+	precisions, err := parseSportPrecision([]string{"horse-racing=1", " tennis = 3 "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := sportPrecision
+	sportPrecision = precisions
+	defer func() { sportPrecision = previous }()
+
+	for _, tt := range []struct {
+		sport string
+		want  float64
+	}{
+		{"horse-racing", 2.3},
+		{"tennis", 2.346},
+		{"football", 2.35},
+	} {
+		if got := roundOdds(2.34567, precisionFor(tt.sport)); got != tt.want {
+			t.Errorf("%s odds 2.34567 display as %v, want %v", tt.sport, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"tennis", "=2", "tennis=x", "tennis=-1", "tennis=7"} {
+		if _, err := parseSportPrecision([]string{bad}); err == nil {
+			t.Errorf("parseSportPrecision(%q) = nil error, want one", bad)
+		}
+	}
+}
+
+func TestSetOddsRejectsNaNAndInf(t *testing.T) {
+	game := testGame()
+	valid := game.HomeOdds
+	before := atomic.LoadInt64(&metrics.invalidOdds)
+
+	for _, odds := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		game.setOdds(marketHome, odds, 1)
+	}
+	if game.HomeOdds != valid || game.Markets[marketHome].Odds != valid {
+		t.Errorf("home odds = %v (market %v) after NaN/Inf, want %v kept", game.HomeOdds, game.Markets[marketHome].Odds, valid)
+	}
+	if got := atomic.LoadInt64(&metrics.invalidOdds) - before; got != 3 {
+		t.Errorf("invalidOdds grew by %d, want 3", got)
+	}
+	if _, err := json.Marshal(game.GameState); err != nil {
+		t.Errorf("state no longer encodes: %v", err)
+	}
+}
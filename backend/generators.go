@@ -0,0 +1,121 @@
+package main
+
+import "math/rand"
+
+// EventGenerator produces match events for a game each tick. Generators run
+// under the registry lock and may mutate the game (scores, odds) alongside
+// the events they return; game.LastUpdated is the tick's timestamp.
+type EventGenerator interface {
+	Generate(game *Game, r *rand.Rand) []MatchEvent
+}
+
+// eventGenerators run in registration order on every published tick
+var eventGenerators []EventGenerator
+
+// registerEventGenerator adds a generator to the publish loop. Register
+// generators in main before the feed starts.
+func registerEventGenerator(g EventGenerator) {
+	eventGenerators = append(eventGenerators, g)
+}
+
+// generateEvents runs every registered generator against a game
+func generateEvents(game *Game) []MatchEvent {
+	var events []MatchEvent
+	for _, g := range eventGenerators {
+		events = append(events, g.Generate(game, game.rng)...)
+	}
+	return events
+}
+
+// eventProbability is the per-tick chance of an event in a game, from its
+// probability in a regular match at real time
+func eventProbability(game *Game, p float64) float64 {
+	return scaledProbability(p * game.Importance)
+}
+
+// goalGenerator rolls for a goal each tick, updating the score and
+// shortening the scorer's odds. A game can't score again until goalCooldown
+// of match time has passed. Only football-playing sports score goals, and
+// scripted games only score from their scenario.
+type goalGenerator struct{}
+
+func (goalGenerator) Generate(game *Game, r *rand.Rand) []MatchEvent {
+	if !playsFootball(game.Sport) || scripted(game) || game.clock < game.nextGoalClock || r.Float64() >= eventProbability(game, goalProbability) {
+		return nil
+	}
+	game.nextGoalClock = game.clock + goalCooldown.Seconds()
+
+	team := teamHome
+	if r.Float64() < 0.5 {
+		team = teamAway
+	}
+	squad := game.squad(team)
+
+	now := game.LastUpdated
+	before, oddsBefore := game.AwayScore, game.prices()
+	if team == teamHome {
+		before = game.HomeScore
+		game.HomeScore++
+		shortenOdds(&game.GameState, marketHome, marketAway, now)
+	} else {
+		game.AwayScore++
+		shortenOdds(&game.GameState, marketAway, marketHome, now)
+	}
+
+	event := MatchEvent{
+		GameID:    game.ID,
+		Type:      eventGoal,
+		Team:      team,
+		HomeScore: game.HomeScore,
+		AwayScore: game.AwayScore,
+		Minute:    game.Minute,
+		Timestamp: now,
+	}
+
+	// Named scorers need a non-empty squad to draw from
+	if len(squad) > 0 {
+		scorer := r.Intn(len(squad))
+		event.Player = squad[scorer]
+		if len(squad) > 1 && r.Float64() < assistProbability {
+			assist := (scorer + 1 + r.Intn(len(squad)-1)) % len(squad)
+			event.AssistBy = squad[assist]
+		}
+	}
+
+	reviewGoal(game, event, before, oddsBefore, r)
+	return []MatchEvent{event}
+}
+
+// cardGenerator books a player now and then, mostly yellows, in
+// football-playing sports other than scripted games
+type cardGenerator struct{}
+
+func (cardGenerator) Generate(game *Game, r *rand.Rand) []MatchEvent {
+	if !playsFootball(game.Sport) || scripted(game) || r.Float64() >= eventProbability(game, cardProbability) {
+		return nil
+	}
+
+	team := teamHome
+	if r.Float64() < 0.5 {
+		team = teamAway
+	}
+	squad := game.squad(team)
+
+	event := MatchEvent{
+		GameID:    game.ID,
+		Type:      eventYellowCard,
+		Team:      team,
+		HomeScore: game.HomeScore,
+		AwayScore: game.AwayScore,
+		Minute:    game.Minute,
+		Timestamp: game.LastUpdated,
+	}
+	if r.Float64() < redCardShare {
+		event.Type = eventRedCard
+	}
+	if len(squad) > 0 {
+		event.Player = squad[r.Intn(len(squad))]
+	}
+
+	return []MatchEvent{event}
+}
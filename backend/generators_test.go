@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestGoalCooldownSpacesGoals(t *testing.T) {
+	previous := goalProbability
+	goalProbability = 1
+	defer func() { goalProbability = previous }()
+	game := testGame()
+	r := rand.New(rand.NewSource(1))
+
+	var goalClocks []float64
+	for game.clock = 0; game.clock < 600; game.clock += 10 {
+		if events := (goalGenerator{}).Generate(game, r); len(events) > 0 {
+			goalClocks = append(goalClocks, game.clock)
+		}
+	}
+	// A goal every tick is possible, so the cooldown alone spaces them
+	if len(goalClocks) != 10 {
+		t.Errorf("%d goals in 10 match minutes at probability 1, want one a minute: %v", len(goalClocks), goalClocks)
+	}
+	for i := 1; i < len(goalClocks); i++ {
+		if gap := goalClocks[i] - goalClocks[i-1]; gap < goalCooldown.Seconds() {
+			t.Errorf("goals at %vs and %vs are %vs apart, under the %s cooldown", goalClocks[i-1], goalClocks[i], gap, goalCooldown)
+		}
+	}
+}
+
+func TestImportanceScalesEventRates(t *testing.T) {
+	previous := cardProbability
+	cardProbability = 0.01
+	defer func() { cardProbability = previous }()
+
+	cards := func(importance float64) int {
+		game := testGame()
+		game.Importance = importance
+		r := rand.New(rand.NewSource(7))
+		n := 0
+		for i := 0; i < 20000; i++ {
+			n += len((cardGenerator{}).Generate(game, r))
+		}
+		return n
+	}
+	low, regular, high := cards(0.5), cards(1), cards(3)
+	if !(low < regular && regular < high) {
+		t.Errorf("cards at importance 0.5, 1, 3 = %d, %d, %d, want more for more important games", low, regular, high)
+	}
+
+	for _, importance := range []float64{-1, maxImportance + 1} {
+		state := defaultGames()[0]
+		state.Importance = importance
+		if err := validateGame(&state); err == nil {
+			t.Errorf("validateGame accepts importance %v", importance)
+		}
+	}
+}
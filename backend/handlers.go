@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Error encoding response: %v", err)
+		http.Error(w, "error encoding response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(withOutputCase(data), '\n'))
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// maxBodyBytes caps request bodies on the write endpoints
+const maxBodyBytes = 1 << 20
+
+// decodeJSONBody strictly decodes a request body into v, turning decoder
+// errors into messages that point at the offending field or position
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(v)
+	if err == nil && dec.More() {
+		return errors.New("request body must contain a single JSON object")
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxErr *http.MaxBytesError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, io.EOF):
+		return errors.New("request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("request body is truncated JSON")
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("malformed JSON at position %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		return fmt.Errorf("field %q must be of type %s", typeErr.Field, typeErr.Type)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return fmt.Errorf("unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	case errors.As(err, &maxErr):
+		return fmt.Errorf("request body exceeds %d bytes", maxErr.Limit)
+	default:
+		return fmt.Errorf("invalid JSON body: %v", err)
+	}
+}
+
+// handleGames serves the /games collection
+func handleGames(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		handleListGames(w, r)
+	case http.MethodPost:
+		handleCreateGame(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// GET /games, optionally ?tag= to list only games carrying that tag
+func handleListGames(w http.ResponseWriter, r *http.Request) {
+	tag := r.URL.Query().Get("tag")
+
+	list := []GameState{}
+	for _, state := range registry.Snapshot() {
+		if tag == "" || state.hasTag(tag) {
+			list = append(list, state)
+		}
+	}
+
+	sortGames(list)
+	writeJSON(w, http.StatusOK, list)
+}
+
+// POST /games registers a new game; the publisher picks it up next tick
+func handleCreateGame(w http.ResponseWriter, r *http.Request) {
+	var game GameState
+	if err := decodeJSONBody(w, r, &game); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateGame(&game); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	game.LastUpdated = time.Now().UnixMilli()
+	game.applyDefaults()
+	game.initMarkets()
+
+	// The ID and matchup are checked under the same lock the game is added
+	// in. The game shares its markets with the registry from then on, so the
+	// response is copied under the lock too.
+	var status int
+	var message string
+	var created GameState
+	registry.Batch(func(games map[string]*Game) {
+		if _, exists := games[game.ID]; exists {
+			status, message = http.StatusConflict, "game already exists: "+game.ID
+			return
+		}
+		if id, ok := existingMatchup(games, &game); ok {
+			if strictConfig {
+				status, message = http.StatusConflict, fmt.Sprintf("same matchup as %s: %s v %s", id, game.HomeTeam, game.AwayTeam)
+				return
+			}
+			log.Printf("⚠️  WARN: duplicate matchup for %s: same as %s (%s v %s)", game.ID, id, game.HomeTeam, game.AwayTeam)
+		}
+		games[game.ID] = newGame(game)
+		created = game.clone()
+	})
+	if status != 0 {
+		writeError(w, status, message)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// GET /games/search?team=&sport=&status= matches team names by
+// case-insensitive substring and sport/status exactly, sorted by ID
+func handleSearchGames(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	team := strings.ToLower(query.Get("team"))
+	sport := query.Get("sport")
+	status := query.Get("status")
+
+	results := []GameState{}
+	for _, game := range registry.Snapshot() {
+		if team != "" && !strings.Contains(strings.ToLower(game.HomeTeam), team) && !strings.Contains(strings.ToLower(game.AwayTeam), team) {
+			continue
+		}
+		if sport != "" && !strings.EqualFold(game.Sport, sport) {
+			continue
+		}
+		if status != "" && !strings.EqualFold(game.Status, status) {
+			continue
+		}
+		results = append(results, game)
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
+
+// handleGameRoutes dispatches /games/{id}/... requests
+func handleGameRoutes(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/games/"), "/"), "/")
+	gameID := parts[0]
+
+	switch {
+	case len(parts) == 1 && gameID == "search" && r.Method == http.MethodGet:
+		handleSearchGames(w, r)
+	case len(parts) == 1 && gameID == "bulk" && r.Method == http.MethodPost:
+		handleBulkCreateGames(w, r)
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		handleGetGame(w, gameID)
+	case len(parts) == 1 && r.Method == http.MethodPatch:
+		handlePatchGame(w, r, gameID)
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		handleDeleteGame(w, gameID)
+	case len(parts) == 2 && (parts[1] == "latest" || parts[1] == "state") && r.Method == http.MethodGet:
+		handleGameLatest(w, gameID)
+	case len(parts) == 2 && parts[1] == "history" && r.Method == http.MethodGet:
+		handleGameHistory(w, r, gameID)
+	case len(parts) == 2 && parts[1] == "history.csv" && r.Method == http.MethodGet:
+		handleGameHistoryCSV(w, gameID)
+	case len(parts) == 2 && parts[1] == "stream" && r.Method == http.MethodGet:
+		handleGameStream(w, r, gameID)
+	case len(parts) == 2 && parts[1] == "suspend" && r.Method == http.MethodPost:
+		handleSetGameSuspended(w, gameID, true)
+	case len(parts) == 2 && parts[1] == "resume" && r.Method == http.MethodPost:
+		handleSetGameSuspended(w, gameID, false)
+	case len(parts) == 2 && parts[1] == "markets" && r.Method == http.MethodGet:
+		handleGameMarkets(w, gameID)
+	case len(parts) == 4 && parts[1] == "markets" && parts[3] == "suspend" && r.Method == http.MethodPost:
+		handleSetMarketStatus(w, gameID, parts[2], marketSuspended)
+	case len(parts) == 4 && parts[1] == "markets" && parts[3] == "resume" && r.Method == http.MethodPost:
+		handleSetMarketStatus(w, gameID, parts[2], marketOpen)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// GET /games/{id} returns the game's current state, with the publishes it
+// has left when MAX_PUBLISHES_PER_GAME is set
+func handleGetGame(w http.ResponseWriter, gameID string) {
+	var state GameState
+	if !registry.View(gameID, func(game *Game) {
+		state = game.clone()
+		if remaining := game.remainingPublishes(); remaining >= 0 {
+			state.Remaining = &remaining
+		}
+	}) {
+		writeError(w, http.StatusNotFound, "game not found: "+gameID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, state)
+}
+
+// GET /games/{id}/markets
+func handleGameMarkets(w http.ResponseWriter, gameID string) {
+	state, ok := registry.Get(gameID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "game not found: "+gameID)
+		return
+	}
+	markets := make(map[string]Market, len(state.Markets))
+	for name, m := range state.Markets {
+		markets[name] = *m
+	}
+
+	writeJSON(w, http.StatusOK, markets)
+}
+
+// PATCH /games/{id} sets scores, odds or market volatility by hand
+func handlePatchGame(w http.ResponseWriter, r *http.Request, gameID string) {
+	var patch GamePatch
+	if err := decodeJSONBody(w, r, &patch); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := patch.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var updated GameState
+	if !registry.Update(gameID, func(game *Game) {
+		patch.apply(&game.GameState, game.now())
+		updated = game.clone()
+	}) {
+		writeError(w, http.StatusNotFound, "game not found: "+gameID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// DELETE /games/{id} publishes the game's final state before removing it
+func handleDeleteGame(w http.ResponseWriter, gameID string) {
+	var final gameUpdate
+	var hasFinal bool
+	if !registry.Remove(gameID, func(game *Game) { final, hasFinal = finalUpdate(game) }) {
+		writeError(w, http.StatusNotFound, "game not found: "+gameID)
+		return
+	}
+
+	if hasFinal {
+		publishFinal(gameID, final)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /games/{id}/markets/{market}/suspend|resume
+func handleSetMarketStatus(w http.ResponseWriter, gameID, market, status string) {
+	var updated Market
+	code, message := http.StatusNotFound, "game not found: "+gameID
+	registry.Update(gameID, func(game *Game) {
+		m, ok := game.Markets[market]
+		switch {
+		case !ok:
+			message = "market not found: " + market
+		case m.Voided:
+			code, message = http.StatusConflict, "market is voided: "+market
+		case m.Status == marketSettled:
+			code, message = http.StatusConflict, "market is settled: "+market
+		default:
+			m.Status = status
+			m.LastUpdated = game.now()
+			updated, code = *m, http.StatusOK
+		}
+	})
+	if code != http.StatusOK {
+		writeError(w, code, message)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// GET /games/{id}/latest (or /state) returns the payload last published for the game,
+// byte-for-byte as it went out on the channel
+func handleGameLatest(w http.ResponseWriter, gameID string) {
+	data, err := latest.GetLatest(ctx, gameID)
+	if errors.Is(err, errNoLatest) {
+		writeError(w, http.StatusNotFound, "no published state for game: "+gameID)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "error reading latest state: "+err.Error())
+		return
+	}
+
+	contentType := "application/json"
+	if !isPlainJSON(data) {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(data)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// serve runs one request through a handler and returns the recorded response
+func serve(handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func TestCreateAndDeleteGamesDuringTicks(t *testing.T) {
+	useGames(t)
+	queue, err := newPublishQueue(256, policyDropNewest, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := publishQueue
+	publishQueue = queue
+	t.Cleanup(func() { publishQueue = previous })
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			publishTick()
+			queue.drain()
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprintf("extra%d", i)
+		body := fmt.Sprintf(`{"id":%q,"homeTeam":"Home %d","awayTeam":"Away %d","homeOdds":2,"awayOdds":3,"drawOdds":3.2}`, id, i, i)
+		if rec := serve(handleGames, http.MethodPost, "/games", body); rec.Code != http.StatusCreated {
+			t.Fatalf("POST /games %s = %d: %s", id, rec.Code, rec.Body)
+		}
+		if rec := serve(handleGameRoutes, http.MethodDelete, "/games/"+id, ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("DELETE /games/%s = %d: %s", id, rec.Code, rec.Body)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	if n := registry.Len(); n != 3 {
+		t.Errorf("registry has %d games after creating and deleting, want 3", n)
+	}
+}
+
+func TestCreateGameRejectsMalformedBodies(t *testing.T) {
+	useGames(t)
+	cases := []struct {
+		body, want string
+	}{
+		{``, "request body is empty"},
+		{`{"id":"x","homeTeam":`, "request body is truncated JSON"},
+		{`{"id":"x",}`, "malformed JSON at position"},
+		{`{"id":7}`, `field "id" must be of type string`},
+		{`{"id":"x","colour":"red"}`, `unknown field "colour"`},
+		{`{"id":"x"} {"id":"y"}`, "request body must contain a single JSON object"},
+	}
+	for _, c := range cases {
+		rec := serve(handleGames, http.MethodPost, "/games", c.body)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("POST %q = %d, want 400", c.body, rec.Code)
+		}
+		var resp struct{ Error string }
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if !strings.Contains(resp.Error, c.want) {
+			t.Errorf("POST %q error = %q, want it to mention %q", c.body, resp.Error, c.want)
+		}
+	}
+	if n := registry.Len(); n != 3 {
+		t.Errorf("registry has %d games after malformed creates, want 3", n)
+	}
+}
+
+func TestPatchMarketVolatility(t *testing.T) {
+	useGames(t)
+	previous := marketUpdateProb
+	marketUpdateProb = 1
+	t.Cleanup(func() { marketUpdateProb = previous })
+
+	rec := serve(handleGameRoutes, http.MethodPatch, "/games/game1", `{"markets":{"home":{"volatility":0},"away":{"volatility":0.5}}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH volatility = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(handleGameRoutes, http.MethodPatch, "/games/game1", `{"markets":{"draw":{"volatility":-1}}}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("PATCH negative volatility = %d, want 400", rec.Code)
+	}
+
+	before, _ := registry.Get("game1")
+	moved := false
+	registry.Update("game1", func(game *Game) {
+		for i := 0; i < 20; i++ {
+			driftMarkets(game, game.now())
+		}
+		moved = game.Markets[marketAway].Odds != before.Markets[marketAway].Odds
+		if game.Markets[marketHome].Odds != before.Markets[marketHome].Odds {
+			t.Errorf("home odds moved %v -> %v at volatility 0", before.Markets[marketHome].Odds, game.Markets[marketHome].Odds)
+		}
+	})
+	if !moved {
+		t.Error("away odds never moved at volatility 0.5")
+	}
+}
+
+func TestAdminChangesKeepGameClock(t *testing.T) {
+	useGames(t)
+	ahead := time.Now().Add(time.Hour).UnixMilli()
+	registry.Update("game1", func(game *Game) { game.LastUpdated = ahead })
+
+	if rec := serve(handleGameRoutes, http.MethodPost, "/games/game1/markets/home/suspend", ""); rec.Code != http.StatusOK {
+		t.Fatalf("suspend market = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(handleGameRoutes, http.MethodPost, "/games/game1/suspend", ""); rec.Code != http.StatusOK {
+		t.Fatalf("suspend game = %d: %s", rec.Code, rec.Body)
+	}
+	state, _ := registry.Get("game1")
+	if state.LastUpdated != ahead || state.Markets[marketHome].LastUpdated != ahead {
+		t.Errorf("lastUpdated game=%d market=%d after admin changes, want both held at %d", state.LastUpdated, state.Markets[marketHome].LastUpdated, ahead)
+	}
+}
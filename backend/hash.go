@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"log"
+)
+
+// HashWriter is implemented by brokers that can keep each game's latest
+// state as a Redis hash, so clients can HGETALL it without subscribing
+type HashWriter interface {
+	WriteHash(ctx context.Context, gameID string, fields map[string]interface{}) error
+}
+
+// hashWriter receives every published game state as a hash (WRITE_HASH);
+// nil when disabled
+var hashWriter HashWriter
+
+// hashKey is the Redis hash holding a game's latest published fields
+func hashKey(gameID string) string {
+	return "game:" + gameID
+}
+
+// stateHash flattens a game's state into hash fields, with each market's odds
+// and status under <market>Odds and <market>Status. It returns nil when
+// hashes aren't written.
+func stateHash(state GameState) map[string]interface{} {
+	if hashWriter == nil {
+		return nil
+	}
+	fields := map[string]interface{}{
+		"id":          state.ID,
+		"homeTeam":    state.HomeTeam,
+		"awayTeam":    state.AwayTeam,
+		"league":      state.League,
+		"sport":       state.Sport,
+		"status":      state.Status,
+		"homeScore":   state.HomeScore,
+		"awayScore":   state.AwayScore,
+		"minute":      state.Minute,
+		"lastUpdated": state.LastUpdated,
+	}
+	for name, m := range state.Markets {
+		fields[name+"Odds"] = m.Odds
+		fields[name+"Status"] = m.Status
+	}
+	return fields
+}
+
+// writeGameHash stores a published state's fields. Like the latest cache, a
+// failure is logged but doesn't fail the publish.
+func writeGameHash(gameID string, fields map[string]interface{}) {
+	if hashWriter == nil || fields == nil || skipsKey(hashKey(gameID)) {
+		return
+	}
+	if err := hashWriter.WriteHash(ctx, gameID, fields); isOOM(err) {
+		noteOOM(err)
+	} else if isWrongType(err) {
+		noteWrongType(err, hashKey(gameID))
+	} else if err != nil {
+		log.Printf("Error writing state hash for %s: %v", gameID, err)
+	}
+}
+
+func (p *redisPublisher) WriteHash(ctx context.Context, gameID string, fields map[string]interface{}) error {
+	return p.client.HSet(ctx, hashKey(gameID), fields).Err()
+}
+
+func (p *routedPublisher) WriteHash(ctx context.Context, gameID string, fields map[string]interface{}) error {
+	return p.forGame(gameID).WriteHash(ctx, gameID, fields)
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestWriteGameHashMirrorsState(t *testing.T) {
+	server, broker := redisBroker(t)
+	hashWriter = broker
+	defer func() { hashWriter = nil }()
+	state := testGame().clone()
+	state.HomeScore = 3
+
+	writeGameHash(state.ID, stateHash(state))
+
+	for field, want := range map[string]string{
+		"id":         state.ID,
+		"homeScore":  "3",
+		"homeTeam":   state.HomeTeam,
+		"homeStatus": marketOpen,
+	} {
+		if got := server.HGet(hashKey(state.ID), field); got != want {
+			t.Errorf("%s %s = %q, want %q", hashKey(state.ID), field, got, want)
+		}
+	}
+	if got := server.HGet(hashKey(state.ID), "drawOdds"); got == "" {
+		t.Error("the hash has no drawOdds")
+	}
+
+	hashWriter = nil
+	if fields := stateHash(state); fields != nil {
+		t.Errorf("stateHash without WRITE_HASH = %v, want nil", fields)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// lastTickAt is when the publish loop last completed a tick (unix millis)
+var lastTickAt atomic.Int64
+
+// lastTickAge is how long ago the publish loop last completed a tick
+func lastTickAge() time.Duration {
+	return time.Since(time.UnixMilli(lastTickAt.Load()))
+}
+
+// lastTickAgeMs is lastTickAge for /health, null until the feed starts
+func lastTickAgeMs() *int64 {
+	if !feedActive.Load() {
+		return nil
+	}
+	age := lastTickAge().Milliseconds()
+	return &age
+}
+
+// watchdogTimeout is how long the loop can go without completing a tick
+// before the feed counts as frozen (FEED_WATCHDOG_TIMEOUT, 3 intervals by
+// default)
+var watchdogTimeout time.Duration
+
+// feedFrozen is set by the watchdog while the publish loop is stalled
+var feedFrozen atomic.Bool
+
+// gamesReady is set once startup has finished, the games loaded and every
+// endpoint registered; until then the backend isn't ready and only the
+// liveness and readiness probes answer
+var gamesReady atomic.Bool
+
+// seeding is set while SEED_DUMMY_DATA's burst is still going out
+var seeding atomic.Bool
+
+// runWatchdog checks the publish loop's last tick every interval, flagging
+// the feed as frozen when it's overdue so /readyz can fail
+func runWatchdog() {
+	ticker := time.NewTicker(publishInterval())
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !feedActive.Load() {
+			continue
+		}
+
+		since := lastTickAge()
+		switch frozen := since > watchdogTimeout; {
+		case frozen && !feedFrozen.Load():
+			log.Printf("❌ CRITICAL: publish loop frozen, no tick completed for %s (timeout %s)", since.Round(time.Millisecond), watchdogTimeout)
+		case !frozen && feedFrozen.Load():
+			log.Println("✅ Publish loop recovered")
+		}
+		feedFrozen.Store(since > watchdogTimeout)
+	}
+}
+
+// GET /livez only reports that the process is serving HTTP
+func handleLivez(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, http.StatusOK, "alive")
+}
+
+// GET /readyz fails until startup has finished and the games are seeded, and
+// while the watchdog sees the feed frozen
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case !gamesReady.Load():
+		writeProbe(w, http.StatusServiceUnavailable, "starting")
+	case seeding.Load():
+		writeProbe(w, http.StatusServiceUnavailable, "seeding")
+	case feedFrozen.Load():
+		writeProbe(w, http.StatusServiceUnavailable, "frozen")
+	default:
+		writeProbe(w, http.StatusOK, "ready")
+	}
+}
+
+// writeProbe writes a probe's {"status": ...}. It skips writeJSON, whose
+// output case is still being configured while the probes already answer.
+func writeProbe(w http.ResponseWriter, code int, status string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, "{\"status\":%q}\n", status)
+}
+
+// untilReady answers everything but /livez and /readyz with 503 "starting"
+// until gamesReady is set, so no handler runs against half-set-up state
+func untilReady(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !gamesReady.Load() && r.URL.Path != "/livez" && r.URL.Path != "/readyz" {
+			writeProbe(w, http.StatusServiceUnavailable, "starting")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+const (
+	checkOK   = "ok"
+	checkFail = "fail"
+)
+
+// HealthCheck is one subcheck of the deep health report. A failing critical
+// check makes the whole backend unhealthy.
+type HealthCheck struct {
+	Status    string   `json:"status"`
+	Critical  bool     `json:"critical"`
+	LatencyMs *float64 `json:"latencyMs,omitempty"`
+	Detail    string   `json:"detail,omitempty"`
+}
+
+// GET /health, or GET /health?deep=true for per-subsystem checks
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("deep") != "true" {
+		status := "healthy"
+		if inScheduledDowntime.Load() {
+			status = "degraded"
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"status":          status,
+			"feedActive":      feedActive.Load(),
+			"redisConnected":  redisConnected(),
+			"deltasPublished": atomic.LoadInt64(&metrics.deltasPublished),
+			"publishErrors":   atomic.LoadInt64(&metrics.publishErrors),
+			"gamesCount":      registry.Len(),
+			"lastTickAgeMs":   lastTickAgeMs(),
+		})
+		return
+	}
+
+	checks := map[string]HealthCheck{
+		"redis":     checkRedis(r.Context()),
+		"publisher": checkPublisher(),
+		"games":     checkGames(),
+		"downtime":  checkDowntime(),
+	}
+
+	status, code := "healthy", http.StatusOK
+	for _, check := range checks {
+		if check.Status == checkOK {
+			continue
+		}
+		if check.Critical {
+			status, code = "unhealthy", http.StatusServiceUnavailable
+			break
+		}
+		status = "degraded"
+	}
+
+	writeJSON(w, code, map[string]interface{}{
+		"status":        status,
+		"checks":        checks,
+		"lastTickAgeMs": lastTickAgeMs(),
+	})
+}
+
+// checkRedis pings the broker and reports the round trip
+func checkRedis(parent context.Context) HealthCheck {
+	ctx, cancel := context.WithTimeout(parent, 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := publisher.Ping(ctx)
+	latency := float64(time.Since(start).Microseconds()) / 1000
+
+	check := HealthCheck{Status: checkOK, Critical: true, LatencyMs: &latency}
+	if err != nil {
+		check.Status, check.Detail = checkFail, err.Error()
+	}
+	return check
+}
+
+// checkPublisher fails when the running publish loop has stopped ticking
+func checkPublisher() HealthCheck {
+	if !feedActive.Load() {
+		return HealthCheck{Status: checkOK, Critical: true, Detail: "feed not started yet"}
+	}
+
+	since := lastTickAge().Round(time.Millisecond)
+	check := HealthCheck{Status: checkOK, Critical: true, Detail: fmt.Sprintf("last tick %s ago", since)}
+	if since > watchdogTimeout {
+		check.Status = checkFail
+	}
+	return check
+}
+
+// checkDowntime fails, without making the backend unhealthy, during a
+// scheduled downtime
+func checkDowntime() HealthCheck {
+	if inScheduledDowntime.Load() {
+		return HealthCheck{Status: checkFail, Detail: "scheduled downtime, not publishing"}
+	}
+	return HealthCheck{Status: checkOK}
+}
+
+// checkGames fails when there is nothing to publish
+func checkGames() HealthCheck {
+	count := registry.Len()
+	check := HealthCheck{Status: checkOK, Detail: fmt.Sprintf("%d games", count)}
+	if count == 0 {
+		check.Status = checkFail
+	}
+	return check
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestProbesAnswerWhileSeeding(t *testing.T) {
+	useGames(t)
+	useFeedChannels(t)
+	pub := &fakePublisher{}
+	previousPublisher, previousReady := publisher, gamesReady.Load()
+	publisher = pub
+	gamesReady.Store(true)
+	t.Cleanup(func() {
+		publisher = previousPublisher
+		gamesReady.Store(previousReady)
+		seeding.Store(false)
+	})
+
+	status := func(handler http.HandlerFunc, target string) int {
+		return serve(handler, http.MethodGet, target, "").Code
+	}
+
+	seeding.Store(true)
+	go runFeed(true, time.Hour)
+	waitFor(t, "the first seeded round", func() bool { return len(pub.messages()) >= 3 })
+	if code := status(handleLivez, "/livez"); code != http.StatusOK {
+		t.Errorf("/livez = %d while seeding, want 200", code)
+	}
+	if code := status(handleReadyz, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz = %d while seeding, want 503", code)
+	}
+
+	// Shutdown cuts the burst short between rounds
+	close(stopFeed)
+	select {
+	case <-feedStopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("feed didn't stop while seeding")
+	}
+	if code := status(handleReadyz, "/readyz"); code != http.StatusOK {
+		t.Errorf("/readyz = %d once seeding was over, want 200", code)
+	}
+}
+
+// silentRedis accepts connections and never answers, so a backend pointed at
+// it hangs connecting to Redis
+func silentRedis(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestProbesAnswerBeforeRedisConnects(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), "BACKEND_RUN_MAIN=1", "TRANSPORT=redis",
+		"REDIS_URL="+silentRedis(t), "HTTP_ADDR="+addr, "ADMIN_TOKEN=secret")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			return 0, err.Error()
+		}
+		defer resp.Body.Close()
+		var body struct {
+			Status string `json:"status"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body.Status
+	}
+
+	waitFor(t, "/livez while Redis is still connecting", func() bool {
+		code, _ := get("/livez")
+		return code == http.StatusOK
+	})
+	if code, status := get("/readyz"); code != http.StatusServiceUnavailable || status != "starting" {
+		t.Errorf("/readyz = %d %q while Redis is connecting, want 503 \"starting\"", code, status)
+	}
+	for _, path := range []string{"/health", "/games", "/config"} {
+		if code, status := get(path); code != http.StatusServiceUnavailable || status != "starting" {
+			t.Errorf("%s = %d %q while Redis is connecting, want 503 \"starting\"", path, code, status)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// History keeps the most recently published states of each game in a fixed
+// size ring buffer, so new subscribers can be warmed up with recent context.
+// With a total limit, the oldest states across all games are evicted once
+// the rings together hold more than that.
+type History struct {
+	mu         sync.Mutex
+	size       int
+	totalLimit int // 0 is no limit beyond size per game
+	retained   int
+	rings      map[string]*stateRing
+
+	seq   uint64         // numbers every added state, increasing
+	order []historyEntry // states in the order they were added, with a total limit
+}
+
+// stateRing is one game's ring of states, next is the slot written next and
+// count how many slots hold a state. published holds each state's own
+// sequence number as it went out, 0 without PUBLISH_SEQ.
+type stateRing struct {
+	states    []GameState
+	seqs      []uint64
+	published []uint64
+	next      int
+	count     int
+}
+
+// historyEntry points at a state in a game's ring by its sequence number
+type historyEntry struct {
+	gameID string
+	seq    uint64
+}
+
+// history records every published game state (HISTORY_SIZE per game, at
+// most HISTORY_TOTAL_LIMIT overall)
+var history = newHistory(100, 0)
+
+func newHistory(size, totalLimit int) *History {
+	return &History{size: size, totalLimit: totalLimit, rings: make(map[string]*stateRing)}
+}
+
+// Add records a published state and its sequence number, overwriting the
+// oldest once the ring is full, then evicts across games while over the total
+// limit
+func (h *History) Add(state GameState, published uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ring, ok := h.rings[state.ID]
+	if !ok {
+		ring = &stateRing{states: make([]GameState, h.size), seqs: make([]uint64, h.size), published: make([]uint64, h.size)}
+		h.rings[state.ID] = ring
+	}
+	h.seq++
+	ring.states[ring.next] = state
+	ring.seqs[ring.next] = h.seq
+	ring.published[ring.next] = published
+	ring.next = (ring.next + 1) % h.size
+	if ring.count < h.size {
+		ring.count++
+		h.retained++
+	}
+
+	if h.totalLimit <= 0 {
+		return
+	}
+	h.order = append(h.order, historyEntry{gameID: state.ID, seq: h.seq})
+	for h.retained > h.totalLimit && len(h.order) > 0 {
+		h.evict(h.order[0])
+		h.order = h.order[1:]
+	}
+
+	// Entries for states their ring already overwrote pile up otherwise
+	if len(h.order) > 2*h.totalLimit {
+		live := h.order[:0]
+		for _, entry := range h.order {
+			if h.holds(entry) {
+				live = append(live, entry)
+			}
+		}
+		h.order = live
+	}
+}
+
+// oldest is the slot of the ring's oldest state
+func (r *stateRing) oldest() int {
+	return (r.next - r.count + len(r.states)) % len(r.states)
+}
+
+// holds reports whether the entry's state is still in its ring. A ring's
+// sequence numbers increase, so anything older than its oldest is gone.
+func (h *History) holds(entry historyEntry) bool {
+	ring, ok := h.rings[entry.gameID]
+	return ok && ring.count > 0 && entry.seq >= ring.seqs[ring.oldest()]
+}
+
+// evict drops the entry's state if it's still the oldest in its ring
+func (h *History) evict(entry historyEntry) {
+	ring, ok := h.rings[entry.gameID]
+	if !ok || ring.count == 0 || ring.seqs[ring.oldest()] != entry.seq {
+		return
+	}
+	ring.states[ring.oldest()] = GameState{}
+	ring.count--
+	h.retained--
+	if ring.count == 0 {
+		delete(h.rings, entry.gameID)
+	}
+}
+
+// Retained is the number of states held across all games
+func (h *History) Retained() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.retained
+}
+
+// Last returns up to n of the game's most recent states, oldest first
+func (h *History) Last(gameID string, n int) []GameState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ring, ok := h.rings[gameID]
+	if !ok || n <= 0 {
+		return nil
+	}
+
+	if n > ring.count {
+		n = ring.count
+	}
+
+	states := make([]GameState, 0, n)
+	for i := n; i > 0; i-- {
+		states = append(states, ring.states[(ring.next-i+h.size)%h.size])
+	}
+	return states
+}
+
+// Page returns up to limit of the game's states and their sequence numbers,
+// oldest first, skipping the offset oldest, along with how many it holds in
+// total
+func (h *History) Page(gameID string, offset, limit int) ([]GameState, []uint64, int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ring, ok := h.rings[gameID]
+	if !ok {
+		return nil, nil, 0
+	}
+	n := min(limit, ring.count-offset)
+	if n <= 0 {
+		return nil, nil, ring.count
+	}
+
+	states := make([]GameState, 0, n)
+	seqs := make([]uint64, 0, n)
+	for i := 0; i < n; i++ {
+		slot := (ring.oldest() + offset + i) % h.size
+		states = append(states, ring.states[slot])
+		seqs = append(seqs, ring.published[slot])
+	}
+	return states, seqs, ring.count
+}
+
+// Since returns how many of the game's oldest states were published at or
+// before seq, or false when states after seq have already been evicted, so
+// they can't all be replayed
+func (h *History) Since(gameID string, seq uint64) (int, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ring, ok := h.rings[gameID]
+	if !ok || ring.count == 0 {
+		return 0, true
+	}
+	if ring.published[ring.oldest()] > seq+1 {
+		return 0, false
+	}
+	skip := 0
+	for skip < ring.count && ring.published[(ring.oldest()+skip)%h.size] <= seq {
+		skip++
+	}
+	return skip, true
+}
+
+// GET /games/{id}/history?offset=&limit= returns the game's history, oldest
+// first, a page at a time: limit defaults to all of it and X-Total-Count is
+// the number of states held. ?since=<seq> starts after that sequence number
+// instead, or is 410 Gone when some of the states after it were evicted;
+// with STATE_STREAM it reads the Redis stream rather than the in-memory
+// history. Each state carries its seq, and the array is written a state at a
+// time rather than marshalled whole.
+func handleGameHistory(w http.ResponseWriter, r *http.Request, gameID string) {
+	if !registry.Has(gameID) {
+		writeError(w, http.StatusNotFound, "game not found: "+gameID)
+		return
+	}
+
+	query := r.URL.Query()
+	offset, limit := 0, history.size
+	for name, v := range map[string]*int{"offset": &offset, "limit": &limit} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, name+" must be a non-negative integer")
+			return
+		}
+		*v = n
+	}
+	if raw := query.Get("since"); raw != "" {
+		since, err := strconv.ParseUint(raw, 10, 64)
+		switch {
+		case err != nil:
+			writeError(w, http.StatusBadRequest, "since must be a non-negative integer")
+			return
+		case !sequenceNumbers:
+			writeError(w, http.StatusBadRequest, "since needs PUBLISH_SEQ=true")
+			return
+		}
+		if streamReader != nil && publishFormat == formatJSON && compressor.codec == "none" {
+			serveStreamSince(w, gameID, since, offset, limit)
+			return
+		}
+		skip, complete := history.Since(gameID, since)
+		if !complete {
+			writeError(w, http.StatusGone, "states after seq "+raw+" are no longer kept; resync from /games/"+gameID+"/state")
+			return
+		}
+		offset += skip
+	}
+
+	states, seqs, total := history.Page(gameID, offset, limit)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	w.Write([]byte("["))
+	for i := range states {
+		data, err := json.Marshal(&states[i])
+		if err == nil {
+			data, err = wrapEnvelope(typeState, withOutputCase(data), seqs[i])
+		}
+		if err != nil {
+			log.Printf("Error encoding history state for %s: %v", gameID, err)
+			break
+		}
+		if i > 0 {
+			w.Write([]byte(","))
+		}
+		w.Write(data)
+	}
+	w.Write([]byte("]\n"))
+}
+
+// serveStreamSince answers ?since= from the game's Redis stream, with
+// STATE_STREAM on, which keeps many more states than the in-memory history.
+// The states are the payloads as published; offset and limit page through
+// the ones after since.
+func serveStreamSince(w http.ResponseWriter, gameID string, since uint64, offset, limit int) {
+	entries, total, err := streamReader.ReadStream(ctx, gameID, since, int64(max(offset+limit, 1)))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "error reading state stream: "+err.Error())
+		return
+	}
+
+	// The stream is trimmed from the oldest end, so states after since are
+	// missing exactly when the first one read isn't the next seq
+	if len(entries) > 0 && entries[0].seq != since+1 {
+		writeError(w, http.StatusGone, "states after seq "+strconv.FormatUint(since, 10)+" are no longer kept; resync from /games/"+gameID+"/state")
+		return
+	}
+
+	entries = entries[min(offset, len(entries)):]
+	entries = entries[:min(limit, len(entries))]
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	w.Write([]byte("["))
+	for i, entry := range entries {
+		if i > 0 {
+			w.Write([]byte(","))
+		}
+		w.Write(entry.data)
+	}
+	w.Write([]byte("]\n"))
+}
+
+// GET /games/{id}/history.csv downloads the game's history, oldest first
+func handleGameHistoryCSV(w http.ResponseWriter, gameID string) {
+	if !registry.Has(gameID) {
+		writeError(w, http.StatusNotFound, "game not found: "+gameID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+gameID+`-history.csv"`)
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"timestamp", "homeOdds", "awayOdds", "drawOdds", "homeScore", "awayScore"})
+	for _, state := range history.Last(gameID, history.size) {
+		out.Write([]string{
+			strconv.FormatInt(state.LastUpdated, 10),
+			strconv.FormatFloat(state.HomeOdds, 'f', -1, 64),
+			strconv.FormatFloat(state.AwayOdds, 'f', -1, 64),
+			strconv.FormatFloat(state.DrawOdds, 'f', -1, 64),
+			strconv.Itoa(state.HomeScore),
+			strconv.Itoa(state.AwayScore),
+		})
+	}
+	out.Flush()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestHistoryTotalLimitEvictsOldestAcrossGames(t *testing.T) {
+	h := newHistory(5, 8)
+	for _, id := range []string{"game1", "game2"} {
+		for seq := uint64(1); seq <= 5; seq++ {
+			h.Add(GameState{ID: id, HomeScore: int(seq)}, seq)
+		}
+	}
+
+	if got := h.Retained(); got != 8 {
+		t.Fatalf("Retained() = %d, want the limit of 8", got)
+	}
+	// game1's states are the oldest overall, so its first two went
+	states, seqs, total := h.Page("game1", 0, 10)
+	if total != 3 || states[0].HomeScore != 3 || seqs[0] != 3 {
+		t.Errorf("game1 holds %d states starting at seq %v, want 3 starting at seq 3", total, seqs)
+	}
+	if _, _, total := h.Page("game2", 0, 10); total != 5 {
+		t.Errorf("game2 holds %d states, want all 5", total)
+	}
+
+	if _, ok := h.Since("game1", 1); ok {
+		t.Error("Since(1) can replay game1 though seq 2 was evicted")
+	}
+	if skip, ok := h.Since("game1", 3); !ok || skip != 1 {
+		t.Errorf("Since(3) = %d, %v; want 1, true", skip, ok)
+	}
+}
+
+func TestHistoryEndpointPages(t *testing.T) {
+	useGames(t)
+	previous := history
+	history = newHistory(20, 0)
+	t.Cleanup(func() { history = previous })
+	state, _ := registry.Get("game1")
+	for seq := uint64(1); seq <= 12; seq++ {
+		history.Add(state, seq)
+	}
+
+	tests := []struct {
+		query string
+		seqs  []uint64
+	}{
+		{"offset=5&limit=4", []uint64{6, 7, 8, 9}},
+		{"offset=10&limit=10", []uint64{11, 12}},
+		{"offset=50", []uint64{}},
+		{"", []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}},
+	}
+	for _, tt := range tests {
+		rec := serve(handleGameRoutes, http.MethodGet, "/games/game1/history?"+tt.query, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET history?%s = %d: %s", tt.query, rec.Code, rec.Body)
+		}
+		if total := rec.Header().Get("X-Total-Count"); total != "12" {
+			t.Errorf("history?%s X-Total-Count = %s, want 12", tt.query, total)
+		}
+		var page []Envelope
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatalf("history?%s is not a JSON array: %v\n%s", tt.query, err, rec.Body)
+		}
+		seqs := []uint64{}
+		for _, env := range page {
+			seqs = append(seqs, env.Seq)
+		}
+		if !slices.Equal(seqs, tt.seqs) {
+			t.Errorf("history?%s seqs = %v, want %v", tt.query, seqs, tt.seqs)
+		}
+	}
+
+	if rec := serve(handleGameRoutes, http.MethodGet, "/games/game1/history?limit=-1", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("negative limit = %d, want 400", rec.Code)
+	}
+	if rec := serve(handleGameRoutes, http.MethodGet, "/games/game9/history", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("history of an unknown game = %d, want 404", rec.Code)
+	}
+}
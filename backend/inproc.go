@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// inprocBroker is an in-memory stand-in for Redis (TRANSPORT=inproc): it fans
+// publishes out to in-process subscribers and keeps the latest payloads, so
+// the backend runs as a single binary with no external broker
+type inprocBroker struct {
+	mu     sync.RWMutex
+	subs   map[string]map[chan brokerMessage]struct{}
+	latest map[string][]byte
+}
+
+func newInprocBroker() *inprocBroker {
+	return &inprocBroker{
+		subs:   make(map[string]map[chan brokerMessage]struct{}),
+		latest: make(map[string][]byte),
+	}
+}
+
+// Publish never blocks: subscribers that fall behind miss messages, as they
+// would with Redis Pub/Sub
+func (b *inprocBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subs[channel] {
+		select {
+		case ch <- brokerMessage{Channel: channel, Payload: payload}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (b *inprocBroker) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (b *inprocBroker) SetLatest(ctx context.Context, gameID string, data []byte) error {
+	b.mu.Lock()
+	b.latest[gameID] = data
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *inprocBroker) GetLatest(ctx context.Context, gameID string) ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	data, ok := b.latest[gameID]
+	if !ok {
+		return nil, errNoLatest
+	}
+	return data, nil
+}
+
+func (b *inprocBroker) Subscribe(ctx context.Context, channels []string) (<-chan brokerMessage, func()) {
+	sub := b.open(64)
+	sub.Add(channels...)
+	return sub.ch, sub.Close
+}
+
+// Open buffers more than Subscribe: the /ws hub's one subscription carries
+// every connection's channels
+func (b *inprocBroker) Open(ctx context.Context) Subscription {
+	return b.open(1024)
+}
+
+func (b *inprocBroker) open(buffer int) *inprocSubscription {
+	return &inprocSubscription{broker: b, ch: make(chan brokerMessage, buffer), channels: map[string]bool{}}
+}
+
+// inprocSubscription is one subscriber of an inprocBroker, on channels that
+// can change while it runs
+type inprocSubscription struct {
+	broker   *inprocBroker
+	ch       chan brokerMessage
+	channels map[string]bool // under broker.mu
+	closed   bool
+	once     sync.Once
+}
+
+func (s *inprocSubscription) Messages() <-chan brokerMessage { return s.ch }
+
+func (s *inprocSubscription) Add(channels ...string) error {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	for _, channel := range channels {
+		if b.subs[channel] == nil {
+			b.subs[channel] = make(map[chan brokerMessage]struct{})
+		}
+		b.subs[channel][s.ch] = struct{}{}
+		s.channels[channel] = true
+	}
+	return nil
+}
+
+func (s *inprocSubscription) Remove(channels ...string) error {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, channel := range channels {
+		s.unsubscribe(channel)
+	}
+	return nil
+}
+
+// unsubscribe drops one channel. Call with broker.mu held.
+func (s *inprocSubscription) unsubscribe(channel string) {
+	b := s.broker
+	delete(b.subs[channel], s.ch)
+	if len(b.subs[channel]) == 0 {
+		delete(b.subs, channel)
+	}
+	delete(s.channels, channel)
+}
+
+func (s *inprocSubscription) Close() {
+	s.once.Do(func() {
+		b := s.broker
+		b.mu.Lock()
+		for channel := range s.channels {
+			s.unsubscribe(channel)
+		}
+		s.closed = true
+		b.mu.Unlock()
+		close(s.ch)
+	})
+}
+
+// Subscriptions counts the channel subscriptions held, one per channel per
+// open subscriber; it drops back to 0 once every /ws client has disconnected
+func (b *inprocBroker) Subscriptions() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	n := 0
+	for _, subs := range b.subs {
+		n += len(subs)
+	}
+	return n
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencyWindow keeps the most recent latency samples for percentiles
+type LatencyWindow struct {
+	mu      sync.Mutex
+	samples []float64
+	next    int
+	full    bool
+}
+
+// producerLatency tracks how long each message took from the state being
+// computed to Redis acking the publish
+var producerLatency = newLatencyWindow(1024)
+
+func newLatencyWindow(size int) *LatencyWindow {
+	return &LatencyWindow{samples: make([]float64, size)}
+}
+
+// Observe records one sample
+func (l *LatencyWindow) Observe(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.samples[l.next] = float64(d.Microseconds()) / 1000
+	l.next = (l.next + 1) % len(l.samples)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Percentile returns the p-th percentile (0-100) in milliseconds over the
+// window, or 0 with no samples
+func (l *LatencyWindow) Percentile(p float64) float64 {
+	l.mu.Lock()
+	n := l.next
+	if l.full {
+		n = len(l.samples)
+	}
+	sorted := append([]float64(nil), l.samples[:n]...)
+	l.mu.Unlock()
+
+	if n == 0 {
+		return 0
+	}
+	sort.Float64s(sorted)
+	return sorted[int(float64(n-1)*p/100)]
+}
+
+// Histogram counts latencies into buckets, for the Prometheus histogram on
+// /metrics
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []float64 // bucket upper bounds in seconds, ascending
+	counts []int64   // per bucket, with one more for +Inf; not cumulative
+	sum    float64   // seconds
+	total  int64
+}
+
+// publishLatency times every Redis publish call (a whole pipeline with
+// PUBLISH_PIPELINE)
+var publishLatency = newHistogram([]float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1})
+
+func newHistogram(bounds []float64) *Histogram {
+	return &Histogram{bounds: bounds, counts: make([]int64, len(bounds)+1)}
+}
+
+// Observe records one sample
+func (h *Histogram) Observe(d time.Duration) {
+	seconds := d.Seconds()
+	i := sort.SearchFloat64s(h.bounds, seconds)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.sum += seconds
+	h.total++
+}
+
+// timedPublish publishes on the broker, observing how long it took
+func timedPublish(channel string, data []byte) error {
+	return timedPublishTo(publisher, channel, data)
+}
+
+// timedPublishTo is timedPublish on a given publisher
+func timedPublishTo(pub Publisher, channel string, data []byte) error {
+	start := time.Now()
+	err := pub.Publish(ctx, channel, data)
+	publishLatency.Observe(time.Since(start))
+	return err
+}
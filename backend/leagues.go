@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LeagueSnapshot is the aggregate published on a league's channel
+type LeagueSnapshot struct {
+	League      string      `json:"league"`
+	Games       []GameState `json:"games"`
+	LastUpdated int64       `json:"lastUpdated"`
+}
+
+// leagueChannel is the Redis channel carrying a league's aggregate snapshot
+func leagueChannel(league string) string {
+	return "league:" + league
+}
+
+// leagueGames returns clones of the publishing games in each league, sorted
+// by ID. Only leagues in filter are included unless filter is nil.
+func leagueGames(filter map[string]bool) map[string][]GameState {
+	leagues := map[string][]GameState{}
+	for _, game := range registry.Snapshot() {
+		if game.League == "" || !publishFilter.Allows(game.ID) {
+			continue
+		}
+		if filter != nil && !filter[game.League] {
+			continue
+		}
+		leagues[game.League] = append(leagues[game.League], game)
+	}
+	return leagues
+}
+
+// publishLeagues publishes an aggregate snapshot for every league with at
+// least one game updated this tick
+func publishLeagues(updated []string) {
+	if len(updated) == 0 {
+		return
+	}
+
+	changed := map[string]bool{}
+	for _, gameID := range updated {
+		registry.View(gameID, func(game *Game) {
+			if game.League != "" {
+				changed[game.League] = true
+			}
+		})
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	for league, list := range leagueGames(changed) {
+		data, ok := encodeForPublish("league snapshot for "+league, LeagueSnapshot{League: league, Games: list, LastUpdated: now})
+		if !ok {
+			continue
+		}
+
+		publishQueue.Enqueue(outbound{kind: kindLeague, channel: leagueChannel(league), data: data})
+	}
+}
+
+// GET /leagues lists leagues with their game counts and channels
+func handleLeagues(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	type leagueSummary struct {
+		Name    string `json:"name"`
+		Channel string `json:"channel"`
+		Games   int    `json:"games"`
+	}
+
+	summaries := []leagueSummary{}
+	for league, list := range leagueGames(nil) {
+		summaries = append(summaries, leagueSummary{Name: league, Channel: leagueChannel(league), Games: len(list)})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// GET /leagues/{name}/games
+func handleLeagueRoutes(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/leagues/"), "/"), "/")
+	if len(parts) != 2 || parts[1] != "games" || r.Method != http.MethodGet {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	list, ok := leagueGames(map[string]bool{parts[0]: true})[parts[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "league not found: "+parts[0])
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
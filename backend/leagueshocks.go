@@ -0,0 +1,74 @@
+package main
+
+import (
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// League shocks move every game in a league together, like weather hitting a
+// whole round of fixtures. Each tick every league has a chance
+// (LEAGUE_SHOCK_PROBABILITY) of one market moving the same way in all its live
+// games, by between half and all of leagueShockMagnitude
+// (LEAGUE_SHOCK_MAGNITUDE) of the price. Only DRIFT_MODEL=random drifts odds
+// freely enough to take them.
+var (
+	leagueShockProbability float64
+	leagueShockMagnitude   = 0.1
+	leagueShockRng         *rand.Rand // only used by the publish loop
+)
+
+// applyLeagueShocks rolls for a shock in each league and moves its live
+// games' odds, marking them to publish straight away
+func applyLeagueShocks() {
+	if leagueShockProbability <= 0 || driftModel != driftRandom {
+		return
+	}
+
+	registry.Batch(shockLeagues)
+}
+
+// shockLeagues is applyLeagueShocks under the registry lock
+func shockLeagues(games map[string]*Game) {
+	leagues := map[string][]*Game{}
+	for _, game := range games {
+		if game.League != "" && game.Status == statusLive && publishFilter.Allows(game.ID) {
+			leagues[game.League] = append(leagues[game.League], game)
+		}
+	}
+	names := make([]string, 0, len(leagues))
+	for league := range leagues {
+		names = append(names, league)
+	}
+	sort.Strings(names)
+
+	for _, league := range names {
+		if leagueShockRng.Float64() >= scaledProbability(leagueShockProbability) {
+			continue
+		}
+		market := marketNames[leagueShockRng.Intn(len(marketNames))]
+		move := leagueShockMagnitude * (0.5 + 0.5*leagueShockRng.Float64())
+		if leagueShockRng.Float64() < 0.5 {
+			move = -move
+		}
+
+		moved := 0
+		for _, game := range leagues[league] {
+			m, ok := game.Markets[market]
+			if !ok || m.Status != marketOpen {
+				continue
+			}
+			newOdds := m.Odds * (1 + move)
+			if newOdds <= oddsFloor {
+				newOdds = math.Max(oddsFloor+0.01, m.Odds/2)
+			}
+			game.setOdds(market, newOdds, game.now())
+			game.leagueShocked = true
+			moved++
+		}
+		if moved > 0 && logEvents {
+			log.Printf("⚡ %s: league shock moves %s odds %+.0f%% in %d games", league, market, move*100, moved)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestLeagueShockMovesALeagueTogether(t *testing.T) {
+	useGames(t)
+	previousProbability, previousRng := leagueShockProbability, leagueShockRng
+	leagueShockProbability, leagueShockRng = 1, rand.New(rand.NewSource(1))
+	t.Cleanup(func() { leagueShockProbability, leagueShockRng = previousProbability, previousRng })
+
+	before := map[string]map[string]float64{}
+	for _, state := range registry.Snapshot() {
+		prices := map[string]float64{}
+		for name, m := range state.Markets {
+			prices[name] = m.Odds
+		}
+		before[state.ID] = prices
+	}
+	applyLeagueShocks()
+
+	moves := map[string]map[string]float64{}
+	registry.Read(func(games map[string]*Game) {
+		for id, game := range games {
+			if !game.leagueShocked {
+				t.Errorf("%s not marked as league shocked", id)
+			}
+			moves[id] = map[string]float64{}
+			for name, m := range game.Markets {
+				if m.Odds != before[id][name] {
+					moves[id][name] = m.Odds / before[id][name]
+				}
+			}
+		}
+	})
+
+	// game1 and game2 share the Premier League, so one move for both
+	if len(moves["game1"]) != 1 || len(moves["game3"]) != 1 {
+		t.Fatalf("markets moved per game = %v, want one each", moves)
+	}
+	for market, ratio := range moves["game1"] {
+		other, ok := moves["game2"][market]
+		if !ok || math.Abs(other-ratio) > 0.02 {
+			t.Errorf("game1 %s moved by x%.3f but game2 by x%.3f", market, ratio, other)
+		}
+		if move := math.Abs(ratio - 1); move < leagueShockMagnitude/2-0.02 || move > leagueShockMagnitude+0.02 {
+			t.Errorf("%s moved %.0f%%, want between %.0f%% and %.0f%%", market, move*100, leagueShockMagnitude*50, leagueShockMagnitude*100)
+		}
+	}
+}
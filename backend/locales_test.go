@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestSyntheticGamesDontDependOnCPUCount(t *testing.T) {
+	pack, err := loadLocale(defaultLocale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parallel := syntheticGames(200, pack, 42)
+
+	previous := runtime.GOMAXPROCS(1)
+	serial := syntheticGames(200, pack, 42)
+	runtime.GOMAXPROCS(previous)
+
+	if !reflect.DeepEqual(parallel, serial) {
+		t.Error("syntheticGames differs between GOMAXPROCS settings for the same seed")
+	}
+	for i, state := range parallel {
+		if state.ID == "" {
+			t.Fatalf("game %d was never generated", i+1)
+		}
+	}
+}
+
+func BenchmarkSyntheticGames10k(b *testing.B) {
+	pack, err := loadLocale(defaultLocale)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		syntheticGames(10000, pack, int64(i))
+	}
+}
@@ -2,199 +2,1077 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"log"
 	"math/rand"
+	"net"
 	"net/http"
 	"os"
+	"sort"
+	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-type GameState struct {
-	ID          string  `json:"id"`
-	HomeTeam    string  `json:"homeTeam"`
-	AwayTeam    string  `json:"awayTeam"`
-	HomeScore   int     `json:"homeScore"`
-	AwayScore   int     `json:"awayScore"`
-	HomeOdds    float64 `json:"homeOdds"`
-	AwayOdds    float64 `json:"awayOdds"`
-	DrawOdds    float64 `json:"drawOdds"`
-	LastUpdated int64   `json:"lastUpdated"`
-}
-
 // Backend now publishes full game state, Socket.IO server calculates deltas
 
 type Metrics struct {
-	deltasPublished int64
-	publishErrors   int64
+	deltasPublished  int64
+	publishErrors    int64
+	panics           int64
+	dedupedPublishes int64
+	slowTicks        int64
+	eventsPublished  int64
+	droppedUpdates   int64
+	oversizePayloads int64
+	gamesConsidered  int64
+	gamesPublished   int64
+	oomErrors        int64
+	wrongTypeErrors  int64
+	finalPublishes   int64
+	dlqPublishes     int64
+	staleInjected    int64
+	delayedPublishes int64
+	invalidOdds      int64
+	bytesPublished   int64
+
+	slowClientDrops       int64
+	slowClientDisconnects int64
+	wsDropped             int64
+
+	webhookDeliveries int64
+	webhookErrors     int64
+
+	arbitragePrevented int64
+	gamesRotated       int64
+	stuckGames         int64
+	scheduledDowntimes int64
+	templateErrors     int64
+	duplicatePayloads  int64
+
+	activeConnections  int64 // open /ws connections (a gauge, not a counter)
+	totalSubscriptions int64 // distinct games subscribed to, summed over /ws connections (a gauge)
 }
 
 var (
-	games   map[string]*GameState
-	metrics Metrics
-	ctx     = context.Background()
+	metrics       Metrics
+	publishFilter PublishFilter
+	compressor    *Compressor
+	dedupEnabled  bool
+	debugEnabled  bool
+	publisher     Publisher
+	latest        LatestStore
+	feedActive    atomic.Bool
+	// ctx is what every Redis call runs under; shutdown cancels it once the
+	// publish queue has drained or shutdownTimeout has passed
+	ctx, cancelPublishes = context.WithCancel(context.Background())
 )
 
-func initializeGames() {
-	games = map[string]*GameState{
-		"game1": {ID: "game1", HomeTeam: "Arsenal", AwayTeam: "Chelsea", HomeScore: 1, AwayScore: 1, HomeOdds: 2.5, AwayOdds: 2.8, DrawOdds: 3.2},
-		"game2": {ID: "game2", HomeTeam: "Liverpool", AwayTeam: "Man United", HomeScore: 2, AwayScore: 0, HomeOdds: 1.8, AwayOdds: 4.2, DrawOdds: 3.5},
-		"game3": {ID: "game3", HomeTeam: "Barcelona", AwayTeam: "Real Madrid", HomeScore: 0, AwayScore: 0, HomeOdds: 2.1, AwayOdds: 3.3, DrawOdds: 3.0},
-	}
-
-	for _, game := range games {
-		game.LastUpdated = time.Now().UnixMilli()
-	}
+// Rates are per-second throughput over the last metrics interval
+type Rates struct {
+	PublishesPerSec float64 `json:"publishesPerSec"`
+	ErrorsPerSec    float64 `json:"errorsPerSec"`
+	IntervalSeconds float64 `json:"intervalSeconds"`
+	// ChangeRate is the fraction of games considered on a tick that
+	// actually published
+	ChangeRate float64 `json:"changeRate"`
 }
 
-func publishOddsUpdates(rdb *redis.Client) {
-	// High frequency updates (200ms)
-	ticker := time.NewTicker(200 * time.Millisecond)
+// currentRates holds the latest Rates computed by printMetrics
+var currentRates atomic.Value
+
+// printMetrics logs counters every interval and, when a statsd client is
+// configured, ships them to statsd as well
+func printMetrics(statsd *StatsdClient) {
+	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
 
-	log.Println("Starting to publish game updates to Redis...")
-
-	for range ticker.C {
-		for gameID, game := range games {
-			// 90% chance of update per game
-			if rand.Float64() < 0.9 {
-				// Update odds randomly
-				if rand.Float64() < 0.6 {
-					newOdds := game.HomeOdds + (rand.Float64()-0.5)*0.6
-					if newOdds > 1.01 {
-						game.HomeOdds = newOdds
-					}
-				}
-				if rand.Float64() < 0.6 {
-					newOdds := game.AwayOdds + (rand.Float64()-0.5)*0.6
-					if newOdds > 1.01 {
-						game.AwayOdds = newOdds
-					}
-				}
-				if rand.Float64() < 0.6 {
-					newOdds := game.DrawOdds + (rand.Float64()-0.5)*0.6
-					if newOdds > 1.01 {
-						game.DrawOdds = newOdds
-					}
-				}
-
-				game.LastUpdated = time.Now().UnixMilli()
-
-				// Publish full game state (Socket.IO server will calculate deltas)
-				data, err := json.Marshal(game)
-				if err != nil {
-					atomic.AddInt64(&metrics.publishErrors, 1)
-					log.Printf("Error marshaling game state: %v", err)
-					continue
-				}
-
-				// Publish to Redis channel (named after the game)
-				if err := rdb.Publish(ctx, gameID, data).Err(); err != nil {
-					atomic.AddInt64(&metrics.publishErrors, 1)
-					log.Printf("Error publishing to Redis: %v", err)
-				} else {
-					atomic.AddInt64(&metrics.deltasPublished, 1)
-				}
+	lastAt := time.Now()
+	var lastPublished, lastErrors, lastConsidered, lastGamesPublished int64
+
+	for now := range ticker.C {
+		published := atomic.LoadInt64(&metrics.deltasPublished)
+		errors := atomic.LoadInt64(&metrics.publishErrors)
+		considered := atomic.LoadInt64(&metrics.gamesConsidered)
+		gamesPublished := atomic.LoadInt64(&metrics.gamesPublished)
+
+		// Diff against the previous snapshot for current throughput
+		elapsed := now.Sub(lastAt).Seconds()
+		rates := Rates{
+			PublishesPerSec: float64(published-lastPublished) / elapsed,
+			ErrorsPerSec:    float64(errors-lastErrors) / elapsed,
+			IntervalSeconds: elapsed,
+		}
+		if considered > lastConsidered {
+			rates.ChangeRate = float64(gamesPublished-lastGamesPublished) / float64(considered-lastConsidered)
+		}
+		currentRates.Store(rates)
+		lastAt, lastPublished, lastErrors = now, published, errors
+		lastConsidered, lastGamesPublished = considered, gamesPublished
+
+		panics := atomic.LoadInt64(&metrics.panics)
+		deduped := atomic.LoadInt64(&metrics.dedupedPublishes)
+		slowTicks := atomic.LoadInt64(&metrics.slowTicks)
+		log.Printf("[METRICS] Deltas Published: %d | Errors: %d | Panics: %d | Deduped: %d | Slow Ticks: %d | Rate: %.1f/s (%.2f errors/s) | Change Rate: %.0f%%", published, errors, panics, deduped, slowTicks, rates.PublishesPerSec, rates.ErrorsPerSec, rates.ChangeRate*100)
+
+		if statsd != nil {
+			if err := statsd.Counters(map[string]int64{
+				"deltas_published": published,
+				"publish_errors":   errors,
+			}); err != nil {
+				log.Printf("Error sending metrics to statsd: %v", err)
 			}
 		}
 	}
 }
 
-func printMetrics() {
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+// avgSubscriptionsPerConnection is how many games a /ws connection follows
+// on average, 0 with no connections
+func avgSubscriptionsPerConnection() float64 {
+	connections := atomic.LoadInt64(&metrics.activeConnections)
+	if connections == 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(&metrics.totalSubscriptions)) / float64(connections)
+}
 
-	for range ticker.C {
-		published := atomic.LoadInt64(&metrics.deltasPublished)
-		errors := atomic.LoadInt64(&metrics.publishErrors)
-		log.Printf("[METRICS] Deltas Published: %d | Errors: %d", published, errors)
+// metricsSnapshot is every counter and gauge, as served on /metrics
+func metricsSnapshot() map[string]interface{} {
+	snapshot := map[string]interface{}{
+		"deltasPublished":               atomic.LoadInt64(&metrics.deltasPublished),
+		"publishErrors":                 atomic.LoadInt64(&metrics.publishErrors),
+		"panics":                        atomic.LoadInt64(&metrics.panics),
+		"dedupedPublishes":              atomic.LoadInt64(&metrics.dedupedPublishes),
+		"slowTicks":                     atomic.LoadInt64(&metrics.slowTicks),
+		"eventsPublished":               atomic.LoadInt64(&metrics.eventsPublished),
+		"droppedUpdates":                atomic.LoadInt64(&metrics.droppedUpdates),
+		"oversizePayloads":              atomic.LoadInt64(&metrics.oversizePayloads),
+		"gamesConsidered":               atomic.LoadInt64(&metrics.gamesConsidered),
+		"gamesPublished":                atomic.LoadInt64(&metrics.gamesPublished),
+		"oomErrors":                     atomic.LoadInt64(&metrics.oomErrors),
+		"wrongTypeErrors":               atomic.LoadInt64(&metrics.wrongTypeErrors),
+		"finalPublishes":                atomic.LoadInt64(&metrics.finalPublishes),
+		"dlqPublishes":                  atomic.LoadInt64(&metrics.dlqPublishes),
+		"staleInjected":                 atomic.LoadInt64(&metrics.staleInjected),
+		"delayedPublishes":              atomic.LoadInt64(&metrics.delayedPublishes),
+		"invalidOdds":                   atomic.LoadInt64(&metrics.invalidOdds),
+		"bytesPublished":                atomic.LoadInt64(&metrics.bytesPublished),
+		"byEncoding":                    encodingSnapshot(),
+		"arbitragePrevented":            atomic.LoadInt64(&metrics.arbitragePrevented),
+		"gamesRotated":                  atomic.LoadInt64(&metrics.gamesRotated),
+		"stuckGames":                    atomic.LoadInt64(&metrics.stuckGames),
+		"scheduledDowntimes":            atomic.LoadInt64(&metrics.scheduledDowntimes),
+		"templateErrors":                atomic.LoadInt64(&metrics.templateErrors),
+		"duplicatePayloads":             atomic.LoadInt64(&metrics.duplicatePayloads),
+		"slowClientDrops":               atomic.LoadInt64(&metrics.slowClientDrops),
+		"slowClientDisconnects":         atomic.LoadInt64(&metrics.slowClientDisconnects),
+		"wsDropped":                     atomic.LoadInt64(&metrics.wsDropped),
+		"webhookDeliveries":             atomic.LoadInt64(&metrics.webhookDeliveries),
+		"webhookErrors":                 atomic.LoadInt64(&metrics.webhookErrors),
+		"activeConnections":             atomic.LoadInt64(&metrics.activeConnections),
+		"totalSubscriptions":            atomic.LoadInt64(&metrics.totalSubscriptions),
+		"avgSubscriptionsPerConnection": avgSubscriptionsPerConnection(),
+		"subscribers":                   subscriberCounts.Load(),
+		"subscribersStale":              subscribersStale.Load(),
+		"queueDepth":                    publishQueue.Depth(),
+		"historySnapshots":              history.Retained(),
+		"rates":                         currentRates.Load(),
+		"producerLatencyMs": map[string]float64{
+			"p50": producerLatency.Percentile(50),
+			"p95": producerLatency.Percentile(95),
+		},
 	}
+	// Leak check for the in-process hub under connection churn
+	if hub, ok := subscriber.(*inprocBroker); ok {
+		snapshot["hubSubscriptions"] = hub.Subscriptions()
+	}
+	return snapshot
 }
 
-func publishInitialDummyData(rdb *redis.Client) {
-	log.Println("Publishing initial dummy data...")
-	
-	// Publish 10 updates immediately so frontend sees data right away
-	for i := 0; i < 10; i++ {
-		for gameID, game := range games {
-			// Make some visible changes
-			game.HomeOdds = game.HomeOdds + float64(i)*0.1
-			game.AwayOdds = game.AwayOdds + float64(i)*0.1
-			game.DrawOdds = game.DrawOdds + float64(i)*0.1
-			game.LastUpdated = time.Now().UnixMilli()
-
-			// Publish full game state
-			data, _ := json.Marshal(game)
-			if err := rdb.Publish(ctx, gameID, data).Err(); err != nil {
-				log.Printf("Error publishing dummy data: %v", err)
-			} else {
-				log.Printf("Published dummy update #%d for %s", i+1, gameID)
-			}
+// newMetricsSink resolves METRICS_SINK=log|statsd; log is always written and
+// statsd is an additional sink
+func newMetricsSink() *StatsdClient {
+	switch sink := envString("METRICS_SINK", "log"); sink {
+	case "log":
+		return nil
+	case "statsd":
+		addr := envString("STATSD_ADDR", "localhost:8125")
+		statsd, err := newStatsdClient(addr)
+		if err != nil {
+			log.Fatal("Failed to set up statsd client:", err)
 		}
-		time.Sleep(500 * time.Millisecond)
+		log.Println("✅ Shipping metrics to statsd at", addr)
+		return statsd
+	default:
+		log.Fatalf("Invalid METRICS_SINK=%q: expected log or statsd", sink)
+		return nil
 	}
-	
-	log.Println("✅ Dummy data published successfully!")
 }
 
 func main() {
-	// Connect to Redis
-	redisAddr := "localhost:6379"
-	if addr := os.Getenv("REDIS_URL"); addr != "" {
-		redisAddr = addr
+	currentRates.Store(Rates{})
+
+	// Profile defaults apply to every setting read below
+	profile, err := loadProfile()
+	if err != nil {
+		log.Fatal("Invalid PROFILE:", err)
+	}
+	if profile != "" {
+		log.Printf("Using %s profile", profile)
 	}
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     redisAddr,
-		Password: "", // no password
-		DB:       0,  // default DB
-	})
+	// Listen address, so several backends can share a host
+	addr := envString("HTTP_ADDR", ":8080")
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		log.Fatalf("Invalid HTTP_ADDR=%q: %v", addr, err)
+	}
+
+	// Optional bearer token on everything but the probes
+	adminToken = envString("ADMIN_TOKEN", "")
+	if adminToken != "" {
+		log.Println("Requiring ADMIN_TOKEN on all endpoints except /health, /livez and /readyz")
+	}
+
+	// The server listens before the broker connects and the games are
+	// generated, so probes see "starting" rather than a refused connection;
+	// every other endpoint answers 503 until startup finishes
+	http.HandleFunc("/livez", handleLivez)
+	http.HandleFunc("/readyz", handleReadyz)
+	server := &http.Server{Addr: addr, Handler: untilReady(requireToken(http.DefaultServeMux))}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("HTTP server error:", err)
+		}
+	}()
+	log.Printf("HTTP server listening on %s", addr)
+
+	// Broker: Redis, or an in-process hub for single-binary runs
+	switch transport := envString("TRANSPORT", "redis"); transport {
+	case "redis":
+		client, redisAddr := newRedisClient()
+		broker := &redisPublisher{client: client}
+		publisher, latest, subscriber = broker, broker, broker
+
+		// Optional per-group logical DBs (single-node only, clusters have no DBs)
+		if items := envList("REDIS_DB_ROUTES"); items != nil {
+			routes, err := parseDBRoutes(items)
+			if err != nil {
+				log.Fatal("Invalid REDIS_DB_ROUTES:", err)
+			}
+			single, ok := client.(*redis.Client)
+			if !ok {
+				log.Fatal("REDIS_DB_ROUTES is not supported with REDIS_CLUSTER=true")
+			}
+			routed := newRoutedPublisher(single, routes)
+			publisher, latest, subscriber = routed, routed, routed
+			log.Printf("Redis DB routes: leagues=%v tiers=%v", routes.leagues, routes.tiers)
+		}
+
+		// Test connection
+		if err := publisher.Ping(ctx); err != nil {
+			log.Fatal("Failed to connect to Redis:", err)
+		}
+		log.Println("✅ Connected to Redis at", redisAddr)
+	case "inproc":
+		broker := newInprocBroker()
+		publisher, latest, subscriber = broker, broker, broker
+		log.Println("✅ Using in-process pub/sub, Redis is not used")
+	default:
+		log.Fatalf("Invalid TRANSPORT=%q: expected redis or inproc", transport)
+	}
+
+	// Latest state as a Redis hash per game, next to the pub/sub feed
+	if envBool("WRITE_HASH", false) {
+		writer, ok := latest.(HashWriter)
+		if !ok {
+			log.Fatal("WRITE_HASH=true requires TRANSPORT=redis")
+		}
+		hashWriter = writer
+		log.Printf("✅ Writing each game's latest state to %s hashes", hashKey("<id>"))
+	}
+
+	// Published states appended to a Redis stream per game, for replay
+	if envBool("STATE_STREAM", false) {
+		writer, ok := latest.(StreamWriter)
+		if !ok {
+			log.Fatal("STATE_STREAM=true requires TRANSPORT=redis")
+		}
+		stateStreamMaxLen = int64(envInt("STATE_STREAM_MAXLEN", int(stateStreamMaxLen)))
+		if stateStreamMaxLen <= 0 {
+			log.Fatalf("Invalid STATE_STREAM_MAXLEN=%d: must be positive", stateStreamMaxLen)
+		}
+		streamWriter = writer
+		streamReader, _ = latest.(StreamReader)
+		log.Printf("✅ Appending each game's published states to %s streams", streamKey("<id>"))
+	}
+
+	// End-to-end pub/sub check, beyond the ping
+	if envBool("STARTUP_SELFCHECK", false) {
+		timeout := envDuration("STARTUP_SELFCHECK_TIMEOUT", 5*time.Second)
+		if timeout <= 0 {
+			log.Fatalf("Invalid STARTUP_SELFCHECK_TIMEOUT=%s: must be positive", timeout)
+		}
+		if err := selfCheck(timeout); err != nil {
+			log.Fatal("Startup self-check failed:", err)
+		}
+		log.Println("✅ Startup self-check passed: pub/sub round trip OK")
+	}
+
+	// Channel naming
+	channelTemplate = envString("CHANNEL_TEMPLATE", channelTemplate)
+	if err := validateChannelTemplate(channelTemplate); err != nil {
+		log.Fatal("Invalid CHANNEL_TEMPLATE:", err)
+	}
+	log.Printf("Channel template: %s", channelTemplate)
+
+	// Optional all-games snapshot channel
+	aggregateChannel = envString("AGGREGATE_CHANNEL", "")
+	aggregateMode = envString("AGGREGATE_MODE", aggregateFull)
+	if err := validateAggregateMode(aggregateMode); err != nil {
+		log.Fatal("Invalid AGGREGATE_MODE:", err)
+	}
+
+	// Reshaped game state payloads
+	if text := envString("PAYLOAD_TEMPLATE", ""); text != "" {
+		tmpl, err := parsePayloadTemplate(text)
+		if err != nil {
+			log.Fatal("Invalid PAYLOAD_TEMPLATE:", err)
+		}
+		payloadTemplate = tmpl
+		log.Println("✅ Rendering game states with PAYLOAD_TEMPLATE")
+	}
+
+	// Field naming of payloads and responses
+	outputCase = envString("OUTPUT_CASE", caseCamel)
+	if err := validateOutputCase(outputCase); err != nil {
+		log.Fatal("Invalid OUTPUT_CASE:", err)
+	}
+
+	// Optionally fan every game's events in to one channel
+	eventsFanIn = envString("EVENTS_CHANNEL", "")
+	if strings.ContainsAny(eventsFanIn, "{}") {
+		log.Fatalf("Invalid EVENTS_CHANNEL=%q: must be a plain channel name", eventsFanIn)
+	}
+	if eventsFanIn != "" {
+		log.Printf("Publishing all match events to %s", eventsFanIn)
+	}
 
-	// Test connection
-	if err := rdb.Ping(ctx).Err(); err != nil {
-		log.Fatal("Failed to connect to Redis:", err)
+	// Source instance tag on every published message
+	hostname, _ := os.Hostname()
+	providerID = envString("PROVIDER_ID", hostname)
+	log.Printf("Provider ID: %s", providerID)
+	region = envString("REGION", region)
+	log.Printf("Region: %s", region)
+
+	// Payload compression
+	c, err := newCompressor(envString("PUBLISH_COMPRESSION", ""))
+	if err != nil {
+		log.Fatal("Invalid PUBLISH_COMPRESSION:", err)
+	}
+	compressor = c
+	log.Printf("Payload compression: %s", compressor.codec)
+
+	// Skip publishes that don't change displayed values
+	dedupEnabled = envBool("DEDUP_PUBLISHES", false)
+	if dedupEnabled {
+		log.Println("Publish deduplication enabled")
+	}
+
+	// Display precision per sport
+	if items := envList("SPORT_PRECISION"); items != nil {
+		precisions, err := parseSportPrecision(items)
+		if err != nil {
+			log.Fatal("Invalid SPORT_PRECISION:", err)
+		}
+		sportPrecision = precisions
+		log.Printf("Odds precision by sport: %v", sportPrecision)
+	}
+
+	// Freshness hints for clients
+	oddsTTL = envDuration("ODDS_TTL", 0)
+	if oddsTTL < 0 || (oddsTTL > 0 && oddsTTL < time.Millisecond) {
+		log.Fatalf("Invalid ODDS_TTL=%v: must be 0 or at least 1ms", oddsTTL)
+	}
+	if items := envList("MARKET_TTL"); items != nil {
+		ttls, err := parseMarketTTLs(items)
+		if err != nil {
+			log.Fatal("Invalid MARKET_TTL:", err)
+		}
+		marketTTLs = ttls
+	}
+	if oddsTTL > 0 || len(marketTTLs) > 0 {
+		log.Printf("Odds TTL: %v, by market: %v", oddsTTL, marketTTLs)
+	}
+
+	// Only publish on changes in these categories
+	if items := envList("PUBLISH_TRIGGERS"); items != nil {
+		triggers, err := parsePublishTriggers(items)
+		if err != nil {
+			log.Fatal("Invalid PUBLISH_TRIGGERS:", err)
+		}
+		publishTriggers = triggers
+		if triggers != nil {
+			log.Printf("Publishing only on changes to: %v", items)
+		}
+	}
+
+	// Rounded plus full-precision odds in payloads
+	includeRawOdds = envBool("INCLUDE_RAW_ODDS", false)
+
+	// Price ladder for drifted odds
+	oddsTickSize = envFloat("ODDS_TICK_SIZE", 0)
+	if raw, _ := getenv("ODDS_TICK_SIZE"); raw != "" && oddsTickSize <= 0 {
+		log.Fatalf("Invalid ODDS_TICK_SIZE=%v: must be positive", oddsTickSize)
+	}
+	if oddsTickSize > 0 {
+		log.Printf("Odds tick size: %v", oddsTickSize)
+	}
+
+	// Pull drifting odds back toward their starting prices
+	meanReversion = envFloat("MEAN_REVERSION", 0)
+	if meanReversion < 0 || meanReversion > 1 {
+		log.Fatalf("Invalid MEAN_REVERSION=%v: must be within [0,1]", meanReversion)
+	}
+	if meanReversion > 0 {
+		log.Printf("Odds mean reversion: %v", meanReversion)
+	}
+	volatilityScale = envFloat("VOLATILITY_SCALE", volatilityScale)
+	if volatilityScale < 0 {
+		log.Fatalf("Invalid VOLATILITY_SCALE=%v: must not be negative", volatilityScale)
+	}
+
+	// Odds model: random walk, or derived from a Poisson goal model
+	driftModel = envString("DRIFT_MODEL", driftRandom)
+	if err := validateDriftModel(driftModel); err != nil {
+		log.Fatal("Invalid DRIFT_MODEL:", err)
+	}
+	if driftModel == driftPoisson {
+		poissonGoals = envFloat("POISSON_GOALS", poissonGoals)
+		if poissonGoals <= 0 {
+			log.Fatalf("Invalid POISSON_GOALS=%v: must be positive", poissonGoals)
+		}
+		poissonMargin = envFloat("POISSON_MARGIN", poissonMargin)
+		if poissonMargin < 0 || poissonMargin >= 1 {
+			log.Fatalf("Invalid POISSON_MARGIN=%v: must be within [0,1)", poissonMargin)
+		}
+		log.Printf("Odds from Poisson goal model: %v goals per match, %v margin", poissonGoals, poissonMargin)
+	}
+
+	// Rare large price moves
+	shockProbability = envFloat("SHOCK_PROBABILITY", shockProbability)
+	shockMagnitude = envFloat("SHOCK_MAGNITUDE", shockMagnitude)
+	shockCooldown = envDuration("SHOCK_COOLDOWN", shockCooldown)
+	if shockProbability < 0 || shockProbability > 1 || shockMagnitude <= 0 || shockMagnitude >= 1 || shockCooldown < 0 {
+		log.Fatalf("Invalid SHOCK_PROBABILITY=%v/SHOCK_MAGNITUDE=%v/SHOCK_COOLDOWN=%s: probability within [0,1], magnitude within (0,1), cooldown not negative", shockProbability, shockMagnitude, shockCooldown)
+	}
+	if shockProbability > 0 {
+		log.Printf("Price shocks: %v per tick, up to %.0f%% moves, %s apart", shockProbability, shockMagnitude*100, shockCooldown)
+	}
+
+	// Correlated moves across a league's games
+	leagueShockProbability = envFloat("LEAGUE_SHOCK_PROBABILITY", leagueShockProbability)
+	leagueShockMagnitude = envFloat("LEAGUE_SHOCK_MAGNITUDE", leagueShockMagnitude)
+	if leagueShockProbability < 0 || leagueShockProbability > 1 || leagueShockMagnitude <= 0 || leagueShockMagnitude >= 1 {
+		log.Fatalf("Invalid LEAGUE_SHOCK_PROBABILITY=%v/LEAGUE_SHOCK_MAGNITUDE=%v: probability within [0,1], magnitude within (0,1)", leagueShockProbability, leagueShockMagnitude)
+	}
+	if leagueShockProbability > 0 {
+		log.Printf("League shocks: %v per league per tick, up to %.0f%% moves", leagueShockProbability, leagueShockMagnitude*100)
 	}
-	log.Println("✅ Connected to Redis at", redisAddr)
 
-	// Initialize games
-	initializeGames()
-	log.Printf("✅ Initialized %d games", len(games))
+	// Referee stoppages hold the clock
+	stoppageProbability = envFloat("STOPPAGE_PROBABILITY", stoppageProbability)
+	stoppageDuration = envDuration("STOPPAGE_DURATION", stoppageDuration)
+	if stoppageProbability < 0 || stoppageProbability > 1 || stoppageDuration <= 0 {
+		log.Fatalf("Invalid STOPPAGE_PROBABILITY=%v/STOPPAGE_DURATION=%s: probability within [0,1], duration positive", stoppageProbability, stoppageDuration)
+	}
+	if stoppageProbability > 0 {
+		log.Printf("Referee stoppages: %v per tick, about %s each", stoppageProbability, stoppageDuration)
+	}
 
-	// Publish dummy data immediately
-	publishInitialDummyData(rdb)
+	// Simulated provider outages
+	if schedule := envString("DOWNTIME_SCHEDULE", ""); schedule != "" {
+		interval, duration, err := parseDowntimeSchedule(schedule)
+		if err != nil {
+			log.Fatal("Invalid DOWNTIME_SCHEDULE:", err)
+		}
+		downtimeInterval, downtimeDuration = interval, duration
+		log.Printf("Scheduled downtime: %s offline every %s", duration, interval)
+	}
 
-	// Start background jobs
-	go publishOddsUpdates(rdb)
-	go printMetrics()
+	// Bounded runs: each game ends after a fixed number of publishes
+	maxPublishesPerGame = envInt("MAX_PUBLISHES_PER_GAME", 0)
+	if maxPublishesPerGame < 0 {
+		log.Fatalf("Invalid MAX_PUBLISHES_PER_GAME=%d: must not be negative", maxPublishesPerGame)
+	}
+	if maxPublishesPerGame > 0 {
+		log.Printf("Publish budget: %d states per game", maxPublishesPerGame)
+	}
+
+	// Markets voided instead of resolved
+	voidProbability = envFloat("VOID_PROBABILITY", voidProbability)
+	if voidProbability < 0 || voidProbability > 1 {
+		log.Fatalf("Invalid VOID_PROBABILITY=%v: must be within [0,1]", voidProbability)
+	}
+	if voidProbability > 0 {
+		log.Printf("Voided markets: %v per tick", voidProbability)
+	}
+
+	// Live games whose odds stop moving point at a simulation bug
+	stuckThreshold = envInt("STUCK_ODDS_THRESHOLD", stuckThreshold)
+	if stuckThreshold < 0 {
+		log.Fatalf("Invalid STUCK_ODDS_THRESHOLD=%d: must not be negative", stuckThreshold)
+	}
+
+	// Keep drifting books from offering arbitrage
+	preventArbitrage = envBool("PREVENT_ARBITRAGE", preventArbitrage)
+	arbitrageMinMargin = envFloat("ARBITRAGE_MIN_MARGIN", arbitrageMinMargin)
+	if arbitrageMinMargin < 0 || arbitrageMinMargin >= 1 {
+		log.Fatalf("Invalid ARBITRAGE_MIN_MARGIN=%v: expected within [0,1)", arbitrageMinMargin)
+	}
+	if preventArbitrage {
+		log.Printf("Arbitrage prevention: books repriced to a %.1f%% margin", arbitrageMinMargin*100)
+	}
+
+	// Scheduled price reviews
+	reviewInterval = envDuration("REVIEW_INTERVAL", 0)
+	reviewDuration = envDuration("REVIEW_DURATION", 10*time.Second)
+	if reviewInterval < 0 || reviewDuration < 0 {
+		log.Fatal("Invalid REVIEW_INTERVAL/REVIEW_DURATION: must not be negative")
+	}
+	if reviewInterval > 0 && reviewDuration >= reviewInterval {
+		log.Fatalf("Invalid REVIEW_DURATION=%s: must be shorter than REVIEW_INTERVAL=%s", reviewDuration, reviewInterval)
+	}
+	if reviewInterval > 0 {
+		reviewEpoch = time.Now().UnixMilli()
+		log.Printf("Price reviews: markets suspended for %s every %s", reviewDuration, reviewInterval)
+	}
+
+	// Optional external true line the odds drift toward
+	if url := envString("TRUE_LINE_URL", ""); url != "" {
+		interval := envDuration("TRUE_LINE_INTERVAL", 5*time.Second)
+		if interval <= 0 {
+			log.Fatalf("Invalid TRUE_LINE_INTERVAL=%s: must be positive", interval)
+		}
+		trueLineStrength = envFloat("TRUE_LINE_STRENGTH", trueLineStrength)
+		if trueLineStrength < 0 || trueLineStrength > 1 {
+			log.Fatalf("Invalid TRUE_LINE_STRENGTH=%v: must be within [0,1]", trueLineStrength)
+		}
+		go pollTrueLine(url, interval)
+		log.Printf("Polling true line from %s every %s", url, interval)
+	}
+
+	// Debug-only knobs, never active unless DEBUG_ENDPOINTS=true
+	debugEnabled = envBool("DEBUG_ENDPOINTS", false)
+	if delayMs := envInt("DEBUG_PUBLISH_DELAY_MS", 0); delayMs > 0 {
+		if debugEnabled {
+			debugPublishDelay = time.Duration(delayMs) * time.Millisecond
+			log.Printf("⚠️  DEBUG: delaying every publish by %s", debugPublishDelay)
+		} else {
+			log.Println("⚠️  Ignoring DEBUG_PUBLISH_DELAY_MS: DEBUG_ENDPOINTS is not enabled")
+		}
+	}
+	if rate := envFloat("DEBUG_STALE_RATE", 0); rate > 0 {
+		if rate > 1 {
+			log.Fatalf("Invalid DEBUG_STALE_RATE=%v: must be within [0,1]", rate)
+		}
+		offsetMs := envInt("DEBUG_STALE_OFFSET_MS", int(staleOffset.Milliseconds()))
+		if offsetMs <= 0 {
+			log.Fatalf("Invalid DEBUG_STALE_OFFSET_MS=%d: must be positive", offsetMs)
+		}
+		if debugEnabled {
+			staleRate, staleOffset = rate, time.Duration(offsetMs)*time.Millisecond
+			log.Printf("⚠️  DEBUG: injecting stale duplicates of %.0f%% of updates, %s in the past", staleRate*100, staleOffset)
+		} else {
+			log.Println("⚠️  Ignoring DEBUG_STALE_RATE: DEBUG_ENDPOINTS is not enabled")
+		}
+	}
+	if rate := envFloat("WS_DROP_RATE", 0); rate > 0 {
+		if rate > 1 {
+			log.Fatalf("Invalid WS_DROP_RATE=%v: must be within [0,1]", rate)
+		}
+		if debugEnabled {
+			wsDropRate = rate
+			log.Printf("⚠️  DEBUG: dropping %.0f%% of messages to each /ws client", wsDropRate*100)
+		} else {
+			log.Println("⚠️  Ignoring WS_DROP_RATE: DEBUG_ENDPOINTS is not enabled")
+		}
+	}
+
+	// Backoff when Redis is out of memory
+	oomCooldown = envDuration("OOM_COOLDOWN", oomCooldown)
+	if oomCooldown <= 0 {
+		log.Fatalf("Invalid OOM_COOLDOWN=%s: must be positive", oomCooldown)
+	}
+
+	// Keys the feed writes that collide with keys of another type
+	skipWrongType = envBool("SKIP_WRONGTYPE_KEYS", false)
+
+	// Exit non-zero after a run of failed publishes, so the instance gets restarted
+	maxConsecutiveErrors = int64(envInt("MAX_CONSECUTIVE_ERRORS", 0))
+	if maxConsecutiveErrors < 0 {
+		log.Fatalf("Invalid MAX_CONSECUTIVE_ERRORS=%d: must not be negative", maxConsecutiveErrors)
+	}
+
+	// Retries, then the dead-letter channel for what still fails
+	publishRetries = envInt("PUBLISH_RETRIES", publishRetries)
+	publishRetryBackoff = envDuration("PUBLISH_RETRY_BACKOFF", publishRetryBackoff)
+	if publishRetries < 0 || publishRetryBackoff < 0 {
+		log.Fatal("Invalid PUBLISH_RETRIES/PUBLISH_RETRY_BACKOFF: must not be negative")
+	}
+	dlqChannel = envString("DLQ_CHANNEL", "")
+	if dlqChannel != "" {
+		log.Printf("Dead-lettering failed publishes to %s after %d retries", dlqChannel, publishRetries)
+	}
+
+	// Goal and status events mirrored to an external HTTP endpoint
+	if url := envString("WEBHOOK_URL", ""); url != "" {
+		timeout := envDuration("WEBHOOK_TIMEOUT", 5*time.Second)
+		retries := envInt("WEBHOOK_RETRIES", 2)
+		size := envInt("WEBHOOK_BUFFER_SIZE", 256)
+		if timeout <= 0 || retries < 0 || size <= 0 {
+			log.Fatal("Invalid WEBHOOK_TIMEOUT/WEBHOOK_RETRIES/WEBHOOK_BUFFER_SIZE: timeout and buffer size must be positive, retries not negative")
+		}
+		webhookDrainTimeout = envDuration("WEBHOOK_DRAIN_TIMEOUT", webhookDrainTimeout)
+		if webhookDrainTimeout < 0 {
+			log.Fatalf("Invalid WEBHOOK_DRAIN_TIMEOUT=%v: must not be negative", webhookDrainTimeout)
+		}
+		webhook = newWebhook(url, timeout, retries, size)
+		go webhook.run()
+		log.Println("✅ Mirroring goal and status events to webhook")
+	}
+
+	// Payload size guard
+	maxPayloadBytes = envInt("MAX_PAYLOAD_BYTES", maxPayloadBytes)
+	if maxPayloadBytes <= 0 {
+		log.Fatalf("Invalid MAX_PAYLOAD_BYTES=%d: must be positive", maxPayloadBytes)
+	}
+
+	// Repeats of a game's last payload point at a republishing bug
+	skipDuplicatePayloads = envBool("SKIP_DUPLICATE_PAYLOADS", false)
+
+	// Simulated partitions sit in front of the real broker in debug mode
+	if debugEnabled {
+		partition = &partitionPublisher{Publisher: publisher}
+		publisher = partition
+		registerDebugEndpoints()
+	}
+
+	// Per-game sequence numbers for gap detection
+	sequenceNumbers = envBool("PUBLISH_SEQ", sequenceNumbers)
+
+	// Optional payload signing
+	if key := envString("PUBLISH_HMAC_KEY", ""); key != "" {
+		hmacKey = []byte(key)
+		log.Println("✅ Signing payloads with HMAC-SHA256 (enveloped)")
+	}
+
+	// Compact binary game states; signing, templates and snake_case all
+	// work on the JSON encoding
+	publishFormat = envString("ENCODING", envString("PUBLISH_FORMAT", formatJSON))
+	if err := validatePublishFormat(publishFormat); err != nil {
+		log.Fatal("Invalid ENCODING:", err)
+	}
+	if publishFormat != formatJSON {
+		if len(hmacKey) > 0 || payloadTemplate != nil || outputCase != caseCamel {
+			log.Fatalf("Invalid ENCODING=%s: can't be combined with PUBLISH_HMAC_KEY, PAYLOAD_TEMPLATE or OUTPUT_CASE", publishFormat)
+		}
+		log.Printf("✅ Publishing game states as %s", publishFormat)
+	}
+
+	// Base seed for the per-game RNGs (random unless pinned for reproducible runs)
+	simSeed = int64(envInt("SIM_SEED", int(time.Now().UnixNano())))
+	log.Printf("Simulation seed: %d", simSeed)
+	leagueShockRng = rand.New(rand.NewSource(simSeed + 2))
+
+	// Match clock speed
+	timeScale = envFloat("TIME_SCALE", timeScale)
+	if timeScale <= 0 {
+		log.Fatalf("Invalid TIME_SCALE=%v: must be positive", timeScale)
+	}
+	if timeScale != 1 {
+		log.Printf("Time scale: %vx", timeScale)
+	}
+
+	// How busy the feed is, independent of the tick rate
+	gameUpdateProb = envFloat("GAME_UPDATE_PROB", gameUpdateProb)
+	if gameUpdateProb < 0 || gameUpdateProb > 1 {
+		log.Fatalf("Invalid GAME_UPDATE_PROB=%v: must be within [0,1]", gameUpdateProb)
+	}
+	marketUpdateProb = envFloat("MARKET_UPDATE_PROB", marketUpdateProb)
+	if marketUpdateProb < 0 || marketUpdateProb > 1 {
+		log.Fatalf("Invalid MARKET_UPDATE_PROB=%v: must be within [0,1]", marketUpdateProb)
+	}
+
+	// Goal simulation
+	goalProbability = envFloat("GOAL_PROBABILITY", goalProbability)
+	if goalProbability < 0 || goalProbability > 1 {
+		log.Fatalf("Invalid GOAL_PROBABILITY=%v: must be within [0,1]", goalProbability)
+	}
+	goalCooldown = envDuration("GOAL_COOLDOWN", goalCooldown)
+	if goalCooldown < 0 {
+		log.Fatalf("Invalid GOAL_COOLDOWN=%s: must not be negative", goalCooldown)
+	}
+	logEvents = envBool("LOG_EVENTS", logEvents)
+	cardProbability = envFloat("CARD_PROBABILITY", cardProbability)
+	if cardProbability < 0 || cardProbability > 1 {
+		log.Fatalf("Invalid CARD_PROBABILITY=%v: must be within [0,1]", cardProbability)
+	}
+
+	// Goals overturned by video review
+	varProbability = envFloat("VAR_PROBABILITY", varProbability)
+	varDelay = envDuration("VAR_DELAY", varDelay)
+	if varProbability < 0 || varProbability > 1 || varDelay <= 0 {
+		log.Fatalf("Invalid VAR_PROBABILITY=%v/VAR_DELAY=%s: probability within [0,1], delay positive", varProbability, varDelay)
+	}
+	if varProbability > 0 {
+		log.Printf("VAR: %.0f%% of goals disallowed after %s", varProbability*100, varDelay)
+	}
+
+	// Events of sports other than football
+	if items := envList("SPORT_EVENTS"); items != nil {
+		catalog, err := parseSportEvents(items)
+		if err != nil {
+			log.Fatal("Invalid SPORT_EVENTS:", err)
+		}
+		sportEvents = catalog
+	}
+	log.Printf("Sport event catalogs: %v", sportEvents)
+
+	// Built-in event generators; custom ones register here too
+	registerEventGenerator(scenarioGenerator{})
+	registerEventGenerator(goalGenerator{})
+	registerEventGenerator(varGenerator{})
+	registerEventGenerator(cardGenerator{})
+	registerEventGenerator(sportEventGenerator{})
+	registerEventGenerator(statsGenerator{})
+	if attendanceDrift = envBool("ATTENDANCE_DRIFT", false); attendanceDrift {
+		registerEventGenerator(attendanceGenerator{})
+	}
+
+	// Match lifecycle
+	fullTimeMinute = envInt("MATCH_LENGTH_MINUTES", fullTimeMinute)
+	if fullTimeMinute <= 0 {
+		log.Fatalf("Invalid MATCH_LENGTH_MINUTES=%d: must be positive", fullTimeMinute)
+	}
+	freezeMinute = envInt("ODDS_FREEZE_MINUTE", 0)
+	if freezeMinute < 0 || freezeMinute >= fullTimeMinute {
+		log.Fatalf("Invalid ODDS_FREEZE_MINUTE=%d: must be within [0,%d)", freezeMinute, fullTimeMinute)
+	}
+	endedGameTTL = envDuration("ENDED_GAME_TTL", 0)
+	if endedGameTTL < 0 {
+		log.Fatalf("Invalid ENDED_GAME_TTL=%s: must not be negative", endedGameTTL)
+	}
+	if endedGameTTL > 0 {
+		log.Printf("Ended games are removed after %s", endedGameTTL)
+	}
+
+	// Final ended state for deleted games and on shutdown
+	finalPublish = envBool("FINAL_PUBLISH", true)
+
+	// Per-game history, replayed to new stream subscribers
+	historySize := envInt("HISTORY_SIZE", 100)
+	if historySize <= 0 {
+		log.Fatalf("Invalid HISTORY_SIZE=%d: must be positive", historySize)
+	}
+	historyTotalLimit := envInt("HISTORY_TOTAL_LIMIT", 0)
+	if historyTotalLimit < 0 {
+		log.Fatalf("Invalid HISTORY_TOTAL_LIMIT=%d: must not be negative", historyTotalLimit)
+	}
+	history = newHistory(historySize, historyTotalLimit)
+	warmupSnapshots = envInt("WARMUP_SNAPSHOTS", 0)
+	if warmupSnapshots < 0 || warmupSnapshots > historySize {
+		log.Fatalf("Invalid WARMUP_SNAPSHOTS=%d: must be within [0,HISTORY_SIZE]", warmupSnapshots)
+	}
+
+	// Publish loop tick rate
+	interval := envDuration("PUBLISH_INTERVAL", defaultPublishInterval)
+	if interval <= 0 {
+		log.Fatalf("Invalid PUBLISH_INTERVAL=%s: must be positive", interval)
+	}
+	setPublishInterval(interval)
+
+	// Stall detection for the publish loop
+	watchdogTimeout = envDuration("FEED_WATCHDOG_TIMEOUT", 3*publishInterval())
+	if watchdogTimeout < publishInterval() {
+		log.Fatalf("Invalid FEED_WATCHDOG_TIMEOUT=%s: must be at least PUBLISH_INTERVAL (%s)", watchdogTimeout, publishInterval())
+	}
+
+	// Feed tiers, which games opt into with their tier field
+	tierIntervals = defaultTierIntervals(publishInterval())
+	if items := envList("TIER_INTERVALS"); items != nil {
+		tiers, err := parseTierIntervals(items)
+		if err != nil {
+			log.Fatal("Invalid TIER_INTERVALS:", err)
+		}
+		tierIntervals = tiers
+	}
+	log.Printf("Feed tiers: %v", tierIntervals)
+
+	// Publish rate following how much each game's odds move
+	adaptivePublish = envBool("ADAPTIVE_PUBLISH", false)
+	adaptiveMinInterval = envDuration("ADAPTIVE_MIN_INTERVAL", publishInterval())
+	adaptiveMaxInterval = envDuration("ADAPTIVE_MAX_INTERVAL", adaptiveMaxInterval)
+	adaptiveSensitivity = envFloat("ADAPTIVE_SENSITIVITY", adaptiveSensitivity)
+	if adaptiveMinInterval < publishInterval() || adaptiveMaxInterval < adaptiveMinInterval || adaptiveSensitivity <= 0 {
+		log.Fatalf("Invalid ADAPTIVE_MIN_INTERVAL=%s/ADAPTIVE_MAX_INTERVAL=%s/ADAPTIVE_SENSITIVITY=%v: need PUBLISH_INTERVAL <= min <= max and a positive sensitivity", adaptiveMinInterval, adaptiveMaxInterval, adaptiveSensitivity)
+	}
+	if adaptivePublish {
+		log.Printf("Adaptive publishing: every %s to %s, sensitivity %v", adaptiveMinInterval, adaptiveMaxInterval, adaptiveSensitivity)
+	}
+
+	// Live-tunable simulation knobs, re-read on SIGHUP
+	if tunablesFile = envString("TUNABLES_FILE", ""); tunablesFile != "" {
+		if err := reloadTunables(tunablesFile); err != nil {
+			log.Fatal("Invalid TUNABLES_FILE:", err)
+		}
+	}
+
+	// Names for synthetic games
+	pack, err := loadLocale(envString("LOCALE", defaultLocale))
+	if err != nil {
+		log.Fatal("Invalid LOCALE:", err)
+	}
+
+	// Initialize games, from GAMES_CONFIG when set (reloaded on SIGHUP), or
+	// NUM_GAMES synthetic ones
+	states := defaultGames()
+	numGames := envInt("NUM_GAMES", 0)
+	if numGames < 0 {
+		log.Fatalf("Invalid NUM_GAMES=%d: must not be negative", numGames)
+	}
+	if numGames > 0 {
+		start := time.Now()
+		states = syntheticGames(numGames, pack, simSeed)
+		log.Printf("Generated %d synthetic games in %s", numGames, time.Since(start).Round(time.Millisecond))
+	}
+	gamesConfig := envString("GAMES_CONFIG", "")
+	if path := gamesConfig; path != "" {
+		loaded, err := loadGamesConfig(path)
+		if err != nil {
+			log.Fatal("Invalid GAMES_CONFIG:", err)
+		}
+		states = loaded
+		log.Printf("Loaded %d games from %s", len(states), path)
+	}
+
+	// Sanity check each game's book before it goes live
+	bookMin = envFloat("BOOK_MARGIN_MIN", bookMin)
+	bookMax = envFloat("BOOK_MARGIN_MAX", bookMax)
+	if bookMin <= 0 || bookMax < bookMin {
+		log.Fatalf("Invalid BOOK_MARGIN_MIN=%v/BOOK_MARGIN_MAX=%v: need 0 < min <= max", bookMin, bookMax)
+	}
+	strictConfig = envBool("STRICT_CONFIG", false)
+	if problems := checkBooks(states, bookMin, bookMax); len(problems) > 0 {
+		for _, problem := range problems {
+			log.Printf("⚠️  WARN: implausible book for %s", problem)
+		}
+		if strictConfig {
+			log.Fatalf("STRICT_CONFIG: %d games with implausible books", len(problems))
+		}
+	}
+
+	// Repeated fixtures under different IDs; synthetic games may repeat
+	// pairings by design
+	if numGames == 0 || gamesConfig != "" {
+		if problems := checkMatchups(states); len(problems) > 0 {
+			for _, problem := range problems {
+				log.Printf("⚠️  WARN: duplicate fixture %s", problem)
+			}
+			if strictConfig {
+				log.Fatalf("STRICT_CONFIG: %d games with duplicate matchups", len(problems))
+			}
+		}
+	}
+
+	// Scripted games, which must be among the games about to start; each
+	// game's timeline is attached as it's created
+	if path := envString("SCENARIO_FILE", ""); path != "" {
+		loaded, err := loadScenarios(path, states)
+		if err != nil {
+			log.Fatalf("Invalid SCENARIO_FILE=%s: %v", path, err)
+		}
+		scenarios = loaded
+		log.Printf("Scenarios: %d scripted games", len(scenarios))
+	}
+
+	initializeGames(states)
+	go watchReload(gamesConfig)
+	log.Printf("✅ Initialized %d games", registry.Len())
+
+	// Endless demos replace finished games with fresh ones
+	autoRotate = envBool("AUTO_ROTATE", false)
+	rotateTarget = envInt("AUTO_ROTATE_GAMES", registry.Len())
+	if rotateTarget < 0 {
+		log.Fatalf("Invalid AUTO_ROTATE_GAMES=%d: must not be negative", rotateTarget)
+	}
+	if autoRotate {
+		rotatePack = pack
+		rotateRng = rand.New(rand.NewSource(simSeed + 1))
+		log.Printf("Auto-rotating games: keeping %d running", rotateTarget)
+		if endedGameTTL == 0 {
+			log.Printf("⚠️  WARN: AUTO_ROTATE without ENDED_GAME_TTL keeps every ended game")
+		}
+	}
+	logSquads()
+
+	// Restrict which games publish (all games stay in state)
+	filter, err := loadPublishFilter()
+	if err != nil {
+		log.Fatal("Invalid publish filter:", err)
+	}
+	publishFilter = filter
+
+	var active, channels []string
+	registered := registry.Snapshot()
+	for i := range registered {
+		if publishFilter.Allows(registered[i].ID) {
+			active = append(active, registered[i].ID)
+			channels = append(channels, gameChannel(&registered[i]))
+		}
+	}
+	sort.Strings(channels)
+	log.Printf("✅ Publishing %d/%d games: %v", len(active), len(registered), active)
+
+	// Bounded queue between the tick loop and the Redis publisher
+	pipelineSize = envInt("PUBLISH_PIPELINE", pipelineSize)
+	if pipelineSize <= 0 {
+		log.Fatalf("Invalid PUBLISH_PIPELINE=%d: must be positive", pipelineSize)
+	}
+	queue, err := newPublishQueue(envInt("PUBLISH_QUEUE_SIZE", 1024), envString("BACKPRESSURE", policyBlock), envInt("PUBLISH_WORKERS", 1))
+	if err != nil {
+		log.Fatal("Invalid publish queue settings:", err)
+	}
+	publishQueue = queue
+	publishQueue.run()
+	log.Printf("Publish queue: size=%d policy=%s workers=%d pipeline=%d", cap(queue.shards[0]), queue.policy, len(queue.shards), pipelineSize)
+
+	// Delayed copy of the feed, for consumers of delayed data
+	if delay := envDuration("DELAYED_FEED_DELAY", 0); delay != 0 {
+		if delay < 0 {
+			log.Fatalf("Invalid DELAYED_FEED_DELAY=%s: must not be negative", delay)
+		}
+		delayedFeed = newDelayedFeed(delay)
+		log.Printf("Republishing game states on %s after %s", delayedChannel("<channel>"), delay)
+	}
+
+	// Dummy data seeds clients before the feed starts, in the background so
+	// HTTP is up (and /readyz reports seeding) in the meantime
+	seedDummyData := envBool("SEED_DUMMY_DATA", true)
+	seeding.Store(seedDummyData)
+
+	// Start background jobs (the feed can be held back so the backend is
+	// healthy before it goes live)
+	startDelay := envDuration("PUBLISH_START_DELAY", 0)
+	if startDelay < 0 {
+		log.Fatalf("Invalid PUBLISH_START_DELAY=%s: must not be negative", startDelay)
+	}
+	if startDelay > 0 {
+		log.Printf("Delaying feed start by %s", startDelay)
+	}
+	staggerStart = envBool("STAGGER_START", false)
+	staggerWindow = envDuration("STAGGER_WINDOW", staggerWindow)
+	if staggerWindow < time.Millisecond {
+		log.Fatalf("Invalid STAGGER_WINDOW=%s: must be at least 1ms", staggerWindow)
+	}
+	if staggerStart {
+		log.Printf("Staggering first publishes over %s", staggerWindow)
+	}
+	go runFeed(seedDummyData, startDelay)
+	go printMetrics(newMetricsSink())
+	go runWatchdog()
+
+	// On-demand snapshots over pub/sub, for clients joining late
+	if envBool("SNAPSHOT_REQUESTS", false) {
+		go serveSnapshots()
+		log.Printf("Answering snapshot requests on %s with replies on %s", snapshotRequestChannel, snapshotReplyChannel)
+	}
+
+	// Downstream subscriber counts, for capacity planning
+	subscriberPoll := envDuration("SUBSCRIBER_POLL_INTERVAL", 10*time.Second)
+	if subscriberPoll <= 0 {
+		log.Fatalf("Invalid SUBSCRIBER_POLL_INTERVAL=%s: must be positive", subscriberPoll)
+	}
+	subscriberQueryTimeout = envDuration("SUBSCRIBER_QUERY_TIMEOUT", subscriberQueryTimeout)
+	if subscriberQueryTimeout <= 0 || subscriberQueryTimeout > subscriberPoll {
+		log.Fatalf("Invalid SUBSCRIBER_QUERY_TIMEOUT=%s: must be positive and at most SUBSCRIBER_POLL_INTERVAL (%s)", subscriberQueryTimeout, subscriberPoll)
+	}
+	subscriberCounts.Store(map[string]int64{})
+	go pollSubscribers(subscriberPoll)
 
 	// HTTP health endpoint
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":           "healthy",
-			"deltasPublished":  atomic.LoadInt64(&metrics.deltasPublished),
-			"publishErrors":    atomic.LoadInt64(&metrics.publishErrors),
-			"gamesCount":       len(games),
-		})
-	})
+	http.HandleFunc("/health", handleHealth)
+
+	// Game REST endpoints
+	http.HandleFunc("/games", handleGames)
+	http.HandleFunc("/games/", handleGameRoutes)
 
-	// HTTP metrics endpoint
-	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"deltasPublished": atomic.LoadInt64(&metrics.deltasPublished),
-			"publishErrors":   atomic.LoadInt64(&metrics.publishErrors),
-		})
+	// Channel listing
+	http.HandleFunc("/channels", handleChannels)
+
+	// Native WebSocket feed, every connection sharing one broker subscription
+	feedHub = newWSHub(subscriber)
+	http.HandleFunc("/ws", handleWebSocket)
+
+	// League endpoints
+	http.HandleFunc("/leagues", handleLeagues)
+	http.HandleFunc("/leagues/", handleLeagueRoutes)
+
+	// HTTP metrics endpoints: Prometheus for scrapers, JSON for everything
+	// else
+	http.HandleFunc("/metrics", handleMetrics)
+	http.HandleFunc("/metrics.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, metricsSnapshot())
 	})
+	http.HandleFunc("/metrics/detailed", handleDetailedMetrics)
+	http.HandleFunc("/config", handleConfig)
+	if prometheusEnabled = envBool("PROMETHEUS_METRICS", false); prometheusEnabled {
+		http.HandleFunc("/metrics/prometheus", handlePrometheusMetrics)
+		log.Println("✅ Serving per-game Prometheus metrics on /metrics/prometheus")
+	}
+
+	// Final games and metrics written on shutdown
+	stateDumpFile = envString("STATE_DUMP_FILE", "")
+
+	// Run for a fixed time then shut down cleanly, e.g. for load tests
+	maxRuntime := envDuration("MAX_RUNTIME", 0)
+	if maxRuntime < 0 {
+		log.Fatalf("Invalid MAX_RUNTIME=%s: must not be negative", maxRuntime)
+	}
+	if maxRuntime > 0 {
+		log.Printf("Shutting down after %s", maxRuntime)
+	}
 
-	port := ":8080"
-	log.Printf("HTTP server listening on %s", port)
-	log.Println("Publishing odds updates to Redis channels: game1, game2, game3")
+	// Per-connection /ws buffering and what happens to clients that overflow it
+	slowClientPolicy = envString("SLOW_CLIENT_POLICY", slowClientPolicy)
+	if err := validateSlowClientPolicy(slowClientPolicy); err != nil {
+		log.Fatal("Invalid SLOW_CLIENT_POLICY:", err)
+	}
+	wsClientBuffer = envInt("WS_CLIENT_BUFFER", wsClientBuffer)
+	if wsClientBuffer <= 0 {
+		log.Fatalf("Invalid WS_CLIENT_BUFFER=%d: must be positive", wsClientBuffer)
+	}
+	wsPingInterval = envDuration("WS_PING_INTERVAL", wsPingInterval)
+	if wsPingInterval <= 0 {
+		log.Fatalf("Invalid WS_PING_INTERVAL=%s: must be positive", wsPingInterval)
+	}
+
+	// In-process WebSocket clients churning against /ws, for load testing
+	selftestClients := envInt("WS_SELFTEST_CLIENTS", 0)
+	selftestReconnect := envDuration("WS_SELFTEST_RECONNECT", 10*time.Second)
+	if selftestClients < 0 || selftestReconnect <= 0 {
+		log.Fatal("Invalid WS_SELFTEST_CLIENTS/WS_SELFTEST_RECONNECT: clients must not be negative and reconnect must be positive")
+	}
+
+	logEffectiveConfig()
 
-	if err := http.ListenAndServe(port, nil); err != nil {
-		log.Fatal("HTTP server error:", err)
+	log.Printf("Publishing odds updates to Redis channels: %s", strings.Join(channels, ", "))
+
+	// Everything is registered and configured: open the rest of the API
+	gamesReady.Store(true)
+	if selftestClients > 0 {
+		go runWSSelftest(addr, selftestClients, selftestReconnect)
 	}
+
+	waitForShutdown(server, maxRuntime)
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// runMain re-runs the test binary as the backend itself
+	if os.Getenv("BACKEND_RUN_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	compressor, _ = newCompressor("none")
+	broker := newInprocBroker()
+	publisher, latest, subscriber = broker, broker, broker
+	tierIntervals = defaultTierIntervals(publishInterval())
+	os.Exit(m.Run())
+}
+
+// useGames runs a test against a fresh registry of states, the default games
+// when none are given
+func useGames(t *testing.T, states ...GameState) {
+	t.Helper()
+	if len(states) == 0 {
+		states = defaultGames()
+	}
+	previous := registry
+	registry = newGameRegistry()
+	initializeGames(states)
+	t.Cleanup(func() { registry = previous })
+}
+
+// fakePublisher records every publish instead of sending it anywhere
+type fakePublisher struct {
+	mu        sync.Mutex
+	published []brokerMessage
+	err       error // returned by every Publish when set
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, channel string, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, brokerMessage{Channel: channel, Payload: append([]byte(nil), payload...)})
+	return nil
+}
+
+func (p *fakePublisher) Ping(ctx context.Context) error { return nil }
+
+// messages returns what was published so far
+func (p *fakePublisher) messages() []brokerMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]brokerMessage(nil), p.published...)
+}
+
+// captureQueue swaps in a publish queue without workers, so a test can read
+// back what was enqueued with drain
+func captureQueue(t *testing.T) *PublishQueue {
+	t.Helper()
+	queue, err := newPublishQueue(256, policyBlock, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := publishQueue
+	publishQueue = queue
+	t.Cleanup(func() { publishQueue = previous })
+	return queue
+}
+
+// drain returns the messages waiting in a queue from captureQueue
+func (q *PublishQueue) drain() []outbound {
+	var messages []outbound
+	for _, ch := range q.shards {
+		for len(ch) > 0 {
+			messages = append(messages, <-ch)
+		}
+	}
+	return messages
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// strictConfig turns configuration warnings into errors (STRICT_CONFIG):
+// startup fails on implausible books or repeated matchups, and creating a game
+// that repeats a matchup is rejected
+var strictConfig bool
+
+// matchupKey identifies a fixture by its teams, ignoring case
+func matchupKey(g *GameState) string {
+	return strings.ToLower(g.HomeTeam) + "\x00" + strings.ToLower(g.AwayTeam)
+}
+
+// checkMatchups returns a message for every game with the same home and away
+// teams as an earlier one, whatever their IDs
+func checkMatchups(states []GameState) []string {
+	var problems []string
+	first := map[string]string{}
+	for i := range states {
+		key := matchupKey(&states[i])
+		if id, ok := first[key]; ok {
+			problems = append(problems, fmt.Sprintf("%s: same matchup as %s (%s v %s)", states[i].ID, id, states[i].HomeTeam, states[i].AwayTeam))
+			continue
+		}
+		first[key] = states[i].ID
+	}
+	return problems
+}
+
+// existingMatchup returns the ID of one of games with the same teams as g, if
+// any. Call under the registry lock.
+func existingMatchup(games map[string]*Game, g *GameState) (string, bool) {
+	key := matchupKey(g)
+	for id, game := range games {
+		if id != g.ID && matchupKey(&game.GameState) == key {
+			return id, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDuplicateMatchupsWarnOrFail(t *testing.T) {
+	states := []GameState{
+		{ID: "game1", HomeTeam: "Arsenal", AwayTeam: "Chelsea"},
+		{ID: "game2", HomeTeam: "Chelsea", AwayTeam: "Arsenal"},
+		{ID: "game3", HomeTeam: "ARSENAL", AwayTeam: "chelsea"},
+	}
+	for i := range states {
+		states[i].HomeOdds, states[i].AwayOdds, states[i].DrawOdds = 2.5, 2.8, 3.2
+	}
+	problems := checkMatchups(states)
+	if len(problems) != 1 || !strings.HasPrefix(problems[0], "game3: same matchup as game1") {
+		t.Errorf("checkMatchups = %q, want game3 flagged as a repeat of game1", problems)
+	}
+
+	path := writeGamesConfig(t, states)
+	if out := runMain(t, "GAMES_CONFIG="+path, "STRICT_CONFIG=true"); !strings.Contains(out, "STRICT_CONFIG: 1 games with duplicate matchups") {
+		t.Errorf("strict startup with a repeated matchup doesn't fail on it:\n%s", out)
+	}
+}
+
+func TestCreateGameWithDuplicateMatchup(t *testing.T) {
+	useGames(t)
+	previous := strictConfig
+	t.Cleanup(func() { strictConfig = previous })
+	body := func(id string) string {
+		return `{"id":"` + id + `","homeTeam":"arsenal","awayTeam":"chelsea","homeOdds":2,"awayOdds":3,"drawOdds":3.2}`
+	}
+
+	strictConfig = true
+	if rec := serve(handleGames, http.MethodPost, "/games", body("rematch1")); rec.Code != http.StatusConflict {
+		t.Errorf("strict POST of game1's matchup = %d, want 409", rec.Code)
+	}
+	strictConfig = false
+	if rec := serve(handleGames, http.MethodPost, "/games", body("rematch2")); rec.Code != http.StatusCreated {
+		t.Errorf("POST of game1's matchup = %d, want 201 with a warning", rec.Code)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// oomCooldown is how long publishing backs off after Redis refuses a write
+// for lack of memory (OOM_COOLDOWN)
+var oomCooldown = 5 * time.Second
+
+// oomUntil is when the current OOM backoff ends (unix millis)
+var oomUntil atomic.Int64
+
+// isOOM reports whether err is Redis refusing a command at maxmemory
+func isOOM(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "OOM ")
+}
+
+// isCanceled reports whether err is a publish cut short by shutdown
+// cancelling ctx rather than a Redis failure
+func isCanceled(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// inOOMBackoff reports whether publishing is backing off after an OOM
+func inOOMBackoff() bool {
+	return time.Now().UnixMilli() < oomUntil.Load()
+}
+
+// noteOOM counts an OOM error and starts the cooldown, logging once per
+// cooldown rather than once per failed publish
+func noteOOM(err error) {
+	atomic.AddInt64(&metrics.oomErrors, 1)
+	if inOOMBackoff() {
+		return
+	}
+	oomUntil.Store(time.Now().Add(oomCooldown).UnixMilli())
+	log.Printf("❌ Redis is out of memory (%v). Pausing publishes for %s. Raise maxmemory, set an eviction policy such as allkeys-lru, or free keys (e.g. stale *:latest)", err, oomCooldown)
+}
+
+// publishFailed accounts for a failed publish, telling a full Redis apart
+// from every other failure
+func publishFailed(err error, what string) {
+	if isCanceled(err) {
+		return
+	}
+	if isOOM(err) {
+		noteOOM(err)
+		return
+	}
+	atomic.AddInt64(&metrics.publishErrors, 1)
+	log.Printf("Error publishing %s to Redis: %v", what, err)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// pipelineSize is the most queued messages a worker sends in one Redis
+// pipeline (PUBLISH_PIPELINE); 1 sends each message on its own
+var pipelineSize = 1
+
+// BatchPublisher is implemented by brokers that can send several messages in
+// one round trip. Each message gets its own error: a pipeline can fail some
+// commands and not others.
+type BatchPublisher interface {
+	PublishBatch(ctx context.Context, batch []outbound) []error
+}
+
+// collectBatch takes first plus whatever else is already queued on ch, up to
+// pipelineSize messages, without waiting
+func collectBatch(ch chan outbound, first outbound) []outbound {
+	batch := []outbound{first}
+	for len(batch) < pipelineSize {
+		select {
+		case m, ok := <-ch:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, m)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// sendBatch makes one attempt at every message in the batch, pipelined when
+// the broker supports it
+func sendBatch(batch []outbound) []error {
+	if batcher, ok := publisher.(BatchPublisher); ok && debugPublishDelay == 0 {
+		return batcher.PublishBatch(ctx, batch)
+	}
+	errs := make([]error, len(batch))
+	for i, m := range batch {
+		errs[i] = send(m)
+	}
+	return errs
+}
+
+// deliverBatch publishes a batch like deliver does each message: every message
+// is accounted for on its own, and only the ones that failed are retried.
+// Retries keep their order, though a retried message lands after later
+// messages of its batch that went through first time.
+func deliverBatch(batch []outbound) {
+	if inOOMBackoff() {
+		atomic.AddInt64(&metrics.droppedUpdates, int64(len(batch)))
+		return
+	}
+
+	pending, errs := batch, sendBatch(batch)
+	for attempt := 1; ; attempt++ {
+		var failed []outbound
+		var failedErrs []error
+		for i, m := range pending {
+			switch {
+			case errs[i] == nil:
+				delivered(m)
+			case isOOM(errs[i]) || isCanceled(errs[i]) || attempt > publishRetries:
+				undelivered(m, errs[i])
+			default:
+				failed = append(failed, m)
+				failedErrs = append(failedErrs, errs[i])
+			}
+		}
+		if len(failed) == 0 {
+			return
+		}
+
+		time.Sleep(time.Duration(attempt) * publishRetryBackoff)
+		pending, errs = failed, sendBatch(failed)
+	}
+}
+
+// PublishBatch sends the batch as one pipeline, game states with their latest
+// cache, state hash and state stream writes. Exec only reports the first failure, so each
+// message's own publish command decides its result. Cache and hash failures
+// are only logged, the same as without a pipeline.
+func (p *redisPublisher) PublishBatch(ctx context.Context, batch []outbound) []error {
+	pipe := p.client.Pipeline()
+	publishes := make([]*redis.IntCmd, len(batch))
+	var caches []redis.Cmder
+	for i, m := range batch {
+		publishes[i] = pipe.Publish(ctx, m.channel, m.data)
+		caches = append(caches, queueCaches(ctx, pipe, m)...)
+	}
+	start := time.Now()
+	pipe.Exec(ctx)
+	publishLatency.Observe(time.Since(start))
+
+	logCacheErrors(caches)
+	return publishErrors(publishes)
+}
+
+// PublishBatch pipelines the publishes through the default DB's client, since
+// channels are server-wide, and each game's keyed writes to its own DB
+func (p *routedPublisher) PublishBatch(ctx context.Context, batch []outbound) []error {
+	pipes := map[*redisPublisher]redis.Pipeliner{p.fallback: p.fallback.client.Pipeline()}
+	publishes := make([]*redis.IntCmd, len(batch))
+	var caches []redis.Cmder
+	for i, m := range batch {
+		publishes[i] = pipes[p.fallback].Publish(ctx, m.channel, m.data)
+		target := p.forGame(m.gameID)
+		if pipes[target] == nil {
+			pipes[target] = target.client.Pipeline()
+		}
+		caches = append(caches, queueCaches(ctx, pipes[target], m)...)
+	}
+
+	// Keyed writes land before the publishes, so a client that hears a state
+	// can already read it back
+	for target, pipe := range pipes {
+		if target != p.fallback {
+			pipe.Exec(ctx)
+		}
+	}
+	start := time.Now()
+	pipes[p.fallback].Exec(ctx)
+	publishLatency.Observe(time.Since(start))
+
+	logCacheErrors(caches)
+	return publishErrors(publishes)
+}
+
+// queueCaches queues a game state's latest cache, state hash and state stream
+// writes; other kinds cache nothing
+func queueCaches(ctx context.Context, pipe redis.Pipeliner, m outbound) []redis.Cmder {
+	if m.kind != kindState && m.kind != kindFinal {
+		return nil
+	}
+	caches := []redis.Cmder{pipe.Set(ctx, latestKey(m.gameID), m.data, 0)}
+	if m.hash != nil && !skipsKey(hashKey(m.gameID)) {
+		caches = append(caches, pipe.HSet(ctx, hashKey(m.gameID), m.hash))
+	}
+	if streamWriter != nil && !skipsKey(streamKey(m.gameID)) {
+		caches = append(caches, queueStreamAppend(ctx, pipe, m.gameID, m.seq, m.data)...)
+	}
+	return caches
+}
+
+func publishErrors(publishes []*redis.IntCmd) []error {
+	errs := make([]error, len(publishes))
+	for i, cmd := range publishes {
+		errs[i] = cmd.Err()
+	}
+	return errs
+}
+
+func logCacheErrors(caches []redis.Cmder) {
+	for _, cmd := range caches {
+		if err := cmd.Err(); isOOM(err) {
+			noteOOM(err)
+		} else if isWrongType(err) {
+			noteWrongType(err, cmd.Args()[1].(string))
+		} else if err != nil {
+			log.Printf("Error caching state (%s): %v", cmd.Name(), err)
+		}
+	}
+}
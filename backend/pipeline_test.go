@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+// flakyBatcher fails each channel's first failures[channel] attempts
+type flakyBatcher struct {
+	fakePublisher
+	failures map[string]int
+	attempts map[string]int
+}
+
+func (p *flakyBatcher) PublishBatch(ctx context.Context, batch []outbound) []error {
+	errs := make([]error, len(batch))
+	for i, m := range batch {
+		p.attempts[m.channel]++
+		if p.attempts[m.channel] <= p.failures[m.channel] {
+			errs[i] = errors.New("pipeline command failed")
+			continue
+		}
+		errs[i] = p.Publish(ctx, m.channel, m.data)
+	}
+	return errs
+}
+
+func TestDeliverBatchRetriesOnlyFailedCommands(t *testing.T) {
+	pub := &flakyBatcher{failures: map[string]int{"flaky": 1, "broken": 100}, attempts: map[string]int{}}
+	previous, previousRetries, previousBackoff := publisher, publishRetries, publishRetryBackoff
+	publisher, publishRetries, publishRetryBackoff = pub, 2, 0
+	t.Cleanup(func() { publisher, publishRetries, publishRetryBackoff = previous, previousRetries, previousBackoff })
+	errorsBefore := atomic.LoadInt64(&metrics.publishErrors)
+	eventsBefore := atomic.LoadInt64(&metrics.eventsPublished)
+
+	deliverBatch([]outbound{
+		{kind: kindEvent, channel: "ok", data: []byte("{}")},
+		{kind: kindEvent, channel: "flaky", data: []byte("{}")},
+		{kind: kindEvent, channel: "broken", data: []byte("{}")},
+	})
+
+	if pub.attempts["ok"] != 1 || pub.attempts["flaky"] != 2 || pub.attempts["broken"] != 3 {
+		t.Errorf("attempts = %v, want ok 1, flaky 2 and broken 3", pub.attempts)
+	}
+	if got := atomic.LoadInt64(&metrics.eventsPublished) - eventsBefore; got != 2 {
+		t.Errorf("eventsPublished grew by %d, want 2", got)
+	}
+	if got := atomic.LoadInt64(&metrics.publishErrors) - errorsBefore; got != 1 {
+		t.Errorf("publishErrors grew by %d, want 1 for the broken command", got)
+	}
+}
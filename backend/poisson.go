@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Drift models (DRIFT_MODEL): a random walk per market, or 1X2 odds derived
+// from a Poisson goal model of the rest of the match
+const (
+	driftRandom  = "random"
+	driftPoisson = "poisson"
+)
+
+var driftModel = driftRandom
+
+// poissonGoals is the expected total goals over a full match, split between
+// the teams by their starting odds (POISSON_GOALS)
+var poissonGoals = 2.6
+
+// poissonMargin is the bookmaker margin added on top of the model's fair
+// probabilities (POISSON_MARGIN), 0.05 prices a 105% book
+var poissonMargin = 0.05
+
+// maxPoissonGoals truncates the per-team goal distribution; the tail beyond
+// it is negligible at football scoring rates
+const maxPoissonGoals = 12
+
+// maxModelOdds caps prices for outcomes the model makes near impossible
+const maxModelOdds = 1000.0
+
+func validateDriftModel(model string) error {
+	switch model {
+	case driftRandom, driftPoisson:
+		return nil
+	default:
+		return fmt.Errorf("unknown model %q (expected random or poisson)", model)
+	}
+}
+
+// scoringRates splits poissonGoals between home and away in proportion to
+// the implied probabilities of their starting odds
+func scoringRates(baseOdds map[string]float64) (home, away float64) {
+	homeOdds, awayOdds := baseOdds[marketHome], baseOdds[marketAway]
+	if homeOdds <= 0 || awayOdds <= 0 {
+		return poissonGoals / 2, poissonGoals / 2
+	}
+	share := (1 / homeOdds) / (1/homeOdds + 1/awayOdds)
+	return poissonGoals * share, poissonGoals * (1 - share)
+}
+
+// poissonPMF returns P(0..n goals) for a Poisson mean
+func poissonPMF(mean float64, n int) []float64 {
+	pmf := make([]float64, n+1)
+	pmf[0] = math.Exp(-mean)
+	for k := 1; k <= n; k++ {
+		pmf[k] = pmf[k-1] * mean / float64(k)
+	}
+	return pmf
+}
+
+// outcomeProbabilities is the chance of each final result given the current
+// score, with each team's remaining goals Poisson over the time left
+func outcomeProbabilities(game *Game) (home, away, draw float64) {
+	remaining := 1 - game.clock/60/float64(fullTimeMinute)
+	if remaining < 0 {
+		remaining = 0
+	}
+	homeGoals := poissonPMF(game.homeRate*remaining, maxPoissonGoals)
+	awayGoals := poissonPMF(game.awayRate*remaining, maxPoissonGoals)
+
+	lead := game.HomeScore - game.AwayScore
+	for i, ph := range homeGoals {
+		for j, pa := range awayGoals {
+			switch final := lead + i - j; {
+			case final > 0:
+				home += ph * pa
+			case final < 0:
+				away += ph * pa
+			default:
+				draw += ph * pa
+			}
+		}
+	}
+	return home, away, draw
+}
+
+// modelOdds prices a probability with the bookmaker margin applied
+func modelOdds(p float64) float64 {
+	odds := maxModelOdds
+	if p > 0 {
+		odds = math.Min(maxModelOdds, 1/(p*(1+poissonMargin)))
+	}
+	odds = snapToTick(odds, oddsTickSize)
+	return math.Max(oddsFloor, odds)
+}
+
+// applyPoissonOdds reprices every open market from the goal model. Call
+// under the registry lock.
+func applyPoissonOdds(game *Game, now int64) {
+	home, away, draw := outcomeProbabilities(game)
+	probabilities := map[string]float64{marketHome: home, marketAway: away, marketDraw: draw}
+	for _, market := range marketNames {
+		m, ok := game.Markets[market]
+		if !ok || m.Status == marketSuspended {
+			continue
+		}
+		if odds := modelOdds(probabilities[market]); odds != m.Odds {
+			game.setOdds(market, odds, now)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPoissonOutcomesFollowScoreAndClock(t *testing.T) {
+	game := testGame()
+	game.HomeScore, game.AwayScore = 1, 0
+
+	game.clock = 0
+	home, away, draw := outcomeProbabilities(game)
+	if sum := home + away + draw; math.Abs(sum-1) > 1e-6 {
+		t.Errorf("outcome probabilities sum to %v, want 1", sum)
+	}
+	kickoffLead := home
+
+	// With the clock run down a one-goal lead is all but won
+	game.clock = float64(fullTimeMinute*60) - 60
+	home, away, draw = outcomeProbabilities(game)
+	if home <= kickoffLead || home < 0.9 {
+		t.Errorf("home win chance at minute %d = %v, want above %v and 0.9", fullTimeMinute-1, home, kickoffLead)
+	}
+
+	game.clock = float64(fullTimeMinute * 60)
+	if home, away, draw = outcomeProbabilities(game); home != 1 || away != 0 || draw != 0 {
+		t.Errorf("at full time probabilities = %v/%v/%v, want 1/0/0", home, away, draw)
+	}
+}
+
+func TestApplyPoissonOddsPricesWithMargin(t *testing.T) {
+	game := testGame()
+	game.HomeScore, game.AwayScore, game.clock = 0, 0, 0
+	applyPoissonOdds(game, game.now())
+
+	book := 0.0
+	for _, market := range marketNames {
+		book += 1 / game.Markets[market].Odds
+	}
+	// Snapping to the tick moves the book a little either way
+	if want := 1 + poissonMargin; math.Abs(book-want) > 0.01 {
+		t.Errorf("implied book = %v, want about %v", book, want)
+	}
+	if modelOdds(0) != maxModelOdds {
+		t.Errorf("modelOdds(0) = %v, want the %v cap", modelOdds(0), maxModelOdds)
+	}
+}
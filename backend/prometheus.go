@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// prometheusEnabled also serves the original per-game series on
+// /metrics/prometheus (PROMETHEUS_METRICS); /metrics is always Prometheus
+var prometheusEnabled bool
+
+// gameSeries holds the labeled series of one game
+type gameSeries struct {
+	published int64
+	errors    int64
+	odds      map[string]float64 // per market, as last published
+}
+
+// promSeries tracks per-game series for games that exist. Series of deleted
+// games are dropped on the next scrape and never recreated, so cardinality
+// stays bounded by the live game count.
+var promSeries = struct {
+	sync.Mutex
+	games map[string]*gameSeries
+}{games: map[string]*gameSeries{}}
+
+// gameExists reports whether a game is registered. It takes the registry
+// lock, so must not be called with promSeries held.
+func gameExists(gameID string) bool {
+	return registry.Has(gameID)
+}
+
+// seriesFor returns a game's series, creating it. Call with promSeries held.
+func seriesFor(gameID string) *gameSeries {
+	s, ok := promSeries.games[gameID]
+	if !ok {
+		s = &gameSeries{odds: map[string]float64{}}
+		promSeries.games[gameID] = s
+	}
+	return s
+}
+
+// observePublish counts a game state publish, or a failed one
+func observePublish(gameID string, failed bool) {
+	if !gameExists(gameID) {
+		return
+	}
+	promSeries.Lock()
+	defer promSeries.Unlock()
+	if failed {
+		seriesFor(gameID).errors++
+	} else {
+		seriesFor(gameID).published++
+	}
+}
+
+// observeOdds records the odds a game is publishing
+func observeOdds(state GameState) {
+	if !gameExists(state.ID) {
+		return
+	}
+	promSeries.Lock()
+	defer promSeries.Unlock()
+	s := seriesFor(state.ID)
+	for name, m := range state.Markets {
+		s.odds[name] = m.Odds
+	}
+}
+
+// lockSeries locks promSeries, drops the series of deleted games and returns
+// the IDs of the rest, sorted. The caller unlocks promSeries.
+func lockSeries() []string {
+	live := map[string]bool{}
+	registry.Read(func(games map[string]*Game) {
+		for id := range games {
+			live[id] = true
+		}
+	})
+
+	promSeries.Lock()
+	for id := range promSeries.games {
+		if !live[id] {
+			delete(promSeries.games, id)
+		}
+	}
+
+	ids := make([]string, 0, len(promSeries.games))
+	for id := range promSeries.games {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// GET /metrics serves the backend's metrics in the Prometheus text format,
+// for a stock scrape config; metricsSnapshot stays on /metrics.json
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var b strings.Builder
+	ids := lockSeries()
+	b.WriteString("# HELP websocket_poc_deltas_published_total Game states published, per game.\n# TYPE websocket_poc_deltas_published_total counter\n")
+	for _, id := range ids {
+		fmt.Fprintf(&b, "websocket_poc_deltas_published_total{game=%s} %d\n", labelValue(id), promSeries.games[id].published)
+	}
+	promSeries.Unlock()
+	b.WriteString("# HELP websocket_poc_states_published_total Game states published across all games, deleted ones included.\n# TYPE websocket_poc_states_published_total counter\n")
+	fmt.Fprintf(&b, "websocket_poc_states_published_total %d\n", atomic.LoadInt64(&metrics.deltasPublished)+atomic.LoadInt64(&metrics.finalPublishes))
+
+	b.WriteString("# HELP websocket_poc_publish_errors_total Messages that failed every publish attempt.\n# TYPE websocket_poc_publish_errors_total counter\n")
+	fmt.Fprintf(&b, "websocket_poc_publish_errors_total %d\n", atomic.LoadInt64(&metrics.publishErrors))
+	b.WriteString("# HELP websocket_poc_games Games currently registered.\n# TYPE websocket_poc_games gauge\n")
+	fmt.Fprintf(&b, "websocket_poc_games %d\n", registry.Len())
+	publishLatency.write(&b, "websocket_poc_publish_latency_seconds", "Time taken by each Redis publish call.")
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(b.String()))
+}
+
+// write renders the histogram in the text format, with cumulative buckets
+func (h *Histogram) write(b *strings.Builder, name, help string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	var cumulative int64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		fmt.Fprintf(b, "%s_bucket{le=%s} %d\n", name, labelValue(strconv.FormatFloat(bound, 'g', -1, 64)), cumulative)
+	}
+	fmt.Fprintf(b, "%s_bucket{le=\"+Inf\"} %d\n", name, h.total)
+	fmt.Fprintf(b, "%s_sum %s\n%s_count %d\n", name, strconv.FormatFloat(h.sum, 'g', -1, 64), name, h.total)
+}
+
+// GET /metrics/prometheus
+func handlePrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ids := lockSeries()
+	var b strings.Builder
+	b.WriteString("# HELP odds_published_total Game states published, per game.\n# TYPE odds_published_total counter\n")
+	for _, id := range ids {
+		fmt.Fprintf(&b, "odds_published_total{game=%s} %d\n", labelValue(id), promSeries.games[id].published)
+	}
+	b.WriteString("# HELP publish_errors_total Game state publishes that failed every retry, per game.\n# TYPE publish_errors_total counter\n")
+	for _, id := range ids {
+		fmt.Fprintf(&b, "publish_errors_total{game=%s} %d\n", labelValue(id), promSeries.games[id].errors)
+	}
+	b.WriteString("# HELP odds Odds as last published, per game and market.\n# TYPE odds gauge\n")
+	for _, id := range ids {
+		odds := promSeries.games[id].odds
+		markets := make([]string, 0, len(odds))
+		for name := range odds {
+			markets = append(markets, name)
+		}
+		sort.Strings(markets)
+		for _, name := range markets {
+			fmt.Fprintf(&b, "odds{game=%s,market=%s} %s\n", labelValue(id), labelValue(name), strconv.FormatFloat(odds[name], 'g', -1, 64))
+		}
+	}
+	promSeries.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(b.String()))
+}
+
+// labelValue quotes a label value, escaping as the text format requires
+func labelValue(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
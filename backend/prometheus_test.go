@@ -0,0 +1,172 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPrometheusSeriesFollowLiveGames(t *testing.T) {
+	useGames(t)
+	promSeries.Lock()
+	promSeries.games = map[string]*gameSeries{}
+	promSeries.Unlock()
+
+	observePublish("game1", false)
+	observePublish("game1", false)
+	observePublish("game2", true)
+	observePublish("ghost", false)
+	state, _ := registry.Get("game1")
+	observeOdds(state)
+
+	body := serve(handlePrometheusMetrics, http.MethodGet, "/metrics/prometheus", "").Body.String()
+	for _, want := range []string{
+		`odds_published_total{game="game1"} 2`,
+		`publish_errors_total{game="game2"} 1`,
+		`odds{game="game1",market="home"} `,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("scrape is missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "ghost") {
+		t.Errorf("scrape has a series for an unregistered game:\n%s", body)
+	}
+
+	registry.Remove("game1", func(*Game) {})
+	observePublish("game1", false)
+	body = serve(handlePrometheusMetrics, http.MethodGet, "/metrics/prometheus", "").Body.String()
+	if strings.Contains(body, `game="game1"`) {
+		t.Errorf("scrape still has series for a deleted game:\n%s", body)
+	}
+}
+
+// sampleLine is one sample of the text format: name, optional labels, value
+var sampleLine = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(?:\{(.*)\})? (\S+)$`)
+
+// histogramSuffix marks the samples a histogram family is made of
+var histogramSuffix = regexp.MustCompile(`_(bucket|sum|count)$`)
+
+// parseLabels reads a sample's label set, undoing the text format's escapes
+func parseLabels(t *testing.T, raw string) map[string]string {
+	t.Helper()
+	labels := map[string]string{}
+	for raw != "" {
+		name, rest, ok := strings.Cut(raw, `="`)
+		if !ok {
+			t.Fatalf("label without a quoted value: %q", raw)
+		}
+		var value strings.Builder
+		i := 0
+		for ; i < len(rest) && rest[i] != '"'; i++ {
+			if rest[i] != '\\' {
+				value.WriteByte(rest[i])
+				continue
+			}
+			i++
+			switch rest[i] {
+			case 'n':
+				value.WriteByte('\n')
+			case '\\', '"':
+				value.WriteByte(rest[i])
+			default:
+				t.Fatalf("invalid escape \\%c in %q", rest[i], raw)
+			}
+		}
+		labels[name] = value.String()
+		raw = strings.TrimPrefix(rest[i+1:], ",")
+	}
+	return labels
+}
+
+// scrape parses /metrics, checking every sample belongs to a family with HELP
+// and TYPE lines ahead of it. Samples are keyed by name, then by the value of
+// their game label.
+func scrape(t *testing.T) (map[string]map[string]float64, map[string]string) {
+	t.Helper()
+	rec := serve(handleMetrics, http.MethodGet, "/metrics", "")
+	if got := rec.Header().Get("Content-Type"); got != "text/plain; version=0.0.4" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	samples := map[string]map[string]float64{}
+	types := map[string]string{}
+	helped := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n") {
+		if help, ok := strings.CutPrefix(line, "# HELP "); ok {
+			name, text, _ := strings.Cut(help, " ")
+			if text == "" {
+				t.Errorf("%s has an empty HELP", name)
+			}
+			helped[name] = true
+			continue
+		}
+		if typ, ok := strings.CutPrefix(line, "# TYPE "); ok {
+			name, kind, _ := strings.Cut(typ, " ")
+			if !helped[name] {
+				t.Errorf("TYPE for %s before its HELP", name)
+			}
+			types[name] = kind
+			continue
+		}
+
+		m := sampleLine.FindStringSubmatch(line)
+		if m == nil {
+			t.Fatalf("unparseable line %q", line)
+		}
+		family := m[1]
+		if types[family] == "" {
+			family = histogramSuffix.ReplaceAllString(family, "")
+			if types[family] != "histogram" {
+				t.Errorf("sample %s has no TYPE line before it", m[1])
+			}
+		}
+		value, err := strconv.ParseFloat(m[3], 64)
+		if err != nil {
+			t.Errorf("sample %s has value %q: %v", m[1], m[3], err)
+		}
+		if samples[m[1]] == nil {
+			samples[m[1]] = map[string]float64{}
+		}
+		samples[m[1]][parseLabels(t, m[2])["game"]] = value
+	}
+	return samples, types
+}
+
+func TestMetricsExpositionCountsSeededStates(t *testing.T) {
+	weird := defaultGames()[0]
+	weird.ID = "cup \"final\"\n\\leg"
+	useGames(t, weird, defaultGames()[1])
+	promSeries.Lock()
+	promSeries.games = map[string]*gameSeries{}
+	promSeries.Unlock()
+
+	before, _ := scrape(t)
+	publishInitialDummyData(&fakePublisher{}, 2, 0)
+	after, types := scrape(t)
+
+	for name, want := range map[string]string{
+		"websocket_poc_deltas_published_total":  "counter",
+		"websocket_poc_states_published_total":  "counter",
+		"websocket_poc_publish_errors_total":    "counter",
+		"websocket_poc_games":                   "gauge",
+		"websocket_poc_publish_latency_seconds": "histogram",
+	} {
+		if types[name] != want {
+			t.Errorf("TYPE of %s = %q, want %s", name, types[name], want)
+		}
+	}
+	perGame := after["websocket_poc_deltas_published_total"]
+	if perGame[weird.ID] != 2 || perGame["game2"] != 2 {
+		t.Errorf("per-game counts after seeding 2 rounds = %v, want 2 each, the escaped ID read back as %q", perGame, weird.ID)
+	}
+	total := "websocket_poc_states_published_total"
+	if got := after[total][""] - before[total][""]; got != 4 {
+		t.Errorf("%s rose by %v over the seed, want 4", total, got)
+	}
+	if got := after["websocket_poc_games"][""]; got != 2 {
+		t.Errorf("websocket_poc_games = %v, want 2", got)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// formatProtoByte prefixes Protocol Buffers game states, like
+// formatMsgpackByte does MessagePack ones
+const formatProtoByte byte = 0x11
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// protoBuffer appends proto3 fields, leaving out zero values as proto3 does.
+// The schema is state.proto; it's encoded by hand to keep the backend free of
+// generated code.
+type protoBuffer []byte
+
+func (b *protoBuffer) tag(field, wireType int) {
+	*b = binary.AppendUvarint(*b, uint64(field)<<3|uint64(wireType))
+}
+
+func (b *protoBuffer) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, wireVarint)
+	*b = binary.AppendUvarint(*b, v)
+}
+
+// int encodes an int32 or int64 field; negatives take ten bytes, as in proto
+func (b *protoBuffer) int(field int, v int64) {
+	b.uint(field, uint64(v))
+}
+
+func (b *protoBuffer) bool(field int, v bool) {
+	if v {
+		b.uint(field, 1)
+	}
+}
+
+func (b *protoBuffer) double(field int, v float64) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, wireFixed64)
+	*b = binary.LittleEndian.AppendUint64(*b, math.Float64bits(v))
+}
+
+func (b *protoBuffer) string(field int, s string) {
+	if s == "" {
+		return
+	}
+	b.bytes(field, []byte(s))
+}
+
+func (b *protoBuffer) bytes(field int, data []byte) {
+	b.tag(field, wireBytes)
+	*b = binary.AppendUvarint(*b, uint64(len(data)))
+	*b = append(*b, data...)
+}
+
+// message encodes a nested message, written even when empty
+func (b *protoBuffer) message(field int, encode func(*protoBuffer)) {
+	var inner protoBuffer
+	encode(&inner)
+	b.bytes(field, inner)
+}
+
+// sortedKeys orders a map's keys, so the same state always encodes the same
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// encodeProto encodes a game state as a Protocol Buffers Envelope behind the
+// format byte
+func encodeProto(state *GameState, seq uint64) ([]byte, error) {
+	b := protoBuffer{formatProtoByte}
+	b.string(1, typeState)
+	b.uint(2, wireVersion)
+	b.uint(3, seq)
+	b.string(4, providerID)
+	b.string(5, region)
+	wire := state.wire()
+	b.message(6, func(g *protoBuffer) { encodeProtoState(g, &wire) })
+	return compressor.Encode(b)
+}
+
+func encodeProtoState(b *protoBuffer, s *wireState) {
+	b.string(1, s.ID)
+	b.string(2, s.HomeTeam)
+	b.string(3, s.AwayTeam)
+	b.string(4, s.League)
+	b.string(5, s.Tier)
+	b.bool(6, s.Featured)
+	for _, tag := range s.Tags {
+		b.bytes(7, []byte(tag))
+	}
+	b.bool(8, s.Shock)
+	b.bool(9, s.Stopped)
+	b.bool(10, s.Suspended)
+	b.string(11, s.Sport)
+	b.string(12, s.Status)
+	b.int(13, s.KickoffTime)
+	b.int(14, int64(s.Attendance))
+	b.double(15, s.Importance)
+	b.int(16, int64(s.HomeScore))
+	b.int(17, int64(s.AwayScore))
+	b.int(18, int64(s.Minute))
+	b.double(19, s.HomeOdds)
+	b.double(20, s.AwayOdds)
+	b.double(21, s.DrawOdds)
+	for _, name := range sortedKeys(s.Markets) {
+		m := s.Markets[name]
+		b.message(22, func(entry *protoBuffer) {
+			entry.string(1, name)
+			entry.message(2, func(market *protoBuffer) {
+				market.double(1, m.Odds)
+				market.string(2, m.Status)
+				market.double(3, m.Volatility)
+				market.int(4, m.LastUpdated)
+				market.int(5, m.TTLMs)
+				market.bool(6, m.Voided)
+			})
+		})
+	}
+	for _, name := range sortedKeys(s.Stats) {
+		b.message(23, func(entry *protoBuffer) {
+			entry.string(1, name)
+			entry.double(2, s.Stats[name])
+		})
+	}
+	b.int(24, s.LastUpdated)
+	b.int(25, s.TTLMs)
+	if s.Squads != nil {
+		b.message(26, func(squads *protoBuffer) {
+			for _, player := range s.Squads.Home {
+				squads.bytes(1, []byte(player))
+			}
+			for _, player := range s.Squads.Away {
+				squads.bytes(2, []byte(player))
+			}
+		})
+	}
+	b.double(27, s.HomeOddsRaw)
+	b.double(28, s.AwayOddsRaw)
+	b.double(29, s.DrawOddsRaw)
+}
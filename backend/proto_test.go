@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// decodeProto decodes an encodeProto payload with the schema in state.proto
+func decodeProto(t *testing.T, data []byte) *dynamicpb.Message {
+	t.Helper()
+	compiler := protocompile.Compiler{Resolver: &protocompile.SourceResolver{}}
+	files, err := compiler.Compile(context.Background(), "state.proto")
+	if err != nil {
+		t.Fatalf("compiling state.proto: %v", err)
+	}
+	if data[0] != formatProtoByte {
+		t.Fatalf("first byte = %#x, want %#x", data[0], formatProtoByte)
+	}
+	msg := dynamicpb.NewMessage(files[0].Messages().ByName("Envelope"))
+	if err := proto.Unmarshal(data[1:], msg); err != nil {
+		t.Fatalf("decoding payload: %v", err)
+	}
+	return msg
+}
+
+func field(m protoreflect.Message, name string) protoreflect.Value {
+	return m.Get(m.Descriptor().Fields().ByName(protoreflect.Name(name)))
+}
+
+func TestEncodeProtoRoundTrip(t *testing.T) {
+	game := testGame()
+	state := &game.GameState
+	state.Tags = []string{"derby", "tv"}
+	state.Stopped = true
+	state.KickoffTime = 1700000000000
+	state.Attendance = 60000
+	state.Stats = map[string]float64{"possessionHome": 55, "shotsAway": 4}
+	state.Markets[marketHome].Status = "suspended"
+
+	data, err := encodeProto(state, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := decodeProto(t, data)
+	if len(env.GetUnknown()) > 0 {
+		t.Errorf("envelope has fields state.proto doesn't define")
+	}
+	if got := field(env, "type").String(); got != typeState {
+		t.Errorf("type = %q, want state", got)
+	}
+	if got := field(env, "version").Uint(); got != wireVersion {
+		t.Errorf("version = %d, want %d", got, wireVersion)
+	}
+	if got := field(env, "seq").Uint(); got != 42 {
+		t.Errorf("seq = %d, want 42", got)
+	}
+
+	s := field(env, "state").Message()
+	if len(s.GetUnknown()) > 0 {
+		t.Errorf("state has fields state.proto doesn't define")
+	}
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"id", field(s, "id").String(), state.ID},
+		{"home_team", field(s, "home_team").String(), state.HomeTeam},
+		{"league", field(s, "league").String(), state.League},
+		{"clock_stopped", field(s, "clock_stopped").Bool(), true},
+		{"status", field(s, "status").String(), state.Status},
+		{"kickoff_time", field(s, "kickoff_time").Int(), state.KickoffTime},
+		{"attendance", field(s, "attendance").Int(), int64(state.Attendance)},
+		{"home_score", field(s, "home_score").Int(), int64(state.HomeScore)},
+		{"minute", field(s, "minute").Int(), int64(state.Minute)},
+		{"home_odds", field(s, "home_odds").Float(), state.HomeOdds},
+		{"draw_odds", field(s, "draw_odds").Float(), state.DrawOdds},
+		{"last_updated", field(s, "last_updated").Int(), state.LastUpdated},
+		{"tags", field(s, "tags").List().Len(), 2},
+		{"stats", field(s, "stats").Map().Get(protoreflect.ValueOfString("possessionHome").MapKey()).Float(), 55.0},
+		{"markets", field(s, "markets").Map().Len(), len(state.Markets)},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	home := field(s, "markets").Map().Get(protoreflect.ValueOfString(marketHome).MapKey()).Message()
+	if got := field(home, "odds").Float(); got != state.Markets[marketHome].Odds {
+		t.Errorf("markets[home].odds = %v, want %v", got, state.Markets[marketHome].Odds)
+	}
+	if got := field(home, "status").String(); got != "suspended" {
+		t.Errorf("markets[home].status = %q, want suspended", got)
+	}
+}
+
+// protoWireState reads a decoded GameState message back into the wire
+// projection it was encoded from
+func protoWireState(s protoreflect.Message) wireState {
+	var w wireState
+	w.ID = field(s, "id").String()
+	w.HomeTeam = field(s, "home_team").String()
+	w.AwayTeam = field(s, "away_team").String()
+	w.League = field(s, "league").String()
+	w.Tier = field(s, "tier").String()
+	w.Featured = field(s, "featured").Bool()
+	tags := field(s, "tags").List()
+	for i := 0; i < tags.Len(); i++ {
+		w.Tags = append(w.Tags, tags.Get(i).String())
+	}
+	w.Shock = field(s, "shock").Bool()
+	w.Stopped = field(s, "clock_stopped").Bool()
+	w.Suspended = field(s, "suspended").Bool()
+	w.Sport = field(s, "sport").String()
+	w.Status = field(s, "status").String()
+	w.KickoffTime = field(s, "kickoff_time").Int()
+	w.Attendance = int(field(s, "attendance").Int())
+	w.Importance = field(s, "importance").Float()
+	w.HomeScore = int(field(s, "home_score").Int())
+	w.AwayScore = int(field(s, "away_score").Int())
+	w.Minute = int(field(s, "minute").Int())
+	w.HomeOdds = field(s, "home_odds").Float()
+	w.AwayOdds = field(s, "away_odds").Float()
+	w.DrawOdds = field(s, "draw_odds").Float()
+	field(s, "markets").Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+		if w.Markets == nil {
+			w.Markets = map[string]*Market{}
+		}
+		m := v.Message()
+		w.Markets[k.String()] = &Market{
+			Odds:        field(m, "odds").Float(),
+			Status:      field(m, "status").String(),
+			Volatility:  field(m, "volatility").Float(),
+			LastUpdated: field(m, "last_updated").Int(),
+			TTLMs:       field(m, "ttl_ms").Int(),
+			Voided:      field(m, "voided").Bool(),
+		}
+		return true
+	})
+	field(s, "stats").Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+		if w.Stats == nil {
+			w.Stats = map[string]float64{}
+		}
+		w.Stats[k.String()] = v.Float()
+		return true
+	})
+	if s.Has(s.Descriptor().Fields().ByName("squads")) {
+		squads := field(s, "squads").Message()
+		w.Squads = &Squads{}
+		for side, players := range map[string]*[]string{"home": &w.Squads.Home, "away": &w.Squads.Away} {
+			list := field(squads, side).List()
+			for i := 0; i < list.Len(); i++ {
+				*players = append(*players, list.Get(i).String())
+			}
+		}
+	}
+	w.LastUpdated = field(s, "last_updated").Int()
+	w.TTLMs = field(s, "ttl_ms").Int()
+	w.HomeOddsRaw = field(s, "home_odds_raw").Float()
+	w.AwayOddsRaw = field(s, "away_odds_raw").Float()
+	w.DrawOddsRaw = field(s, "draw_odds_raw").Float()
+	return w
+}
+
+func TestEncodeProtoCoversEveryField(t *testing.T) {
+	includeRawOdds = true
+	defer func() { includeRawOdds = false }()
+	state := GameState{
+		ID: "game1", HomeTeam: "Arsenal", AwayTeam: "Chelsea", League: "premier-league", Tier: "premium",
+		Featured: true, Tags: []string{"derby"}, Shock: true, Stopped: true, Suspended: true,
+		Sport: "football", Status: "live", KickoffTime: 1700000000000, Attendance: 60000, Importance: 2.5,
+		HomeScore: 2, AwayScore: 1, Minute: 67, HomeOdds: 1.8765, AwayOdds: 4.2345, DrawOdds: 3.5555,
+		Markets: map[string]*Market{
+			marketHome: {Odds: 1.8765, Status: "suspended", Volatility: 0.02, LastUpdated: 1700000001000, TTLMs: 1500, Voided: true},
+		},
+		Stats:       map[string]float64{"possessionHome": 55},
+		Squads:      &Squads{Home: []string{"Saka"}, Away: []string{"Palmer"}},
+		LastUpdated: 1700000002000,
+		TTLMs:       1000,
+	}
+
+	// Every field a state publishes is set, so one added to GameState
+	// without the schema fails here; remainingPublishes is REST-only
+	want := state.wire()
+	var unset func(v reflect.Value)
+	unset = func(v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			switch {
+			case f.Anonymous:
+				unset(v.Field(i))
+			case f.Name == "Remaining":
+			case v.Field(i).IsZero():
+				t.Errorf("the test state leaves %s unset", f.Name)
+			}
+		}
+	}
+	unset(reflect.ValueOf(want))
+	for _, m := range want.Markets {
+		unset(reflect.ValueOf(*m))
+	}
+
+	data, err := encodeProto(&state, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := field(decodeProto(t, data), "state").Message()
+
+	// Nothing encoded outside the schema, and nothing in the schema left out
+	for _, m := range []protoreflect.Message{
+		s,
+		field(s, "squads").Message(),
+		field(s, "markets").Map().Get(protoreflect.ValueOfString(marketHome).MapKey()).Message(),
+	} {
+		if len(m.GetUnknown()) > 0 {
+			t.Errorf("%s has fields state.proto doesn't define", m.Descriptor().Name())
+		}
+		fields := m.Descriptor().Fields()
+		for i := 0; i < fields.Len(); i++ {
+			if !m.Has(fields.Get(i)) {
+				t.Errorf("%s.%s isn't encoded", m.Descriptor().Name(), fields.Get(i).Name())
+			}
+		}
+	}
+
+	if got := protoWireState(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded state\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"log"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// defaultPublishInterval is the publish loop's tick rate without
+// PUBLISH_INTERVAL
+const defaultPublishInterval = 200 * time.Millisecond
+
+// tickNanos is the publish loop's tick rate in nanoseconds, zero for the
+// default. The loop changes it on a tunables reload while the watchdog and
+// HTTP handlers read it.
+var tickNanos atomic.Int64
+
+// publishInterval is the tick rate of the publish loop (PUBLISH_INTERVAL)
+func publishInterval() time.Duration {
+	if interval := time.Duration(tickNanos.Load()); interval > 0 {
+		return interval
+	}
+	return defaultPublishInterval
+}
+
+func setPublishInterval(interval time.Duration) {
+	tickNanos.Store(int64(interval))
+}
+
+// debugPublishDelay is artificial latency added before each publish to
+// simulate a slow broker (DEBUG_PUBLISH_DELAY_MS, debug mode only)
+var debugPublishDelay time.Duration
+
+// runFeed seeds the dummy data, waits out the start delay and runs the publish
+// loop until stopFeed is closed. feedStopped is closed once it returns, whether
+// or not the loop ever started, so shutdown can always wait on it.
+func runFeed(seed bool, startDelay time.Duration) {
+	defer close(feedStopped)
+	if seed {
+		publishInitialDummyData(publisher, seedRounds, seedPause)
+		seeding.Store(false)
+	}
+	select {
+	case <-time.After(startDelay):
+	case <-stopFeed:
+		return
+	}
+	// The delay and a shutdown can come due together, the shutdown wins
+	select {
+	case <-stopFeed:
+		return
+	default:
+	}
+	publishOddsUpdates()
+}
+
+func publishOddsUpdates() {
+	// High frequency updates (200ms)
+	ticker := time.NewTicker(publishInterval())
+	defer ticker.Stop()
+
+	log.Println("Starting to publish game updates to Redis...")
+	lastTickAt.Store(time.Now().UnixMilli())
+	feedStartedAt.Store(time.Now().UnixMilli())
+	feedActive.Store(true)
+	defer feedActive.Store(false)
+
+	for {
+		select {
+		case <-stopFeed:
+			log.Println("Publish loop stopped")
+			return
+		case interval := <-intervalChanges:
+			log.Printf("Tunable publishInterval: %s -> %s", publishInterval(), interval)
+			setPublishInterval(interval)
+			ticker.Reset(interval)
+			continue
+		case <-ticker.C:
+		}
+
+		// A scheduled downtime skips the tick, though the loop is still alive
+		start := time.Now()
+		if updateDowntime(start.UnixMilli()) {
+			lastTickAt.Store(start.UnixMilli())
+			continue
+		}
+		safeTick(publishTick)
+		lastTickAt.Store(time.Now().UnixMilli())
+
+		// A tick that overruns the interval means the feed is falling behind
+		if elapsed := time.Since(start); elapsed > publishInterval() {
+			atomic.AddInt64(&metrics.slowTicks, 1)
+			log.Printf("⚠️  Slow tick: took %s (interval %s)", elapsed.Round(time.Millisecond), publishInterval())
+		}
+	}
+}
+
+// safeTick runs one tick of work, recovering from any panic so a single bad
+// tick can't silently kill the publisher goroutine
+func safeTick(tick func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			atomic.AddInt64(&metrics.panics, 1)
+			log.Printf("❌ Recovered from panic in publish loop: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	tick()
+}
+
+// publishTick works from a snapshot of the games taken at the start of the
+// tick, so games created or deleted mid-tick can't disturb the iteration
+func publishTick() {
+	applyLeagueShocks()
+
+	var updated []string
+	for _, game := range registry.Games() {
+		if !publishFilter.Allows(game.ID) {
+			continue
+		}
+
+		atomic.AddInt64(&metrics.gamesConsidered, 1)
+		update, ok := advanceGame(game)
+		if !ok {
+			continue
+		}
+		if update.data == nil {
+			publishEvents(update.channel, update.events)
+			publishScenarioMessages(game.ID, update)
+			continue
+		}
+		atomic.AddInt64(&metrics.gamesPublished, 1)
+
+		// Publish to the game's Redis channel
+		publishQueue.Enqueue(outbound{kind: kindState, gameID: game.ID, channel: update.channel, data: update.data, hash: stateHash(update.state), seq: update.seq, computed: update.computed})
+		updated = append(updated, game.ID)
+		recordState(update.state, update.seq)
+		observeOdds(update.state)
+
+		// Deliberately out-of-order duplicates, debug mode only; these skip
+		// the latest cache
+		if stale, ok := staleCopy(update); ok {
+			publishQueue.Enqueue(outbound{kind: kindStale, gameID: game.ID, channel: update.channel, data: stale})
+		}
+
+		// Tiered games are also published on their tier's channel
+		if update.state.Tier != "" {
+			publishQueue.Enqueue(outbound{kind: kindTier, gameID: game.ID, channel: tierChannel(update.state.Tier), data: update.data})
+		}
+		if delayedFeed != nil {
+			delayedFeed.Add(game.ID, update.channel, update.data)
+		}
+
+		publishEvents(update.channel, update.events)
+		publishScenarioMessages(game.ID, update)
+	}
+
+	publishLeagues(updated)
+	publishAggregate(updated)
+	rotateGames()
+}
+
+// latestKey is the Redis key caching a game's most recently published payload
+func latestKey(gameID string) string {
+	return gameID + ":latest"
+}
+
+// publishState publishes a game's payload on its channel and caches it under
+// latestKey, so a client joining late can read the current state first and
+// then follow the live channel. With STATE_STREAM it's also appended to the
+// game's stream under its sequence number.
+func publishState(channel, gameID string, seq uint64, data []byte) error {
+	return publishStateTo(publisher, channel, gameID, seq, data)
+}
+
+// publishStateTo is publishState on a given publisher
+func publishStateTo(pub Publisher, channel, gameID string, seq uint64, data []byte) error {
+	if debugPublishDelay > 0 {
+		time.Sleep(debugPublishDelay)
+	}
+
+	if err := timedPublishTo(pub, channel, data); err != nil {
+		return err
+	}
+	if err := latest.SetLatest(ctx, gameID, data); isOOM(err) {
+		noteOOM(err)
+	} else if err != nil && !isCanceled(err) {
+		log.Printf("Error caching latest state for %s: %v", gameID, err)
+	}
+	appendStateStream(gameID, seq, data)
+	return nil
+}
+
+// gameUpdate is what one tick produced for a game, ready to publish. Data is
+// nil when only events are published.
+type gameUpdate struct {
+	channel string
+	data    []byte
+	state   GameState // copy of the published state, safe to use unlocked
+	events  []MatchEvent
+	seq     uint64 // the state's sequence number
+
+	computed time.Time // when the update was built
+}
+
+// advanceGame drifts one game's odds and simulates goals, returning the
+// encoded update and any match events, or false if there is nothing to
+// publish. The lock is released before publishing.
+func advanceGame(game *Game) (update gameUpdate, ok bool) {
+	// Ended games are removed once their grace period is up
+	if registry.RemoveGame(game, func(game *Game) bool { return expired(game, game.now()) }) {
+		log.Printf("Removed ended game %s", game.ID)
+		return gameUpdate{}, false
+	}
+
+	// Nothing happens to a game deleted since the snapshot was taken
+	registry.UpdateGame(game, func(game *Game) { update, ok = stepGame(game) })
+	return update, ok
+}
+
+// stepGame is one tick of advanceGame, under the registry lock
+func stepGame(game *Game) (gameUpdate, bool) {
+	now := game.now()
+
+	// Ended games are frozen
+	if game.Status == statusEnded {
+		return gameUpdate{}, false
+	}
+
+	// Scheduled games wait for kickoff, with no clock, drift or events
+	if game.Status == statusScheduled {
+		return scheduledUpdate(game, now)
+	}
+
+	// Suspended games hold still until resumed
+	if update, publish, ok := suspendedUpdate(game, now); ok {
+		return update, publish
+	}
+
+	// Scripted games break at half time
+	if update, publish, ok := halfTimeUpdate(game, publishInterval(), now); ok {
+		return update, publish
+	}
+
+	// The clock runs every tick, whether or not the game publishes, unless
+	// a stoppage holds it
+	stoppage := updateStoppage(game, publishInterval())
+	advanceClock(game, publishInterval())
+
+	// Full time and price reviews always publish, skipping the gate and dedup
+	if ended, ok := endMatch(game, now); ok {
+		settleScenario(game, now)
+		return forcedUpdate(game, "final state for "+game.ID, ended)
+	}
+	if ended, ok := endOnBudget(game, now); ok {
+		return forcedUpdate(game, "final state for "+game.ID, ended)
+	}
+	if review, ok := applyReview(game, now); ok {
+		return forcedUpdate(game, "review state for "+game.ID, review)
+	}
+
+	// So do stoppages starting and ending
+	if stoppage {
+		game.LastUpdated = now
+		return forcedUpdate(game, "stoppage state for "+game.ID)
+	}
+
+	// Voided markets publish their void marker straight away
+	if void, ok := applyVoid(game, now); ok {
+		return forcedUpdate(game, "void state for "+game.ID, void)
+	}
+
+	// Price shocks, the game's own or its league's, publish straight away
+	// too, annotated as such
+	if driftModel == driftRandom {
+		if _, ok := applyShock(game, now); ok || game.leagueShocked {
+			game.leagueShocked = false
+			game.LastUpdated = now
+			game.Shock = true
+			update, ok := forcedUpdate(game, "shock state for "+game.ID)
+			game.Shock = false
+			return update, ok
+		}
+	}
+
+	// Staggered games hold their first publish until their offset is up
+	if staggered(game.ID, now) {
+		return gameUpdate{}, false
+	}
+
+	// Adaptive games only publish once their odds-dependent wait is up,
+	// tiered ones once their tier's interval is
+	if !adaptiveDue(game, now) || !tierDue(game, now) {
+		return gameUpdate{}, false
+	}
+
+	// Not every game updates every tick
+	if game.rng.Float64() >= gameUpdateProb {
+		return gameUpdate{}, false
+	}
+
+	// The goal model reprices from clock and score, otherwise odds drift randomly
+	if driftModel == driftPoisson {
+		applyPoissonOdds(game, now)
+	} else {
+		driftMarkets(game, now)
+	}
+	restoreMargin(game, now)
+
+	game.LastUpdated = now
+
+	// Nothing happens on the pitch while play is stopped
+	var events []MatchEvent
+	if !game.Stopped {
+		events = generateEvents(game)
+	}
+
+	// Skip updates that don't change anything after rounding, unless they
+	// carry events
+	if dedupEnabled {
+		key := game.displayKey()
+		if key == game.lastKey && len(events) == 0 {
+			atomic.AddInt64(&metrics.dedupedPublishes, 1)
+			return gameUpdate{}, false
+		}
+		game.lastKey = key
+	}
+
+	// Changes outside PUBLISH_TRIGGERS don't publish the state, though any
+	// events still go out on the events channel
+	if !game.triggered() {
+		if len(events) == 0 {
+			return gameUpdate{}, false
+		}
+		return gameUpdate{channel: gameChannel(&game.GameState), events: events}, true
+	}
+
+	// Publish full game state (Socket.IO server will calculate deltas)
+	data, ok := game.encodeState("game state for " + game.ID)
+	if !ok {
+		return gameUpdate{}, false
+	}
+	game.notePublished()
+
+	return gameUpdate{channel: gameChannel(&game.GameState), data: data, state: game.clone(), events: events, seq: game.seq, computed: time.Now()}, true
+}
+
+// forcedUpdate encodes a state transition that must reach clients regardless
+// of the update gate and dedup
+func forcedUpdate(game *Game, what string, events ...MatchEvent) (gameUpdate, bool) {
+	data, ok := game.encodeState(what)
+	if !ok {
+		return gameUpdate{}, false
+	}
+	game.lastKey = game.displayKey()
+	game.notePublished()
+	return gameUpdate{channel: gameChannel(&game.GameState), data: data, state: game.clone(), events: events, seq: game.seq, computed: time.Now()}, true
+}
+
+// Startup seeding: seedRounds updates per game, seedPause apart
+const (
+	seedRounds = 10
+	seedPause  = 500 * time.Millisecond
+)
+
+// publishInitialDummyData seeds clients with a burst of updates. It updates
+// each game through the registry exactly like advanceGame, so it is safe to
+// run alongside the publish loop.
+//
+// Each of rounds publishes every game once through pub, pausing between
+// rounds, until shutdown; it returns how many updates were published.
+func publishInitialDummyData(pub Publisher, rounds int, pause time.Duration) int {
+	log.Println("Publishing initial dummy data...")
+
+	// Publish a burst of updates immediately so frontend sees data right away
+	published := 0
+	for i := 0; i < rounds; i++ {
+		for _, game := range registry.Games() {
+			if !publishFilter.Allows(game.ID) {
+				continue
+			}
+
+			var data []byte
+			var channel string
+			var state GameState
+			var seq uint64
+			ok := false
+			registry.UpdateGame(game, func(game *Game) {
+				if game.Status != statusLive {
+					return
+				}
+
+				// Make some visible changes
+				now := game.now()
+				for _, market := range marketNames {
+					game.setOdds(market, game.Markets[market].Odds+float64(i)*0.1, now)
+				}
+				game.LastUpdated = now
+
+				// Publish full game state
+				data, ok = game.encodeState("dummy data for " + game.ID)
+				channel = gameChannel(&game.GameState)
+				state, seq = game.clone(), game.seq
+			})
+			if !ok {
+				continue
+			}
+			recordState(state, seq)
+
+			// Counted like a queued state, so /metrics includes the seed
+			m := outbound{kind: kindState, gameID: game.ID, channel: channel, data: data, seq: seq, computed: time.Now()}
+			if err := publishStateTo(pub, channel, game.ID, seq, data); err != nil {
+				log.Printf("Error publishing dummy data: %v", err)
+				observePublish(game.ID, true)
+			} else {
+				delivered(m)
+				writeGameHash(game.ID, stateHash(state))
+				published++
+				log.Printf("Published dummy update #%d for %s", i+1, game.ID)
+			}
+		}
+		select {
+		case <-time.After(pause):
+		case <-stopFeed:
+			log.Printf("Stopped seeding dummy data for shutdown (%d updates)", published)
+			return published
+		}
+	}
+
+	log.Printf("✅ Dummy data published successfully! (%d updates)", published)
+	return published
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPublishInitialDummyDataCount(t *testing.T) {
+	useGames(t)
+	pub := &fakePublisher{}
+
+	if n := publishInitialDummyData(pub, seedRounds, 0); n != 30 {
+		t.Errorf("publishInitialDummyData returned %d, want 30", n)
+	}
+
+	perGame := map[string]int{}
+	for _, msg := range pub.messages() {
+		var env struct {
+			Type string    `json:"type"`
+			Data GameState `json:"data"`
+		}
+		if err := json.Unmarshal(msg.Payload, &env); err != nil {
+			t.Fatalf("payload on %s is not valid JSON: %v", msg.Channel, err)
+		}
+		if env.Type != typeState {
+			t.Errorf("payload on %s has type %q, want %q", msg.Channel, env.Type, typeState)
+		}
+		perGame[env.Data.ID]++
+	}
+	for _, id := range []string{"game1", "game2", "game3"} {
+		if perGame[id] != 10 {
+			t.Errorf("%s got %d publishes, want 10", id, perGame[id])
+		}
+	}
+	if len(perGame) != 3 {
+		t.Errorf("published games %v, want exactly game1-3", perGame)
+	}
+}
+
+func TestSafeTickRecoversFromPanic(t *testing.T) {
+	before := atomic.LoadInt64(&metrics.panics)
+
+	safeTick(func() { panic("bad tick") })
+	ran := false
+	safeTick(func() { ran = true })
+
+	if got := atomic.LoadInt64(&metrics.panics) - before; got != 1 {
+		t.Errorf("panics grew by %d, want 1", got)
+	}
+	if !ran {
+		t.Error("the tick after a panic didn't run")
+	}
+}
+
+// hookPublisher is a fakePublisher that runs onPublish before each publish
+type hookPublisher struct {
+	fakePublisher
+	onPublish func(channel string)
+}
+
+func (p *hookPublisher) Publish(ctx context.Context, channel string, payload []byte) error {
+	p.onPublish(channel)
+	return p.fakePublisher.Publish(ctx, channel, payload)
+}
+
+func TestSeedingSkipsGamesReplacedMidRound(t *testing.T) {
+	useGames(t)
+	replaced := false
+	pub := &hookPublisher{}
+	pub.onPublish = func(string) {
+		// game2 is deleted and recreated while round one is still on game1
+		if replaced {
+			return
+		}
+		replaced = true
+		state, _ := registry.Get("game2")
+		registry.Remove("game2", func(*Game) {})
+		registry.Add(&state)
+	}
+
+	publishInitialDummyData(pub, seedRounds, 0)
+
+	perGame := map[string]int{}
+	for _, msg := range pub.messages() {
+		var env struct {
+			Data GameState `json:"data"`
+		}
+		json.Unmarshal(msg.Payload, &env)
+		perGame[env.Data.ID]++
+	}
+	if perGame["game1"] != 10 || perGame["game3"] != 10 {
+		t.Errorf("game1 and game3 got %d and %d publishes, want 10 each", perGame["game1"], perGame["game3"])
+	}
+	// The replaced game is skipped in round one, its replacement gets the rest
+	if perGame["game2"] != 9 {
+		t.Errorf("game2 got %d publishes, want 9", perGame["game2"])
+	}
+}
+
+func TestPublishTickPublishesInIDOrder(t *testing.T) {
+	var states []GameState
+	for _, i := range []int{7, 3, 9, 1, 5, 8, 2, 6, 4} {
+		state := defaultGames()[0]
+		state.ID = fmt.Sprintf("game%d", i)
+		state.HomeTeam = fmt.Sprintf("Home %d", i)
+		states = append(states, state)
+	}
+	useGames(t, states...)
+	queue := captureQueue(t)
+	previous := gameUpdateProb
+	gameUpdateProb = 1
+	t.Cleanup(func() { gameUpdateProb = previous })
+
+	for tick := 0; tick < 3; tick++ {
+		publishTick()
+		var order []string
+		for _, m := range queue.drain() {
+			if m.kind == kindState {
+				order = append(order, m.gameID)
+			}
+		}
+		if len(order) != len(states) || !sort.StringsAreSorted(order) {
+			t.Errorf("tick %d published %v, want all %d games in ID order", tick, order, len(states))
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Backpressure policies for a full publish queue
+const (
+	policyBlock      = "block"
+	policyDropOldest = "drop_oldest"
+	policyDropNewest = "drop_newest"
+)
+
+type messageKind int
+
+const (
+	kindState messageKind = iota
+	kindEvent
+	kindLeague
+	kindTier
+	kindAggregate
+	kindFinal
+	kindStale
+	kindDelayed
+)
+
+// outbound is one encoded message waiting to be published
+type outbound struct {
+	kind     messageKind
+	gameID   string
+	channel  string
+	data     []byte
+	hash     map[string]interface{} // fields for the game's state hash, with WRITE_HASH
+	seq      uint64                 // the game state's sequence number, for STATE_STREAM
+	computed time.Time              // when the payload was built, for producer latency
+}
+
+// PublishQueue decouples the tick loop from Redis: the loop enqueues and
+// workers publish. When the workers can't keep up, the policy decides whether
+// the loop waits or updates are dropped.
+//
+// Messages are sharded by game ID, one queue per worker, so every message for
+// a game goes through the same worker and is published in the order it was
+// enqueued; only different games are published in parallel. Messages without
+// a game (leagues, aggregates) share a shard and stay ordered among themselves.
+type PublishQueue struct {
+	shards []chan outbound
+	policy string
+	wg     sync.WaitGroup // running workers
+}
+
+var publishQueue *PublishQueue
+
+func newPublishQueue(size int, policy string, workers int) (*PublishQueue, error) {
+	switch policy {
+	case policyBlock, policyDropOldest, policyDropNewest:
+	default:
+		return nil, fmt.Errorf("unknown policy %q (expected block, drop_oldest or drop_newest)", policy)
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("queue size must be positive, got %d", size)
+	}
+	if workers <= 0 {
+		return nil, fmt.Errorf("worker count must be positive, got %d", workers)
+	}
+	q := &PublishQueue{shards: make([]chan outbound, workers), policy: policy}
+	for i := range q.shards {
+		q.shards[i] = make(chan outbound, size)
+	}
+	return q, nil
+}
+
+// shard is the queue a game's messages always go through
+func (q *PublishQueue) shard(gameID string) chan outbound {
+	if len(q.shards) == 1 {
+		return q.shards[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(gameID))
+	return q.shards[h.Sum32()%uint32(len(q.shards))]
+}
+
+// Enqueue hands a message to its game's worker, applying the backpressure
+// policy when that worker's queue is full. Only the block policy ever waits.
+func (q *PublishQueue) Enqueue(m outbound) {
+	ch := q.shard(m.gameID)
+	switch q.policy {
+	case policyBlock:
+		ch <- m
+	case policyDropNewest:
+		select {
+		case ch <- m:
+		default:
+			atomic.AddInt64(&metrics.droppedUpdates, 1)
+		}
+	case policyDropOldest:
+		for {
+			select {
+			case ch <- m:
+				return
+			default:
+			}
+			select {
+			case <-ch:
+				atomic.AddInt64(&metrics.droppedUpdates, 1)
+			default:
+			}
+		}
+	}
+}
+
+// Depth is the number of messages waiting to be published, across workers
+func (q *PublishQueue) Depth() int {
+	depth := 0
+	for _, ch := range q.shards {
+		depth += len(ch)
+	}
+	return depth
+}
+
+// run starts one worker per shard, each publishing its messages in order
+// until the queue is closed
+func (q *PublishQueue) run() {
+	for _, ch := range q.shards {
+		q.wg.Add(1)
+		go func(ch chan outbound) {
+			defer q.wg.Done()
+			for m := range ch {
+				if pipelineSize > 1 {
+					deliverBatch(collectBatch(ch, m))
+				} else {
+					deliver(m)
+				}
+			}
+		}(ch)
+	}
+}
+
+// Close stops accepting messages and waits for the workers to publish what's
+// left. Nothing may be enqueued afterwards.
+func (q *PublishQueue) Close() {
+	for _, ch := range q.shards {
+		close(ch)
+	}
+	q.wg.Wait()
+	log.Println("Publish queue drained")
+}
+
+// kindNames describe each kind of message in logs and dead letters
+var kindNames = map[messageKind]string{
+	kindState:     "game state",
+	kindFinal:     "final state",
+	kindEvent:     "match event",
+	kindLeague:    "league snapshot",
+	kindTier:      "tier update",
+	kindAggregate: "aggregate snapshot",
+	kindStale:     "stale state",
+	kindDelayed:   "delayed state",
+}
+
+func (k messageKind) String() string {
+	return kindNames[k]
+}
+
+// deliver publishes one message, retrying failures, and accounts for it in
+// the metrics
+func deliver(m outbound) {
+	// Nothing is sent while a full Redis cools down
+	if inOOMBackoff() {
+		atomic.AddInt64(&metrics.droppedUpdates, 1)
+		return
+	}
+
+	if err := withRetries(func() error { return send(m) }); err != nil {
+		undelivered(m, err)
+		return
+	}
+	delivered(m)
+}
+
+// undelivered accounts for a message that failed every attempt. One cut short
+// by shutdown is only counted as dropped, it says nothing about Redis.
+func undelivered(m outbound, err error) {
+	if isCanceled(err) {
+		atomic.AddInt64(&metrics.droppedUpdates, 1)
+		return
+	}
+	if m.kind == kindState || m.kind == kindFinal {
+		observePublish(m.gameID, true)
+	}
+	publishFailed(err, m.kind.String())
+	deadLetter(m, err)
+	notePublishResult(true)
+}
+
+// delivered accounts for a message that was published
+func delivered(m outbound) {
+	notePublishResult(false)
+
+	switch m.kind {
+	case kindState:
+		producerLatency.Observe(time.Since(m.computed))
+		atomic.AddInt64(&metrics.deltasPublished, 1)
+		observePublish(m.gameID, false)
+	case kindFinal:
+		producerLatency.Observe(time.Since(m.computed))
+		atomic.AddInt64(&metrics.finalPublishes, 1)
+		observePublish(m.gameID, false)
+	case kindEvent:
+		atomic.AddInt64(&metrics.eventsPublished, 1)
+	case kindStale:
+		atomic.AddInt64(&metrics.staleInjected, 1)
+	case kindDelayed:
+		atomic.AddInt64(&metrics.delayedPublishes, 1)
+	}
+	countChannelPublish(m.channel)
+	countEncoded(m.kind, m.data)
+}
+
+// send makes one publish attempt; game states also refresh the latest cache,
+// state hash and state stream
+func send(m outbound) error {
+	switch m.kind {
+	case kindState, kindFinal:
+		if err := publishState(m.channel, m.gameID, m.seq, m.data); err != nil {
+			return err
+		}
+		writeGameHash(m.gameID, m.hash)
+		return nil
+	default:
+		return timedPublish(m.channel, m.data)
+	}
+}
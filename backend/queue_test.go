@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestQueuePoliciesWhenFull(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   []uint64 // seqs left in the queue
+	}{
+		{policyDropNewest, []uint64{1, 2}},
+		{policyDropOldest, []uint64{4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			queue, err := newPublishQueue(2, tt.policy, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := atomic.LoadInt64(&metrics.droppedUpdates)
+			for seq := uint64(1); seq <= 5; seq++ {
+				queue.Enqueue(outbound{kind: kindState, gameID: "game1", seq: seq})
+			}
+
+			var got []uint64
+			for _, m := range queue.drain() {
+				got = append(got, m.seq)
+			}
+			if len(got) != len(tt.want) || got[0] != tt.want[0] || got[1] != tt.want[1] {
+				t.Errorf("queued seqs = %v, want %v", got, tt.want)
+			}
+			if dropped := atomic.LoadInt64(&metrics.droppedUpdates) - before; dropped != 3 {
+				t.Errorf("droppedUpdates grew by %d, want 3", dropped)
+			}
+		})
+	}
+}
+
+func TestNewPublishQueueRejectsBadSettings(t *testing.T) {
+	for _, tt := range []struct {
+		size    int
+		policy  string
+		workers int
+	}{
+		{8, "drop_random", 1},
+		{0, policyBlock, 1},
+		{8, policyBlock, 0},
+	} {
+		if _, err := newPublishQueue(tt.size, tt.policy, tt.workers); err == nil {
+			t.Errorf("newPublishQueue(%d, %q, %d) = nil error, want one", tt.size, tt.policy, tt.workers)
+		}
+	}
+}
+
+func TestShardedWorkersKeepPerGameOrder(t *testing.T) {
+	pub := &fakePublisher{}
+	previous := publisher
+	publisher = pub
+	t.Cleanup(func() { publisher = previous })
+
+	queue, err := newPublishQueue(64, policyBlock, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := queue.shard("game1"), queue.shard("game1"); a != b {
+		t.Fatal("one game mapped to two shards")
+	}
+	queue.run()
+	for i := 0; i < 100; i++ {
+		for g := 0; g < 8; g++ {
+			id := fmt.Sprintf("game%d", g)
+			queue.Enqueue(outbound{kind: kindEvent, gameID: id, channel: id, data: []byte(strconv.Itoa(i))})
+		}
+	}
+	queue.Close()
+
+	next := map[string]int{}
+	for _, msg := range pub.messages() {
+		if got := string(msg.Payload); got != strconv.Itoa(next[msg.Channel]) {
+			t.Fatalf("%s published %s after %d, want in enqueue order", msg.Channel, got, next[msg.Channel]-1)
+		}
+		next[msg.Channel]++
+	}
+	for g := 0; g < 8; g++ {
+		if id := fmt.Sprintf("game%d", g); next[id] != 100 {
+			t.Errorf("%s published %d messages, want 100", id, next[id])
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// maxRedisDB is the highest logical DB a stock Redis server has (databases 16)
+const maxRedisDB = 15
+
+// dbRoutes maps game groups to Redis logical DBs (REDIS_DB_ROUTES), e.g.
+// "league:la-liga=1,tier:premium=2". A game's league route wins over its
+// tier route; unrouted games stay on the default DB.
+type dbRoutes struct {
+	leagues map[string]int
+	tiers   map[string]int
+}
+
+func parseDBRoutes(items []string) (dbRoutes, error) {
+	routes := dbRoutes{leagues: map[string]int{}, tiers: map[string]int{}}
+	for _, item := range items {
+		group, rawDB, ok := strings.Cut(item, "=")
+		kind, name, okGroup := strings.Cut(strings.TrimSpace(group), ":")
+		if !ok || !okGroup || name == "" {
+			return dbRoutes{}, fmt.Errorf("%q: expected league:<name>=<db> or tier:<name>=<db>", item)
+		}
+		db, err := strconv.Atoi(strings.TrimSpace(rawDB))
+		if err != nil || db < 0 || db > maxRedisDB {
+			return dbRoutes{}, fmt.Errorf("%q: db must be an integer between 0 and %d", item, maxRedisDB)
+		}
+		switch kind {
+		case "league":
+			routes.leagues[name] = db
+		case "tier":
+			routes.tiers[name] = db
+		default:
+			return dbRoutes{}, fmt.Errorf("%q: unknown group %q (expected league or tier)", item, kind)
+		}
+	}
+	return routes, nil
+}
+
+// dbFor returns the DB a game, league or tier publishes to
+func (r dbRoutes) dbFor(league, tier string) (int, bool) {
+	if db, ok := r.leagues[league]; ok && league != "" {
+		return db, true
+	}
+	if db, ok := r.tiers[tier]; ok && tier != "" {
+		return db, true
+	}
+	return 0, false
+}
+
+// routedPublisher keeps each game's keyed data (<id>:latest, game:<id> and
+// <id>:stream) on the logical DB its group routes to, following dbRoutes.
+// Pub/Sub channels are server-wide in Redis, whatever DB a client selected,
+// so channels aren't isolated: every publish and subscription goes through
+// the default DB's client.
+type routedPublisher struct {
+	routes   dbRoutes
+	fallback *redisPublisher
+	byDB     map[int]*redisPublisher
+}
+
+// newRoutedPublisher opens a client per routed DB, cloned from the default
+// single-node client's options
+func newRoutedPublisher(base *redis.Client, routes dbRoutes) *routedPublisher {
+	fallback := &redisPublisher{client: base}
+	p := &routedPublisher{
+		routes:   routes,
+		fallback: fallback,
+		byDB:     map[int]*redisPublisher{base.Options().DB: fallback},
+	}
+	for _, group := range []map[string]int{routes.leagues, routes.tiers} {
+		for _, db := range group {
+			if _, ok := p.byDB[db]; ok {
+				continue
+			}
+			opts := *base.Options()
+			opts.DB = db
+			p.byDB[db] = &redisPublisher{client: redis.NewClient(&opts)}
+		}
+	}
+	return p
+}
+
+func (p *routedPublisher) route(league, tier string) *redisPublisher {
+	if db, ok := p.routes.dbFor(league, tier); ok {
+		return p.byDB[db]
+	}
+	return p.fallback
+}
+
+// forGame routes by a game's current league and tier, so a reload that
+// moves a game moves its keyed data with it
+func (p *routedPublisher) forGame(gameID string) *redisPublisher {
+	var league, tier string
+	registry.View(gameID, func(game *Game) { league, tier = game.League, game.Tier })
+	return p.route(league, tier)
+}
+
+func (p *routedPublisher) Publish(ctx context.Context, channel string, payload []byte) error {
+	return p.fallback.Publish(ctx, channel, payload)
+}
+
+// Ping checks every DB's client; a DB index the server doesn't have fails here
+func (p *routedPublisher) Ping(ctx context.Context) error {
+	for db, target := range p.byDB {
+		if err := target.Ping(ctx); err != nil {
+			return fmt.Errorf("db %d: %w", db, err)
+		}
+	}
+	return nil
+}
+
+func (p *routedPublisher) SetLatest(ctx context.Context, gameID string, data []byte) error {
+	return p.forGame(gameID).SetLatest(ctx, gameID, data)
+}
+
+func (p *routedPublisher) GetLatest(ctx context.Context, gameID string) ([]byte, error) {
+	return p.forGame(gameID).GetLatest(ctx, gameID)
+}
+
+func (p *routedPublisher) Subscribe(ctx context.Context, channels []string) (<-chan brokerMessage, func()) {
+	return p.fallback.Subscribe(ctx, channels)
+}
+
+func (p *routedPublisher) Open(ctx context.Context) Subscription {
+	return p.fallback.Open(ctx)
+}
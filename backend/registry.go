@@ -0,0 +1,185 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+// GameRegistry holds the running games. The publisher and the admin
+// endpoints both mutate games in place, so a game's fields are only touched
+// inside the registry's methods, under its lock. The *Game pointers Games
+// hands out identify a game; their fields go back through UpdateGame.
+type GameRegistry struct {
+	mu    sync.RWMutex
+	games map[string]*Game
+}
+
+// registry is every game the backend runs, publishing or not
+var registry = newGameRegistry()
+
+// gamesChanged is signalled after games are added or removed, for /ws
+// connections that follow every game
+var gamesChanged = make(chan struct{}, 1)
+
+func notifyGamesChanged() {
+	select {
+	case gamesChanged <- struct{}{}:
+	default:
+	}
+}
+
+func newGameRegistry() *GameRegistry {
+	return &GameRegistry{games: map[string]*Game{}}
+}
+
+// Add registers a new game built from state, false when the ID is taken
+func (r *GameRegistry) Add(state *GameState) bool {
+	defer notifyGamesChanged()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.games[state.ID]; exists {
+		return false
+	}
+	r.games[state.ID] = newGame(*state)
+	return true
+}
+
+// Get returns a copy of a game's state
+func (r *GameRegistry) Get(id string) (GameState, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	game, ok := r.games[id]
+	if !ok {
+		return GameState{}, false
+	}
+	return game.clone(), true
+}
+
+// Has reports whether a game is registered
+func (r *GameRegistry) Has(id string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.games[id]
+	return ok
+}
+
+// Len is the number of registered games
+func (r *GameRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.games)
+}
+
+// Snapshot copies every game's state, sorted by ID
+func (r *GameRegistry) Snapshot() []GameState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	states := make([]GameState, 0, len(r.games))
+	for _, game := range r.games {
+		states = append(states, game.clone())
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].ID < states[j].ID })
+	return states
+}
+
+// Games returns the current games sorted by ID, so every tick publishes in
+// the same order. Games added or removed afterwards don't change the list.
+func (r *GameRegistry) Games() []*Game {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]*Game, 0, len(r.games))
+	for _, game := range r.games {
+		list = append(list, game)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// View runs fn on a game under the read lock, false when it isn't registered
+func (r *GameRegistry) View(id string, fn func(*Game)) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	game, ok := r.games[id]
+	if ok {
+		fn(game)
+	}
+	return ok
+}
+
+// Update runs fn on a game under the write lock, false when it isn't
+// registered
+func (r *GameRegistry) Update(id string, fn func(*Game)) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	game, ok := r.games[id]
+	if ok {
+		fn(game)
+	}
+	return ok
+}
+
+// UpdateGame is Update for a game from Games, false once it has been deleted
+// or replaced by a new game with the same ID
+func (r *GameRegistry) UpdateGame(game *Game, fn func(*Game)) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.games[game.ID] != game {
+		return false
+	}
+	fn(game)
+	return true
+}
+
+// Remove deletes a game, after running fn on it under the same lock (e.g.
+// for its final update); false when it isn't registered
+func (r *GameRegistry) Remove(id string, fn func(*Game)) bool {
+	defer notifyGamesChanged()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	game, ok := r.games[id]
+	if !ok {
+		return false
+	}
+	fn(game)
+	delete(r.games, id)
+	return true
+}
+
+// RemoveGame deletes a game from Games if it's still registered and when
+// reports true for it
+func (r *GameRegistry) RemoveGame(game *Game, when func(*Game) bool) bool {
+	defer notifyGamesChanged()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.games[game.ID] != game || !when(game) {
+		return false
+	}
+	delete(r.games, game.ID)
+	return true
+}
+
+// Read runs fn with every game under the read lock, for lookups across games
+func (r *GameRegistry) Read(fn func(games map[string]*Game)) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn(r.games)
+}
+
+// Batch runs fn with every game under the write lock, for changes across
+// games that must apply together, like bulk creates and reloads. The
+// simulation knobs are also changed under it.
+func (r *GameRegistry) Batch(fn func(games map[string]*Game)) {
+	defer notifyGamesChanged()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(r.games)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestGameRegistryConcurrentAccess(t *testing.T) {
+	r := newGameRegistry()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			state := GameState{ID: fmt.Sprintf("game%d", i), HomeTeam: "Home", AwayTeam: "Away", HomeOdds: 2, AwayOdds: 3, DrawOdds: 3}
+			state.applyDefaults()
+			state.initMarkets()
+			if !r.Add(&state) {
+				t.Errorf("Add(%s) = false, want true", state.ID)
+			}
+			for j := 0; j < 100; j++ {
+				r.Update(state.ID, func(game *Game) { game.HomeScore++ })
+				r.Snapshot()
+				r.Get(state.ID)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	states := r.Snapshot()
+	if len(states) != 8 {
+		t.Fatalf("Snapshot() has %d games, want 8", len(states))
+	}
+	for i, state := range states {
+		if want := fmt.Sprintf("game%d", i); state.ID != want {
+			t.Errorf("Snapshot()[%d].ID = %s, want %s", i, state.ID, want)
+		}
+		if state.HomeScore != 100 {
+			t.Errorf("%s HomeScore = %d, want 100", state.ID, state.HomeScore)
+		}
+	}
+}
+
+func TestGameRegistryUpdateGameSkipsReplacedGames(t *testing.T) {
+	r := newGameRegistry()
+	state := GameState{ID: "game1", HomeTeam: "Home", AwayTeam: "Away"}
+	r.Add(&state)
+	old := r.Games()[0]
+
+	if r.Add(&state) {
+		t.Fatal("Add of a taken ID = true, want false")
+	}
+	r.Remove("game1", func(*Game) {})
+	r.Add(&state)
+
+	if r.UpdateGame(old, func(game *Game) { game.HomeScore = 9 }) {
+		t.Error("UpdateGame of a replaced game = true, want false")
+	}
+	if got, _ := r.Get("game1"); got.HomeScore != 0 {
+		t.Errorf("replacement HomeScore = %d, want 0", got.HomeScore)
+	}
+}
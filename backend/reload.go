@@ -0,0 +1,151 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// stopReload is closed to stop watching for SIGHUP; reloadStopped is closed
+// once the watcher has returned, so no reload publishes after the queue closes
+var (
+	stopReload    = make(chan struct{})
+	reloadStopped = make(chan struct{})
+)
+
+// watchReload re-reads the games config and the tunables on every SIGHUP,
+// until stopReload is closed
+func watchReload(path string) {
+	defer close(reloadStopped)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-hup:
+		case <-stopReload:
+			return
+		}
+
+		if path == "" && tunablesFile == "" {
+			log.Println("⚠️  SIGHUP ignored: neither GAMES_CONFIG nor TUNABLES_FILE is set")
+			continue
+		}
+		if path != "" {
+			reloadGames(path)
+		}
+		if tunablesFile != "" {
+			if err := reloadTunables(tunablesFile); err != nil {
+				log.Printf("⚠️  Tunables reload rejected, keeping current values: %v", err)
+			}
+		}
+	}
+}
+
+// reloadGames applies the games config at path to the running games: new
+// games are added, missing ones removed, and existing ones keep their live
+// score, clock and odds while taking the new metadata. Removed games publish
+// their final state, the same as a DELETE, and an added game repeating a
+// running matchup is rejected under STRICT_CONFIG. An invalid config, or
+// under STRICT_CONFIG one with implausible books, is rejected as a whole and
+// the current games are kept.
+func reloadGames(path string) {
+	states, err := loadGamesConfig(path)
+	if err != nil {
+		log.Printf("⚠️  Reload rejected, keeping current games: %v", err)
+		return
+	}
+	if problems := checkBooks(states, bookMin, bookMax); len(problems) > 0 {
+		for _, problem := range problems {
+			log.Printf("⚠️  WARN: implausible book for %s", problem)
+		}
+		if strictConfig {
+			log.Printf("⚠️  Reload rejected, keeping current games: STRICT_CONFIG: %d games with implausible books", len(problems))
+			return
+		}
+	}
+
+	wanted := make(map[string]GameState, len(states))
+	for _, state := range states {
+		state.applyDefaults()
+		wanted[state.ID] = state
+	}
+
+	var added, removed, updated, rejected []string
+	var finals []gameUpdate
+	now := time.Now().UnixMilli()
+
+	registry.Batch(func(games map[string]*Game) {
+		for id, game := range games {
+			if _, ok := wanted[id]; !ok {
+				if update, ok := finalUpdate(game); ok {
+					finals = append(finals, update)
+				}
+				delete(games, id)
+				removed = append(removed, id)
+			}
+		}
+
+		// Additions go in ID order, so which of two repeats is rejected
+		// doesn't depend on map order
+		ids := make([]string, 0, len(wanted))
+		for id := range wanted {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			state := wanted[id]
+			game, ok := games[id]
+			if !ok {
+				if other, ok := existingMatchup(games, &state); ok {
+					if strictConfig {
+						log.Printf("⚠️  Reload skipped %s: same matchup as %s (%s v %s)", id, other, state.HomeTeam, state.AwayTeam)
+						rejected = append(rejected, id)
+						continue
+					}
+					log.Printf("⚠️  WARN: duplicate matchup for %s: same as %s (%s v %s)", id, other, state.HomeTeam, state.AwayTeam)
+				}
+				state.LastUpdated = now
+				state.initMarkets()
+				games[id] = newGame(state)
+				added = append(added, id)
+				continue
+			}
+
+			if game.HomeTeam != state.HomeTeam || game.AwayTeam != state.AwayTeam || game.League != state.League ||
+				game.Tier != state.Tier || game.Featured != state.Featured || game.Sport != state.Sport ||
+				game.Importance != state.Importance || !slices.Equal(game.Tags, state.Tags) {
+				game.HomeTeam, game.AwayTeam = state.HomeTeam, state.AwayTeam
+				game.League, game.Tier = state.League, state.Tier
+				game.Featured, game.Sport = state.Featured, state.Sport
+				game.Importance = state.Importance
+				game.Tags = state.Tags
+				updated = append(updated, id)
+			}
+
+			// Games not yet kicked off follow schedule changes
+			if game.Status == statusScheduled && state.KickoffTime != game.KickoffTime && state.KickoffTime > 0 {
+				game.KickoffTime = state.KickoffTime
+				updated = append(updated, id)
+			}
+		}
+	})
+
+	for _, update := range finals {
+		publishFinal(update.state.ID, update)
+	}
+
+	sort.Strings(removed)
+	sort.Strings(updated)
+	if len(rejected) > 0 {
+		log.Printf("✅ Reloaded %s: added %v, removed %v, updated %v, rejected %v", path, added, removed, updated, rejected)
+		return
+	}
+	log.Printf("✅ Reloaded %s: added %v, removed %v, updated %v", path, added, removed, updated)
+}
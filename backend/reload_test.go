@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeGamesConfig writes states as a GAMES_CONFIG file
+func writeGamesConfig(t *testing.T, states []GameState) string {
+	t.Helper()
+	data, err := json.Marshal(states)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "games.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReloadPublishesFinalsForRemovedGames(t *testing.T) {
+	useGames(t)
+	queue := captureQueue(t)
+
+	reloadGames(writeGamesConfig(t, defaultGames()[:2]))
+
+	if registry.Has("game3") {
+		t.Fatal("game3 still registered after a reload without it")
+	}
+	var finals []outbound
+	for _, m := range queue.drain() {
+		if m.kind == kindFinal {
+			finals = append(finals, m)
+		}
+	}
+	if len(finals) != 1 || finals[0].gameID != "game3" {
+		t.Fatalf("final publishes %v, want one for game3", finals)
+	}
+	var env struct {
+		Data GameState `json:"data"`
+	}
+	if err := json.Unmarshal(finals[0].data, &env); err != nil || env.Data.Status != statusEnded {
+		t.Errorf("game3's final state has status %q (%v), want %s", env.Data.Status, err, statusEnded)
+	}
+}
+
+func TestReloadRejectsRepeatedMatchupUnderStrictConfig(t *testing.T) {
+	useGames(t)
+	captureQueue(t)
+	strictConfig = true
+	defer func() { strictConfig = false }()
+
+	repeat := defaultGames()[0]
+	repeat.ID = "game9"
+	reloadGames(writeGamesConfig(t, append(defaultGames(), repeat)))
+
+	if registry.Has("game9") {
+		t.Error("game9 added though it repeats game1's matchup")
+	}
+	if registry.Len() != 3 {
+		t.Errorf("%d games after the reload, want the 3 running ones", registry.Len())
+	}
+}
+
+func TestReloadMovesKeyedDataOfMovedGames(t *testing.T) {
+	useGames(t)
+	captureQueue(t)
+	server, pub := routedBroker(t)
+
+	states := defaultGames()
+	states[0].League = "la-liga"
+	reloadGames(writeGamesConfig(t, states))
+
+	if err := pub.SetLatest(context.Background(), "game1", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	server.Select(1)
+	if !server.Exists(latestKey("game1")) {
+		t.Error("game1's latest state not on DB 1 after moving to la-liga")
+	}
+}
+
+func TestReloadRejectsImplausibleBooksUnderStrictConfig(t *testing.T) {
+	useGames(t)
+	captureQueue(t)
+	strictConfig = true
+	defer func() { strictConfig = false }()
+
+	arbitrage := defaultGames()[0]
+	arbitrage.ID, arbitrage.HomeTeam, arbitrage.AwayTeam = "game9", "Ajax", "PSV"
+	arbitrage.HomeOdds, arbitrage.AwayOdds, arbitrage.DrawOdds = 5, 5, 5
+	reloadGames(writeGamesConfig(t, append(defaultGames()[:2], arbitrage)))
+
+	if registry.Has("game9") {
+		t.Error("game9 added though its book is implausible")
+	}
+	if !registry.Has("game3") {
+		t.Error("game3 removed by a rejected reload")
+	}
+}
+
+func TestWatchReloadStopsOnShutdown(t *testing.T) {
+	useGames(t)
+	queue := captureQueue(t)
+	previousStop, previousStopped := stopReload, reloadStopped
+	stopReload, reloadStopped = make(chan struct{}), make(chan struct{})
+	defer func() { stopReload, reloadStopped = previousStop, previousStopped }()
+
+	// Keeps a SIGHUP after the watcher stops from killing the test binary
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	go watchReload(writeGamesConfig(t, defaultGames()[:2]))
+	close(stopReload)
+	select {
+	case <-reloadStopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("watchReload still running after stopReload was closed")
+	}
+
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	<-hup
+	if !registry.Has("game3") || len(queue.drain()) != 0 {
+		t.Error("a SIGHUP after shutdown still reloaded the games")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import "time"
+
+// Scheduled price reviews suspend every open market for reviewDuration once
+// per reviewInterval (REVIEW_INTERVAL, REVIEW_DURATION); off when either is 0
+var (
+	reviewInterval time.Duration
+	reviewDuration time.Duration
+	reviewEpoch    int64 // unix millis the review schedule counts from
+)
+
+const (
+	eventMarketsSuspended = "marketsSuspended"
+	eventMarketsResumed   = "marketsResumed"
+)
+
+// inReview reports whether now falls inside a review window. The first window
+// opens one interval after the epoch.
+func inReview(now int64) bool {
+	if reviewInterval <= 0 || reviewDuration <= 0 {
+		return false
+	}
+	elapsed := now - reviewEpoch
+	if elapsed < reviewInterval.Milliseconds() {
+		return false
+	}
+	return elapsed%reviewInterval.Milliseconds() < reviewDuration.Milliseconds()
+}
+
+// applyReview suspends or resumes a game's markets as a review window opens
+// or closes, returning the transition event. Only markets the review itself
+// suspended are resumed, so manual suspensions survive it.
+func applyReview(game *Game, now int64) (MatchEvent, bool) {
+	want := inReview(now)
+	if want == (game.reviewed != nil) {
+		return MatchEvent{}, false
+	}
+
+	event := MatchEvent{
+		GameID:    game.ID,
+		HomeScore: game.HomeScore,
+		AwayScore: game.AwayScore,
+		Minute:    game.Minute,
+		Timestamp: now,
+	}
+
+	if want {
+		event.Type = eventMarketsSuspended
+		game.reviewed = []string{}
+		for _, name := range marketNames {
+			if m, ok := game.Markets[name]; ok && m.Status == marketOpen {
+				m.Status = marketSuspended
+				m.LastUpdated = now
+				game.reviewed = append(game.reviewed, name)
+			}
+		}
+	} else {
+		event.Type = eventMarketsResumed
+		for _, name := range game.reviewed {
+			if m, ok := game.Markets[name]; ok && m.Status == marketSuspended {
+				m.Status = marketOpen
+				m.LastUpdated = now
+			}
+		}
+		game.reviewed = nil
+	}
+
+	game.LastUpdated = now
+	return event, true
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// With autoRotate (AUTO_ROTATE) the feed never runs dry: whenever fewer than
+// rotateTarget games (AUTO_ROTATE_GAMES, default the startup count) are still
+// to finish, fresh synthetic games kick off to make up the difference.
+var (
+	autoRotate   bool
+	rotateTarget int
+	rotatePack   LocalePack
+	rotateRng    *rand.Rand // guarded by the registry lock
+	rotateNext   = 1        // next candidate game number, guarded by the registry lock
+)
+
+// rotateGames tops the unfinished games back up to rotateTarget
+func rotateGames() {
+	if !autoRotate {
+		return
+	}
+
+	var added []string
+	registry.Batch(func(games map[string]*Game) {
+		running := 0
+		for _, game := range games {
+			if game.Status != statusEnded {
+				running++
+			}
+		}
+		for ; running < rotateTarget; running++ {
+			id := nextRotatedID(games)
+			state := syntheticGame(id, rotatePack, rotateRng)
+			state.LastUpdated = time.Now().UnixMilli()
+			state.applyDefaults()
+			state.initMarkets()
+			games[id] = newGame(state)
+			added = append(added, id)
+		}
+	})
+
+	for _, id := range added {
+		atomic.AddInt64(&metrics.gamesRotated, 1)
+		log.Printf("🔄 Rotated in %s", id)
+	}
+}
+
+// nextRotatedID is the first gameN ID not among games. Call under the
+// registry lock.
+func nextRotatedID(games map[string]*Game) string {
+	for {
+		id := fmt.Sprintf("game%d", rotateNext)
+		rotateNext++
+		if _, taken := games[id]; !taken {
+			return id
+		}
+	}
+}
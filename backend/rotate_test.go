@@ -0,0 +1,29 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestRotateTopsUpFinishedGames(t *testing.T) {
+	useGames(t)
+	pack, err := loadLocale(defaultLocale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	autoRotate, rotateTarget, rotatePack, rotateRng, rotateNext = true, 3, pack, rand.New(rand.NewSource(1)), 1
+	defer func() { autoRotate, rotateTarget, rotateRng, rotateNext = false, 0, nil, 1 }()
+
+	rotateGames()
+	if n := registry.Len(); n != 3 {
+		t.Fatalf("rotating with 3 running games left %d, want 3", n)
+	}
+
+	registry.Update("game2", func(game *Game) { game.Status = statusEnded })
+	rotateGames()
+	// game1-3 are taken, so the replacement is the next free ID
+	state, ok := registry.Get("game4")
+	if !ok || state.Status != statusLive || registry.Len() != 4 {
+		t.Errorf("after game2 ended: game4 found=%v status=%q, %d games; want a live game4 and 4 games", ok, state.Status, registry.Len())
+	}
+}
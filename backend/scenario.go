@@ -0,0 +1,440 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+)
+
+// scenarios script games by ID (SCENARIO_FILE). A scripted game gets its
+// goals, cards and price moves from its timeline instead of at random, can
+// break at half time and settles its markets at full time; its drift runs as
+// usual, tuned by the scenario's odds model. Games without a scenario stay
+// random.
+var scenarios map[string]*Scenario
+
+// Scenario is one game's script: a timeline of events by match minute, a
+// multiplier on its drift volatility (default 1), how its odds react
+// (ScenarioOdds) and the length of its half-time break in match minutes
+// (none by default)
+type Scenario struct {
+	Volatility *float64        `json:"volatility"`
+	Odds       ScenarioOdds    `json:"odds"`
+	HalfTime   float64         `json:"halfTime"`
+	Events     []ScenarioEvent `json:"events"`
+}
+
+// ScenarioEvent is a scripted goal, yellowCard or redCard, e.g.
+// {"minute": 23, "type": "goal", "team": "home", "player": "Saka"}, or an
+// odds step setting prices, e.g. {"minute": 30, "type": "odds", "odds":
+// {"home": 1.8}}
+type ScenarioEvent struct {
+	Minute int                `json:"minute"`
+	Type   string             `json:"type"`
+	Team   string             `json:"team"`
+	Player string             `json:"player"`
+	Odds   map[string]float64 `json:"odds"`
+}
+
+// scenarioOdds is the timeline step that sets prices instead of publishing
+// a match event
+const scenarioOdds = "odds"
+
+// ScenarioOdds is a scripted game's odds model: the shifts a goal and a red
+// card apply, and reversion overriding MEAN_REVERSION for its drift
+type ScenarioOdds struct {
+	Goal      *OddsShift `json:"goal"`
+	RedCard   *OddsShift `json:"redCard"`
+	Reversion *float64   `json:"reversion"`
+}
+
+// OddsShift moves the 1X2 prices after an incident: the favoured side's odds
+// are multiplied by Shorten, the other side's and the draw's by Lengthen
+type OddsShift struct {
+	Shorten  float64 `json:"shorten"`
+	Lengthen float64 `json:"lengthen"`
+}
+
+// The shifts of a goal, for the scorer, and of a red card, for the carded
+// side's opponent, when the scenario doesn't give its own
+var (
+	defaultGoalShift    = OddsShift{Shorten: 0.75, Lengthen: 1.2}
+	defaultRedCardShift = OddsShift{Shorten: 0.9, Lengthen: 1.1}
+)
+
+func (s OddsShift) validate() error {
+	if s.Shorten <= 0 || s.Shorten > 1 || s.Lengthen < 1 {
+		return fmt.Errorf("shorten must be within (0,1] and lengthen at least 1, got %v and %v", s.Shorten, s.Lengthen)
+	}
+	return nil
+}
+
+// loadScenarios reads a JSON object of scenarios keyed by game ID, ordering
+// each timeline by minute. Every ID must be one of games.
+func loadScenarios(path string, games []GameState) (map[string]*Scenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var loaded map[string]*Scenario
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&loaded); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(loaded) == 0 {
+		return nil, fmt.Errorf("%s defines no scenarios", path)
+	}
+
+	known := make(map[string]bool, len(games))
+	for _, game := range games {
+		known[game.ID] = true
+	}
+	for id, s := range loaded {
+		if err := validateScenario(id, s, known); err != nil {
+			return nil, err
+		}
+		sort.SliceStable(s.Events, func(i, j int) bool { return s.Events[i].Minute < s.Events[j].Minute })
+	}
+	return loaded, nil
+}
+
+func validateScenario(id string, s *Scenario, known map[string]bool) error {
+	switch {
+	case !known[id]:
+		return fmt.Errorf("scenario %s: no such game", id)
+	case s == nil:
+		return fmt.Errorf("scenario %s: must be an object", id)
+	case s.Volatility != nil && *s.Volatility < 0:
+		return fmt.Errorf("scenario %s: volatility %v must not be negative", id, *s.Volatility)
+	case s.HalfTime < 0:
+		return fmt.Errorf("scenario %s: halfTime %v must not be negative", id, s.HalfTime)
+	case s.Odds.Reversion != nil && (*s.Odds.Reversion < 0 || *s.Odds.Reversion > 1):
+		return fmt.Errorf("scenario %s: odds.reversion %v must be within [0,1]", id, *s.Odds.Reversion)
+	}
+	for name, shift := range map[string]*OddsShift{"goal": s.Odds.Goal, "redCard": s.Odds.RedCard} {
+		if shift == nil {
+			continue
+		}
+		if err := shift.validate(); err != nil {
+			return fmt.Errorf("scenario %s: odds.%s: %v", id, name, err)
+		}
+	}
+
+	for i, event := range s.Events {
+		if event.Minute < 0 {
+			return fmt.Errorf("scenario %s, event #%d: minute must not be negative", id, i)
+		}
+		if event.Type == scenarioOdds {
+			if len(event.Odds) == 0 {
+				return fmt.Errorf("scenario %s, event #%d: odds must set at least one market", id, i)
+			}
+			for market, odds := range event.Odds {
+				if !isMarket(market) {
+					return fmt.Errorf("scenario %s, event #%d: unknown market %q", id, i, market)
+				}
+				if odds <= oddsFloor {
+					return fmt.Errorf("scenario %s, event #%d: %s odds %v must be above %v", id, i, market, odds, oddsFloor)
+				}
+			}
+			continue
+		}
+		switch {
+		case event.Type != eventGoal && event.Type != eventYellowCard && event.Type != eventRedCard:
+			return fmt.Errorf("scenario %s, event #%d: unknown type %q (expected goal, yellowCard, redCard or odds)", id, i, event.Type)
+		case event.Team != teamHome && event.Team != teamAway:
+			return fmt.Errorf("scenario %s, event #%d: team must be home or away", id, i)
+		}
+	}
+	return nil
+}
+
+// scripted reports whether a game's goals and cards come from a scenario
+func scripted(game *Game) bool {
+	return scenarios[game.ID] != nil
+}
+
+// scenarioVolatility is the scenario's multiplier on a game's drift
+func scenarioVolatility(game *Game) float64 {
+	if s := scenarios[game.ID]; s != nil && s.Volatility != nil {
+		return *s.Volatility
+	}
+	return 1
+}
+
+// scenarioReversion is how strongly a game's drift is pulled back to its
+// base odds: the scenario's reversion, or MEAN_REVERSION
+func scenarioReversion(game *Game) float64 {
+	if s := scenarios[game.ID]; s != nil && s.Odds.Reversion != nil {
+		return *s.Odds.Reversion
+	}
+	return meanReversion
+}
+
+// attachScenario starts a new game's timeline at its current minute, so
+// events already in the past when it joins aren't replayed on its first
+// tick, and passes its half-time break if it joins after the half
+func attachScenario(game *Game) {
+	game.halfTimeDone = game.clock >= halfTimeClock()
+	s := scenarios[game.ID]
+	if s == nil {
+		return
+	}
+	for game.scenarioStep < len(s.Events) && s.Events[game.scenarioStep].Minute < game.Minute {
+		game.scenarioStep++
+	}
+}
+
+// halfTimeClock is the match clock, in seconds, of the half
+func halfTimeClock() float64 {
+	return float64(fullTimeMinute*60) / 2
+}
+
+// scenarioGenerator plays a scripted game's timeline: each event goes out on
+// the tick its minute is reached, goals updating the score, and goals and
+// red cards moving the odds by the scenario's shifts. Odds steps set their
+// prices without an event. A recreated game replays from its minute.
+type scenarioGenerator struct{}
+
+func (scenarioGenerator) Generate(game *Game, r *rand.Rand) []MatchEvent {
+	s := scenarios[game.ID]
+	if s == nil {
+		return nil
+	}
+
+	var events []MatchEvent
+	now := game.LastUpdated
+	for ; game.scenarioStep < len(s.Events) && s.Events[game.scenarioStep].Minute <= game.Minute; game.scenarioStep++ {
+		step := s.Events[game.scenarioStep]
+		own, other := marketHome, marketAway
+		if step.Team == teamAway {
+			own, other = marketAway, marketHome
+		}
+		switch step.Type {
+		case scenarioOdds:
+			for market, odds := range step.Odds {
+				// Suspended and settled markets hold their price
+				if game.Markets[market].Status == marketOpen {
+					game.setOdds(market, odds, now)
+				}
+			}
+			continue
+		case eventGoal:
+			if step.Team == teamHome {
+				game.HomeScore++
+			} else {
+				game.AwayScore++
+			}
+			shiftOdds(&game.GameState, own, other, shiftOr(s.Odds.Goal, defaultGoalShift), now)
+		case eventRedCard:
+			shiftOdds(&game.GameState, other, own, shiftOr(s.Odds.RedCard, defaultRedCardShift), now)
+		}
+		events = append(events, MatchEvent{
+			GameID:    game.ID,
+			Type:      step.Type,
+			Team:      step.Team,
+			Player:    step.Player,
+			HomeScore: game.HomeScore,
+			AwayScore: game.AwayScore,
+			Minute:    game.Minute,
+			Timestamp: now,
+		})
+	}
+	return events
+}
+
+// shiftOr is a scenario's own shift when it gives one, otherwise fallback
+func shiftOr(given *OddsShift, fallback OddsShift) OddsShift {
+	if given != nil {
+		return *given
+	}
+	return fallback
+}
+
+const (
+	eventHalfTime   = "halfTime"
+	eventSecondHalf = "secondHalf"
+)
+
+// halfTimeUpdate breaks a scripted game at the half for its scenario's
+// halfTime: the tick the clock reaches the half publishes the halfTime event
+// with the clock stopped, the break runs down with no clock, drift or events,
+// and the tick it's over publishes secondHalf. ok is false outside the
+// break. Call under the registry lock, before the clock advances.
+func halfTimeUpdate(game *Game, tick time.Duration, now int64) (update gameUpdate, publish, ok bool) {
+	s := scenarios[game.ID]
+	if s == nil || s.HalfTime <= 0 || game.Status != statusLive {
+		return gameUpdate{}, false, false
+	}
+
+	if game.halfTimeLeft > 0 {
+		game.halfTimeLeft -= tick.Seconds() * timeScale
+		if game.halfTimeLeft > 0 {
+			return gameUpdate{}, false, true
+		}
+		game.Stopped = false
+		game.LastUpdated = now
+		update, publish = forcedUpdate(game, "second half state for "+game.ID, halfTimeEvent(game, eventSecondHalf, now))
+		return update, publish, true
+	}
+
+	if game.halfTimeDone || game.Stopped || game.clock < halfTimeClock() {
+		return gameUpdate{}, false, false
+	}
+	game.halfTimeDone = true
+	game.halfTimeLeft = s.HalfTime * 60
+	game.Stopped = true
+	game.LastUpdated = now
+	update, publish = forcedUpdate(game, "half time state for "+game.ID, halfTimeEvent(game, eventHalfTime, now))
+	return update, publish, true
+}
+
+func halfTimeEvent(game *Game, kind string, now int64) MatchEvent {
+	return MatchEvent{
+		GameID:    game.ID,
+		Type:      kind,
+		HomeScore: game.HomeScore,
+		AwayScore: game.AwayScore,
+		Minute:    game.Minute,
+		Timestamp: now,
+	}
+}
+
+// settleScenario settles a scripted game's markets as it reaches full time;
+// voided ones stay void. Call under the registry lock.
+func settleScenario(game *Game, now int64) {
+	if !scripted(game) {
+		return
+	}
+	for _, m := range game.Markets {
+		if !m.Voided {
+			m.Status = marketSettled
+			m.LastUpdated = now
+		}
+	}
+	if logEvents {
+		log.Printf("🧾 %s: markets settled, %s wins", game.ID, matchResult(&game.GameState))
+	}
+}
+
+// matchResult is the 1X2 market a game's score wins
+func matchResult(game *GameState) string {
+	switch {
+	case game.HomeScore > game.AwayScore:
+		return marketHome
+	case game.AwayScore > game.HomeScore:
+		return marketAway
+	default:
+		return marketDraw
+	}
+}
+
+// The typed messages a scripted game publishes on its events channel, each
+// in an envelope of its own type: its match events as match_event rather
+// than event, a score_update after each goal, an odds_update with each state
+// and a settlement at full time
+type (
+	scenarioEvent MatchEvent
+
+	// OddsUpdate is a scripted game's prices as of a state publish
+	OddsUpdate struct {
+		GameID    string             `json:"gameId"`
+		Odds      map[string]float64 `json:"odds"`
+		Minute    int                `json:"minute"`
+		Timestamp int64              `json:"timestamp"`
+	}
+
+	// ScoreUpdate is a scripted game's score after a goal
+	ScoreUpdate struct {
+		GameID    string `json:"gameId"`
+		HomeScore int    `json:"homeScore"`
+		AwayScore int    `json:"awayScore"`
+		Minute    int    `json:"minute"`
+		Timestamp int64  `json:"timestamp"`
+	}
+
+	// Settlement resolves a scripted game's markets at full time: result is
+	// the winning market, and markets each market's outcome, won, lost or
+	// void
+	Settlement struct {
+		GameID    string            `json:"gameId"`
+		Result    string            `json:"result"`
+		Markets   map[string]string `json:"markets"`
+		HomeScore int               `json:"homeScore"`
+		AwayScore int               `json:"awayScore"`
+		Timestamp int64             `json:"timestamp"`
+	}
+)
+
+// Settlement outcomes
+const (
+	outcomeWon  = "won"
+	outcomeLost = "lost"
+	outcomeVoid = "void"
+)
+
+// scenarioMessages are the score_update, odds_update and settlement messages
+// a scripted game's update adds to its match events
+func scenarioMessages(gameID string, update gameUpdate) []interface{} {
+	if scenarios[gameID] == nil {
+		return nil
+	}
+
+	var messages []interface{}
+	ended := false
+	for _, event := range update.events {
+		switch event.Type {
+		case eventGoal:
+			messages = append(messages, ScoreUpdate{GameID: gameID, HomeScore: event.HomeScore, AwayScore: event.AwayScore, Minute: event.Minute, Timestamp: event.Timestamp})
+		case eventEnded:
+			ended = true
+		}
+	}
+	if update.data == nil {
+		return messages
+	}
+
+	state := &update.state
+	wire := state.wire()
+	messages = append(messages, OddsUpdate{
+		GameID:    gameID,
+		Odds:      map[string]float64{marketHome: wire.HomeOdds, marketAway: wire.AwayOdds, marketDraw: wire.DrawOdds},
+		Minute:    state.Minute,
+		Timestamp: state.LastUpdated,
+	})
+	if !ended {
+		return messages
+	}
+
+	result := matchResult(state)
+	settlement := Settlement{GameID: gameID, Result: result, Markets: map[string]string{}, HomeScore: state.HomeScore, AwayScore: state.AwayScore, Timestamp: state.LastUpdated}
+	for name, m := range state.Markets {
+		switch {
+		case m.Voided:
+			settlement.Markets[name] = outcomeVoid
+		case name == result:
+			settlement.Markets[name] = outcomeWon
+		default:
+			settlement.Markets[name] = outcomeLost
+		}
+	}
+	return append(messages, settlement)
+}
+
+// publishScenarioMessages publishes a scripted game's typed messages for an
+// update on its events channel, after the update's match events
+func publishScenarioMessages(gameID string, update gameUpdate) {
+	for _, msg := range scenarioMessages(gameID, update) {
+		data, ok := encodeForPublish("scenario message for "+gameID, msg)
+		if !ok {
+			continue
+		}
+		publishQueue.Enqueue(outbound{kind: kindEvent, gameID: gameID, channel: eventsChannel(update.channel), data: data})
+	}
+}
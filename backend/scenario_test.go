@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeScenarios writes a scenario file holding config
+func writeScenarios(t *testing.T, config string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scenarios.json")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// useScenarios loads config for the default games and then creates them, so
+// their timelines attach as they do at startup
+func useScenarios(t *testing.T, config string) {
+	t.Helper()
+	loaded, err := loadScenarios(writeScenarios(t, config), defaultGames())
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := scenarios
+	scenarios = loaded
+	t.Cleanup(func() { scenarios = previous })
+	useGames(t)
+}
+
+func TestLoadScenariosRejectsInvalidFiles(t *testing.T) {
+	for _, tt := range []struct {
+		name, config, want string
+	}{
+		{"no scenarios", `{}`, "defines no scenarios"},
+		{"unknown game", `{"ghost": {"events": []}}`, "scenario ghost: no such game"},
+		{"unknown field", `{"game1": {"speed": 2}}`, "unknown field"},
+		{"negative volatility", `{"game1": {"volatility": -1}}`, "volatility -1"},
+		{"negative half time", `{"game1": {"halfTime": -5}}`, "halfTime -5"},
+		{"reversion above 1", `{"game1": {"odds": {"reversion": 2}}}`, "odds.reversion 2"},
+		{"goal lengthening the scorer", `{"game1": {"odds": {"goal": {"shorten": 1.2, "lengthen": 1.2}}}}`, "odds.goal"},
+		{"red card shortening the others", `{"game1": {"odds": {"redCard": {"shorten": 0.9, "lengthen": 0.9}}}}`, "odds.redCard"},
+		{"unknown type", `{"game1": {"events": [{"minute": 5, "type": "corner", "team": "home"}]}}`, `unknown type "corner"`},
+		{"no team", `{"game1": {"events": [{"minute": 5, "type": "goal"}]}}`, "team must be home or away"},
+		{"negative minute", `{"game1": {"events": [{"minute": -1, "type": "goal", "team": "home"}]}}`, "minute must not be negative"},
+		{"empty odds step", `{"game1": {"events": [{"minute": 5, "type": "odds"}]}}`, "at least one market"},
+		{"unknown market", `{"game1": {"events": [{"minute": 5, "type": "odds", "odds": {"over": 1.9}}]}}`, `unknown market "over"`},
+		{"odds on the floor", `{"game1": {"events": [{"minute": 5, "type": "odds", "odds": {"home": 1.01}}]}}`, "home odds 1.01"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadScenarios(writeScenarios(t, tt.config), defaultGames())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("loadScenarios() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadScenariosOrdersTimelinesByMinute(t *testing.T) {
+	path := writeScenarios(t, `{"game1": {"events": [
+		{"minute": 60, "type": "goal", "team": "home"},
+		{"minute": 40, "type": "yellowCard", "team": "away", "player": "first"},
+		{"minute": 40, "type": "redCard", "team": "away", "player": "second"},
+		{"minute": 10, "type": "odds", "odds": {"draw": 3.4}}
+	]}}`)
+	loaded, err := loadScenarios(path, defaultGames())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, event := range loaded["game1"].Events {
+		got = append(got, event.Type)
+	}
+	// Events in the same minute keep the file's order
+	if want := []string{scenarioOdds, eventYellowCard, eventRedCard, eventGoal}; !reflect.DeepEqual(got, want) {
+		t.Errorf("timeline = %v, want %v", got, want)
+	}
+}
+
+// atMinute moves a game's clock to minute and runs the scenario generator on
+// it, as a tick reaching that minute does
+func atMinute(t *testing.T, id string, minute int) []MatchEvent {
+	t.Helper()
+	var events []MatchEvent
+	if !registry.Update(id, func(game *Game) {
+		game.clock = float64(minute * 60)
+		game.Minute = minute
+		events = scenarioGenerator{}.Generate(game, game.rng)
+	}) {
+		t.Fatalf("%s isn't registered", id)
+	}
+	return events
+}
+
+func TestScenarioReplaysInMinuteOrderFromWhenTheGameJoins(t *testing.T) {
+	// game3 joins at minute 12, 0-0
+	useScenarios(t, `{"game3": {"events": [
+		{"minute": 20, "type": "goal", "team": "home", "player": "Yamal"},
+		{"minute": 15, "type": "yellowCard", "team": "away"},
+		{"minute": 15, "type": "redCard", "team": "home"},
+		{"minute": 5, "type": "goal", "team": "away"}
+	]}}`)
+
+	if events := atMinute(t, "game3", 12); len(events) != 0 {
+		t.Fatalf("first tick replayed %+v from before the game joined", events)
+	}
+	if events := atMinute(t, "game3", 14); len(events) != 0 {
+		t.Fatalf("got %+v before minute 15", events)
+	}
+
+	before, _ := registry.Get("game3")
+	events := atMinute(t, "game3", 16)
+	if len(events) != 2 || events[0].Type != eventYellowCard || events[1].Type != eventRedCard {
+		t.Fatalf("minute 16 got %+v, want minute 15's yellow and red cards in order", events)
+	}
+	after, _ := registry.Get("game3")
+	if after.HomeOdds <= before.HomeOdds || after.AwayOdds >= before.AwayOdds {
+		t.Errorf("odds %v/%v -> %v/%v, want the carded home side to drift out", before.HomeOdds, before.AwayOdds, after.HomeOdds, after.AwayOdds)
+	}
+
+	events = atMinute(t, "game3", 30)
+	if len(events) != 1 || events[0].Type != eventGoal || events[0].Player != "Yamal" {
+		t.Fatalf("minute 30 got %+v, want minute 20's goal", events)
+	}
+	if state, _ := registry.Get("game3"); state.HomeScore != 1 || state.AwayScore != 0 {
+		t.Errorf("score %d-%d, want 1-0 without the goal from before the game joined", state.HomeScore, state.AwayScore)
+	}
+	if events := atMinute(t, "game3", 90); len(events) != 0 {
+		t.Errorf("got %+v after the timeline ran out", events)
+	}
+}
+
+func TestScenarioOddsModel(t *testing.T) {
+	useScenarios(t, `{"game1": {
+		"odds": {"goal": {"shorten": 0.5, "lengthen": 1.5}, "reversion": 0.4},
+		"events": [
+			{"minute": 35, "type": "odds", "odds": {"home": 2.2, "draw": 3.6}},
+			{"minute": 40, "type": "goal", "team": "home"}
+		]
+	}}`)
+
+	if events := atMinute(t, "game1", 35); len(events) != 0 {
+		t.Errorf("an odds step published %+v, want no event", events)
+	}
+	state, _ := registry.Get("game1")
+	if state.HomeOdds != 2.2 || state.DrawOdds != 3.6 || state.AwayOdds != 2.8 {
+		t.Fatalf("odds after the step = %v/%v/%v, want 2.2/2.8/3.6", state.HomeOdds, state.AwayOdds, state.DrawOdds)
+	}
+
+	atMinute(t, "game1", 40)
+	state, _ = registry.Get("game1")
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-9 }
+	if !near(state.HomeOdds, 2.2*0.5) || !near(state.AwayOdds, 2.8*1.5) || !near(state.DrawOdds, 3.6*1.5) {
+		t.Errorf("odds after the goal = %v/%v/%v, want the scenario's goal shift", state.HomeOdds, state.AwayOdds, state.DrawOdds)
+	}
+
+	registry.View("game1", func(game *Game) {
+		if _, strength := driftTarget(game, marketHome); strength != 0.4 {
+			t.Errorf("drift reversion = %v, want the scenario's 0.4", strength)
+		}
+	})
+	registry.View("game2", func(game *Game) {
+		if _, strength := driftTarget(game, marketHome); strength != meanReversion {
+			t.Errorf("unscripted drift reversion = %v, want MEAN_REVERSION", strength)
+		}
+	})
+}
+
+func TestScriptedGameBreaksAtHalfTimeAndSettlesAtFullTime(t *testing.T) {
+	useScenarios(t, `{"game1": {"halfTime": 15}}`)
+	previous := timeScale
+	timeScale = 600 // two match minutes a tick
+	t.Cleanup(func() { timeScale = previous })
+	registry.Update("game1", func(game *Game) { game.clock, game.Minute = halfTimeClock(), 45 })
+
+	update, ok := tick(t)
+	if !ok || len(update.events) != 1 || update.events[0].Type != eventHalfTime || !update.state.Stopped {
+		t.Fatalf("tick at the half = %v %+v, want the halfTime event with the clock stopped", ok, update.events)
+	}
+
+	// The 15-minute break is 7 held ticks, the 8th restarts the game
+	held := 0
+	for ; held < 20; held++ {
+		if update, ok = tick(t); ok {
+			break
+		}
+		if state, _ := registry.Get("game1"); state.Minute != 45 {
+			t.Fatalf("clock moved to %d' during the break", state.Minute)
+		}
+	}
+	if held != 7 || len(update.events) != 1 || update.events[0].Type != eventSecondHalf || update.state.Stopped {
+		t.Fatalf("after %d held ticks got %+v, want 7 then secondHalf with the clock running", held, update.events)
+	}
+
+	registry.Update("game1", func(game *Game) { game.clock = float64(fullTimeMinute * 60) })
+	update, ok = tick(t)
+	if !ok || len(update.events) != 1 || update.events[0].Type != eventEnded {
+		t.Fatalf("tick at full time = %v %+v, want the ended event", ok, update.events)
+	}
+	for name, m := range update.state.Markets {
+		if m.Status != marketSettled {
+			t.Errorf("%s market is %s at full time, want settled", name, m.Status)
+		}
+	}
+
+	messages := scenarioMessages("game1", update)
+	want := Settlement{
+		GameID: "game1", Result: marketDraw, HomeScore: 1, AwayScore: 1, Timestamp: update.state.LastUpdated,
+		Markets: map[string]string{marketHome: outcomeLost, marketAway: outcomeLost, marketDraw: outcomeWon},
+	}
+	if len(messages) != 2 || !reflect.DeepEqual(messages[1], want) {
+		t.Errorf("full time messages = %+v, want an odds update then %+v", messages, want)
+	}
+
+	if rec := serve(handleGameRoutes, http.MethodPost, "/games/game1/markets/home/resume", ""); rec.Code != http.StatusConflict {
+		t.Errorf("reopening a settled market = %d, want 409", rec.Code)
+	}
+}
+
+func TestScriptedGamePublishesTypedMessages(t *testing.T) {
+	useScenarios(t, `{"game1": {"events": []}}`)
+	queue := captureQueue(t)
+	game1, _ := registry.Get("game1")
+	game2, _ := registry.Get("game2")
+
+	goal := MatchEvent{GameID: "game1", Type: eventGoal, Team: teamHome, HomeScore: 2, AwayScore: 1, Minute: 40}
+	update := gameUpdate{channel: gameChannel(&game1), data: []byte("{}"), state: game1, events: []MatchEvent{goal}}
+	publishEvents(update.channel, update.events)
+	publishScenarioMessages("game1", update)
+	// Unscripted games' events keep their plain event type
+	publishEvents(gameChannel(&game2), []MatchEvent{{GameID: "game2", Type: eventGoal, Team: teamAway}})
+
+	var types []string
+	for _, msg := range queue.drain() {
+		var env Envelope
+		if err := json.Unmarshal(msg.data, &env); err != nil {
+			t.Fatal(err)
+		}
+		if msg.channel != eventsChannel(gameChannel(&game1)) && msg.channel != eventsChannel(gameChannel(&game2)) {
+			t.Errorf("%s published on %s, want an events channel", env.Type, msg.channel)
+		}
+		types = append(types, msg.gameID+":"+env.Type)
+	}
+	want := []string{"game1:match_event", "game1:score_update", "game1:odds_update", "game2:event"}
+	if !sameItems(types, want) {
+		t.Errorf("published %v, want %v", types, want)
+	}
+	// A game's own messages keep their order
+	var game1Types []string
+	for _, kind := range types {
+		if strings.HasPrefix(kind, "game1:") {
+			game1Types = append(game1Types, kind)
+		}
+	}
+	if !reflect.DeepEqual(game1Types, want[:3]) {
+		t.Errorf("game1 published %v, want %v in order", game1Types, want[:3])
+	}
+}
+
+// sameItems reports whether a and b hold the same strings in any order
+func sameItems(a, b []string) bool {
+	counts := map[string]int{}
+	for _, s := range a {
+		counts[s]++
+	}
+	for _, s := range b {
+		counts[s]--
+	}
+	for _, n := range counts {
+		if n != 0 {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
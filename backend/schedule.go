@@ -0,0 +1,39 @@
+package main
+
+// Scheduled games sit before their KickoffTime without a clock, drift or
+// odds publishes. Their events channel gets one scheduled marker, and the
+// game goes live with a kickoff event once the time comes.
+const (
+	eventScheduled = "scheduled"
+	eventKickoff   = "kickoff"
+)
+
+// scheduledUpdate handles a tick of a scheduled game: the kickoff once it's
+// due, otherwise the scheduled marker the first time round. Call under
+// the registry lock.
+func scheduledUpdate(game *Game, now int64) (gameUpdate, bool) {
+	if now >= game.KickoffTime {
+		game.Status = statusLive
+		game.LastUpdated = now
+		return forcedUpdate(game, "kickoff state for "+game.ID, MatchEvent{
+			GameID:      game.ID,
+			Type:        eventKickoff,
+			HomeScore:   game.HomeScore,
+			AwayScore:   game.AwayScore,
+			Minute:      game.Minute,
+			KickoffTime: game.KickoffTime,
+			Timestamp:   now,
+		})
+	}
+
+	if game.announced {
+		return gameUpdate{}, false
+	}
+	game.announced = true
+	return gameUpdate{channel: gameChannel(&game.GameState), events: []MatchEvent{{
+		GameID:      game.ID,
+		Type:        eventScheduled,
+		KickoffTime: game.KickoffTime,
+		Timestamp:   now,
+	}}}, true
+}
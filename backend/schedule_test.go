@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestScheduledGameKicksOffOnTime(t *testing.T) {
+	game := testGame()
+	game.Status, game.KickoffTime = statusScheduled, 5000
+
+	update, ok := scheduledUpdate(game, 1000)
+	if !ok || update.data != nil || len(update.events) != 1 || update.events[0].Type != eventScheduled {
+		t.Fatalf("first tick before kickoff = %+v, want one scheduled event and no state", update)
+	}
+	if _, ok := scheduledUpdate(game, 2000); ok {
+		t.Error("the scheduled marker was published twice")
+	}
+
+	update, ok = scheduledUpdate(game, 5000)
+	if !ok || game.Status != statusLive || update.data == nil || len(update.events) != 1 || update.events[0].Type != eventKickoff {
+		t.Errorf("at kickoff status=%q update=%+v, want live with a state and a kickoff event", game.Status, update.events)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+)
+
+// selfCheckRetry is how often the self-check message is re-sent until it
+// arrives, since a publish can overtake the subscription being set up
+const selfCheckRetry = 100 * time.Millisecond
+
+// selfCheck round-trips a message through the broker's pub/sub: it subscribes
+// to a throwaway channel, publishes to it and waits for the message, so a
+// broker that answers pings but blocks pub/sub is caught at startup
+func selfCheck(timeout time.Duration) error {
+	channel := fmt.Sprintf("selfcheck:%d", time.Now().UnixNano())
+	payload := []byte(channel)
+
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	messages, closeSub := subscriber.Subscribe(checkCtx, []string{channel})
+	defer closeSub()
+
+	retry := time.NewTicker(selfCheckRetry)
+	defer retry.Stop()
+
+	lastErr := publisher.Publish(checkCtx, channel, payload)
+	for {
+		select {
+		case <-checkCtx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("no message received on %s within %s, last publish failed: %w", channel, timeout, lastErr)
+			}
+			return fmt.Errorf("no message received on %s within %s", channel, timeout)
+		case <-retry.C:
+			lastErr = publisher.Publish(checkCtx, channel, payload)
+		case msg, ok := <-messages:
+			if !ok {
+				return fmt.Errorf("subscription to %s closed", channel)
+			}
+			if bytes.Equal(msg.Payload, payload) {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsSelftestStats are totals across every self-test client
+var wsSelftestStats struct {
+	connects int64
+	failures int64
+	received int64
+}
+
+// runWSSelftest starts that many in-process /ws clients against addr, each
+// subscribing to a random game and reconnecting after a random time up to
+// reconnect, and logs what they received every interval
+func runWSSelftest(addr string, clients int, reconnect time.Duration) {
+	host, port, _ := net.SplitHostPort(addr)
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	endpoint := url.URL{Scheme: "ws", Host: net.JoinHostPort(host, port), Path: "/ws"}
+
+	log.Printf("Starting %d WebSocket self-test clients against %s", clients, endpoint.String())
+	for i := 0; i < clients; i++ {
+		go runSelftestClient(endpoint, rand.New(rand.NewSource(simSeed+int64(i))), reconnect)
+	}
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closeStreams:
+			return
+		case <-ticker.C:
+		}
+		log.Printf("[WS SELFTEST] Clients: %d | Connects: %d | Failures: %d | Received: %d | Open: %d",
+			clients,
+			atomic.LoadInt64(&wsSelftestStats.connects),
+			atomic.LoadInt64(&wsSelftestStats.failures),
+			atomic.LoadInt64(&wsSelftestStats.received),
+			atomic.LoadInt64(&metrics.activeConnections),
+		)
+	}
+}
+
+// runSelftestClient connects, reads until its random lifetime is up, then
+// connects again to a new random game, until shutdown
+func runSelftestClient(endpoint url.URL, r *rand.Rand, reconnect time.Duration) {
+	header := http.Header{}
+	if adminToken != "" {
+		header.Set("Authorization", "Bearer "+adminToken)
+	}
+
+	for {
+		select {
+		case <-closeStreams:
+			return
+		default:
+		}
+
+		var channel string
+		if list := wsChannels(""); len(list) > 0 {
+			channel = list[r.Intn(len(list))]
+		}
+		u := endpoint
+		u.RawQuery = url.Values{"channels": {channel}}.Encode()
+
+		conn, _, err := websocket.DefaultDialer.Dial(u.String(), header)
+		if err != nil {
+			atomic.AddInt64(&wsSelftestStats.failures, 1)
+			time.Sleep(time.Second)
+			continue
+		}
+		atomic.AddInt64(&wsSelftestStats.connects, 1)
+
+		lifetime := time.Duration(r.Int63n(int64(reconnect))) + time.Millisecond
+		conn.SetReadDeadline(time.Now().Add(lifetime))
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				break
+			}
+			atomic.AddInt64(&wsSelftestStats.received, 1)
+		}
+		conn.Close()
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log"
+	"math"
+	"time"
+)
+
+// Price shocks are rare large single-step moves, like team news breaking.
+// shockProbability is the per-tick chance a game takes one (SHOCK_PROBABILITY),
+// shockMagnitude the fraction of the price it moves by, at least half and at
+// most all of it (SHOCK_MAGNITUDE), and shockCooldown the minimum time between
+// two shocks in a game (SHOCK_COOLDOWN).
+var (
+	shockProbability float64
+	shockMagnitude   = 0.3
+	shockCooldown    = time.Minute
+)
+
+// applyShock may move one open market of a game sharply, returning the market
+// it moved. Call under the registry lock.
+func applyShock(game *Game, now int64) (string, bool) {
+	if shockProbability <= 0 || now < game.nextShockAt || game.rng.Float64() >= scaledProbability(shockProbability) {
+		return "", false
+	}
+
+	var open []string
+	for _, name := range marketNames {
+		if m, ok := game.Markets[name]; ok && m.Status == marketOpen {
+			open = append(open, name)
+		}
+	}
+	if len(open) == 0 {
+		return "", false
+	}
+
+	market := open[game.rng.Intn(len(open))]
+	odds := game.Markets[market].Odds
+	move := shockMagnitude * (0.5 + 0.5*game.rng.Float64())
+	if game.rng.Float64() < 0.5 {
+		move = -move
+	}
+	newOdds := odds * (1 + move)
+	if newOdds <= oddsFloor {
+		newOdds = math.Max(oddsFloor+0.01, odds/2)
+	}
+
+	game.setOdds(market, newOdds, now)
+	game.nextShockAt = now + shockCooldown.Milliseconds()
+	if logEvents {
+		log.Printf("⚡ %s: %s price shock %.2f -> %.2f", game.ID, market, odds, newOdds)
+	}
+	return market, true
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestShocksMoveSharplyAndCoolDown(t *testing.T) {
+	previous := shockProbability
+	shockProbability = 1
+	defer func() { shockProbability = previous }()
+	game := testGame()
+	before := game.prices()
+
+	market, ok := applyShock(game, 1000)
+	if !ok {
+		t.Fatal("no shock at SHOCK_PROBABILITY=1")
+	}
+	move := math.Abs(game.Markets[market].Odds/before[market] - 1)
+	if move < shockMagnitude/2-0.01 || move > shockMagnitude+0.01 {
+		t.Errorf("%s moved %.0f%%, want between %.0f%% and %.0f%%", market, move*100, shockMagnitude*50, shockMagnitude*100)
+	}
+
+	if _, again := applyShock(game, 1000+shockCooldown.Milliseconds()-1); again {
+		t.Error("a second shock landed within SHOCK_COOLDOWN")
+	}
+	if _, again := applyShock(game, 1000+shockCooldown.Milliseconds()); !again {
+		t.Error("no shock once SHOCK_COOLDOWN had passed")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// stopFeed is closed to stop the publish loop; feedStopped is closed once it
+// has returned
+var (
+	stopFeed    = make(chan struct{})
+	feedStopped = make(chan struct{})
+)
+
+// abortFeed is closed, with abortReason set, when the backend decides to shut
+// itself down and exit non-zero so orchestration restarts it
+var (
+	abortFeed   = make(chan struct{})
+	abortReason string
+	abortOnce   sync.Once
+)
+
+// abort requests a graceful shutdown that exits with status 1
+func abort(reason string) {
+	abortOnce.Do(func() {
+		abortReason = reason
+		close(abortFeed)
+	})
+}
+
+// maxConsecutiveErrors aborts the backend once this many publishes in a row
+// have failed (MAX_CONSECUTIVE_ERRORS); 0 never does
+var maxConsecutiveErrors int64
+
+// consecutiveErrors counts failed publishes since the last success
+var consecutiveErrors atomic.Int64
+
+// notePublishResult tracks the run of consecutive failures, aborting when it
+// exceeds maxConsecutiveErrors
+func notePublishResult(failed bool) {
+	if !failed {
+		consecutiveErrors.Store(0)
+		return
+	}
+	if n := consecutiveErrors.Add(1); maxConsecutiveErrors > 0 && n > maxConsecutiveErrors {
+		abort(fmt.Sprintf("%d consecutive publish errors (MAX_CONSECUTIVE_ERRORS=%d)", n, maxConsecutiveErrors))
+	}
+}
+
+// shutdownTimeout bounds how long in-flight HTTP requests get to finish
+const shutdownTimeout = 5 * time.Second
+
+// waitForShutdown blocks until SIGINT/SIGTERM, until maxRuntime has passed
+// when it's positive, or until the backend aborts, then shuts everything down
+// in order: HTTP server, SIGHUP reloads, feed, final states, publish queue, webhook, state dump.
+// Everything already queued is still published, within shutdownTimeout. An abort exits with status 1 afterwards.
+func waitForShutdown(server *http.Server, maxRuntime time.Duration) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	var deadline <-chan time.Time
+	if maxRuntime > 0 {
+		deadline = time.After(maxRuntime)
+	}
+
+	select {
+	case sig := <-signals:
+		log.Printf("Received %s, shutting down...", sig)
+	case <-deadline:
+		log.Printf("MAX_RUNTIME of %s reached, shutting down...", maxRuntime)
+	case <-abortFeed:
+		log.Printf("❌ Aborting: %s, shutting down...", abortReason)
+	}
+
+	close(closeStreams)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down HTTP server: %v", err)
+	}
+
+	// A SIGHUP from here on would publish into a closing queue
+	close(stopReload)
+	<-reloadStopped
+
+	// The feed may still be seeding or waiting out its start delay; either way
+	// it must be done before the publish queue closes
+	close(stopFeed)
+	<-feedStopped
+	publishFinalStates()
+	if delayedFeed != nil {
+		delayedFeed.Close()
+	}
+	// Publishes still in flight when the drain runs out of time are cancelled
+	// rather than left to hold up the exit
+	cutoff := time.AfterFunc(shutdownTimeout, cancelPublishes)
+	publishQueue.Close()
+	cutoff.Stop()
+	cancelPublishes()
+	if webhook != nil {
+		webhook.Close(webhookDrainTimeout)
+	}
+
+	writeStateDump()
+	logFinalMetrics()
+	select {
+	case <-abortFeed:
+		log.Printf("❌ Shutdown complete, exiting with status 1: %s", abortReason)
+		os.Exit(1)
+	default:
+		log.Println("✅ Shutdown complete")
+	}
+}
+
+// logFinalMetrics prints the run's totals once on exit
+func logFinalMetrics() {
+	log.Printf("[FINAL METRICS] Deltas Published: %d | Finals: %d | Errors: %d | Panics: %d | Deduped: %d | Slow Ticks: %d | Events: %d | Dropped: %d | Oversize: %d",
+		atomic.LoadInt64(&metrics.deltasPublished),
+		atomic.LoadInt64(&metrics.finalPublishes),
+		atomic.LoadInt64(&metrics.publishErrors),
+		atomic.LoadInt64(&metrics.panics),
+		atomic.LoadInt64(&metrics.dedupedPublishes),
+		atomic.LoadInt64(&metrics.slowTicks),
+		atomic.LoadInt64(&metrics.eventsPublished),
+		atomic.LoadInt64(&metrics.droppedUpdates),
+		atomic.LoadInt64(&metrics.oversizePayloads),
+	)
+}
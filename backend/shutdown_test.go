@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingPublisher holds every publish until its context is cancelled
+type blockingPublisher struct {
+	started chan struct{}
+}
+
+func (p *blockingPublisher) Publish(ctx context.Context, channel string, payload []byte) error {
+	p.started <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (p *blockingPublisher) Ping(ctx context.Context) error { return nil }
+
+func TestCancelledPublishIsNotAnError(t *testing.T) {
+	pub := &blockingPublisher{started: make(chan struct{}, 1)}
+	previousPublisher, previousCtx, previousCancel := publisher, ctx, cancelPublishes
+	publisher = pub
+	ctx, cancelPublishes = context.WithCancel(context.Background())
+	t.Cleanup(func() { publisher, ctx, cancelPublishes = previousPublisher, previousCtx, previousCancel })
+
+	queue, err := newPublishQueue(8, policyBlock, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue.run()
+	errorsBefore := atomic.LoadInt64(&metrics.publishErrors)
+	droppedBefore := atomic.LoadInt64(&metrics.droppedUpdates)
+	consecutiveErrors.Store(0)
+
+	queue.Enqueue(outbound{kind: kindEvent, gameID: "game1", channel: "game1", data: []byte("{}")})
+	<-pub.started
+	cancelPublishes()
+	queue.Close()
+
+	if got := atomic.LoadInt64(&metrics.publishErrors) - errorsBefore; got != 0 {
+		t.Errorf("publishErrors grew by %d for a cancelled publish, want 0", got)
+	}
+	if got := consecutiveErrors.Load(); got != 0 {
+		t.Errorf("consecutiveErrors = %d after a cancelled publish, want 0", got)
+	}
+	if got := atomic.LoadInt64(&metrics.droppedUpdates) - droppedBefore; got != 1 {
+		t.Errorf("droppedUpdates grew by %d, want 1", got)
+	}
+}
+
+// useFeedChannels swaps in fresh stop channels, since shutdown closes them
+func useFeedChannels(t *testing.T) {
+	t.Helper()
+	previousStop, previousStopped := stopFeed, feedStopped
+	stopFeed, feedStopped = make(chan struct{}), make(chan struct{})
+	t.Cleanup(func() { stopFeed, feedStopped = previousStop, previousStopped })
+}
+
+func TestFeedNeverStartsOnceStopped(t *testing.T) {
+	useFeedChannels(t)
+	started := feedStartedAt.Load()
+
+	// A start delay that is already due when shutdown begins
+	close(stopFeed)
+	for i := 0; i < 50; i++ {
+		feedStopped = make(chan struct{})
+		go runFeed(false, 0)
+		<-feedStopped
+	}
+
+	if feedStartedAt.Load() != started {
+		t.Error("publish loop started after stopFeed was closed")
+	}
+}
+
+func TestFeedStoppedClosesDuringStartDelay(t *testing.T) {
+	useFeedChannels(t)
+	go runFeed(false, time.Hour)
+	close(stopFeed)
+
+	select {
+	case <-feedStopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("feedStopped not closed while the start was still delayed")
+	}
+	if feedActive.Load() {
+		t.Error("feedActive = true, want false")
+	}
+}
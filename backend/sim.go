@@ -0,0 +1,171 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// timeScale speeds up the match clock and event rates (TIME_SCALE), so 10
+// plays a 90-minute match in about 9 minutes
+var timeScale = 1.0
+
+// advanceClock moves a game's match clock on by one tick of scaled time,
+// unless a stoppage is holding it
+func advanceClock(game *Game, tick time.Duration) {
+	if game.Stopped {
+		return
+	}
+	game.clock += tick.Seconds() * timeScale
+	game.Minute = int(game.clock / 60)
+}
+
+// gameUpdateProb is the per-tick chance a game publishes at all
+// (GAME_UPDATE_PROB), and marketUpdateProb the chance each of its open
+// markets then drifts (MARKET_UPDATE_PROB)
+var (
+	gameUpdateProb   = 0.9
+	marketUpdateProb = 0.6
+)
+
+// fullTimeMinute is the match minute a live game ends at (MATCH_LENGTH_MINUTES)
+var fullTimeMinute = 90
+
+// endedGameTTL is how long an ended game is kept before it is removed
+// (ENDED_GAME_TTL); 0 keeps ended games until they are deleted
+var endedGameTTL time.Duration
+
+// endMatch ends a live game once its clock reaches full time plus any time
+// added for stoppages, returning the ended event. Ended games no longer
+// drift, score or advance their clock.
+func endMatch(game *Game, now int64) (MatchEvent, bool) {
+	if game.Status != statusLive || game.clock < float64(fullTimeMinute*60)+game.addedTime {
+		return MatchEvent{}, false
+	}
+
+	game.Status = statusEnded
+	game.Minute = fullTimeMinute + int(game.addedTime/60)
+	game.endedAt = now
+	game.LastUpdated = now
+
+	return MatchEvent{
+		GameID:    game.ID,
+		Type:      eventEnded,
+		HomeScore: game.HomeScore,
+		AwayScore: game.AwayScore,
+		Minute:    game.Minute,
+		Timestamp: now,
+	}, true
+}
+
+// expired reports whether an ended game has outlived endedGameTTL
+func expired(game *Game, now int64) bool {
+	return game.Status == statusEnded && endedGameTTL > 0 && now-game.endedAt >= endedGameTTL.Milliseconds()
+}
+
+// scaledProbability converts a per-tick probability at real time into the
+// per-tick probability at the current time scale
+func scaledProbability(p float64) float64 {
+	return math.Min(1, p*timeScale)
+}
+
+// oddsTickSize is the price ladder setOdds snaps every market to; 0 disables
+// snapping
+var oddsTickSize float64
+
+// oddsFloor is the shortest price any market can reach
+const oddsFloor = 1.01
+
+// meanReversion is how strongly drifting odds are pulled back toward the
+// game's base odds each step (MEAN_REVERSION); 0 is a pure random walk
+var meanReversion float64
+
+// driftOdds applies one random-walk step to odds, scaled by the market's
+// volatility and pulled toward target by strength. Steps through the floor
+// are reflected back off it, since rejecting them would bias the walk
+// upwards. Returns false when the price doesn't move.
+func driftOdds(r *rand.Rand, odds, target, strength, volatility float64) (float64, bool) {
+	newOdds := odds + (r.Float64()-0.5)*0.6*volatility + strength*(target-odds)
+	if newOdds <= oddsFloor {
+		newOdds = 2*oddsFloor - newOdds
+	}
+
+	// Snapping can still land on the floor
+	newOdds = snapToTick(newOdds, oddsTickSize)
+	if newOdds <= oddsFloor {
+		return odds, false
+	}
+	return newOdds, true
+}
+
+// volatilityScale multiplies every market's drift volatility (VOLATILITY_SCALE)
+var volatilityScale = 1.0
+
+// freezeMinute is the match minute from which drift volatility winds down,
+// reaching zero at full time (ODDS_FREEZE_MINUTE); 0 never winds it down
+var freezeMinute int
+
+// lateVolatilityScale is how much of its normal volatility a market keeps at
+// the game's point in the match
+func lateVolatilityScale(game *Game) float64 {
+	if freezeMinute <= 0 {
+		return 1
+	}
+	minute := game.clock / 60
+	if minute <= float64(freezeMinute) {
+		return 1
+	}
+	left := (float64(fullTimeMinute) - minute) / float64(fullTimeMinute-freezeMinute)
+	return math.Max(0, left)
+}
+
+// driftMarkets random-walks each open market's odds. Call under the
+// registry lock.
+func driftMarkets(game *Game, now int64) {
+	scale := lateVolatilityScale(game) * volatilityScale * scenarioVolatility(game)
+	for _, market := range marketNames {
+		// Suspended markets hold their price
+		if game.Markets[market].Status == marketSuspended {
+			continue
+		}
+		if game.rng.Float64() < marketUpdateProb {
+			m := game.Markets[market]
+			target, strength := driftTarget(game, market)
+			if newOdds, ok := driftOdds(game.rng, m.Odds, target, strength, m.Volatility*scale); ok {
+				game.setOdds(market, newOdds, now)
+			}
+		}
+	}
+}
+
+// driftTarget is where a market's odds are pulled toward: the external true
+// line when one is available, otherwise the game's base odds
+func driftTarget(game *Game, market string) (target, strength float64) {
+	if odds, ok := trueLineTarget(game.ID, market); ok {
+		return odds, trueLineStrength
+	}
+	return game.baseOdds[market], scenarioReversion(game)
+}
+
+// snapToTick rounds odds to the nearest multiple of tick
+func snapToTick(odds, tick float64) float64 {
+	if tick <= 0 {
+		return odds
+	}
+	return cleanTick(math.Round(odds/tick) * tick)
+}
+
+// snapDownToTick rounds odds down to a multiple of tick, allowing for float
+// error in odds that are already on it
+func snapDownToTick(odds, tick float64) float64 {
+	if tick <= 0 {
+		return odds
+	}
+	return cleanTick(math.Floor(odds/tick+1e-9) * tick)
+}
+
+// cleanTick drops the float error a multiple of the tick picks up, so 56
+// ticks of 0.05 publish as 2.8 rather than 2.8000000000000003
+func cleanTick(odds float64) float64 {
+	return math.Round(odds*1e9) / 1e9
+}
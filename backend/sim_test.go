@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestTimeScaleSpeedsUpClockAndEvents(t *testing.T) {
+	previous := timeScale
+	defer func() { timeScale = previous }()
+	timeScale = 10
+	game := testGame()
+	game.clock, game.Minute = 0, 0
+
+	// Ten real seconds at 10x are 100 match seconds
+	for i := 0; i < 10; i++ {
+		advanceClock(game, time.Second)
+	}
+	if game.clock != 100 || game.Minute != 1 {
+		t.Errorf("after 10s at 10x clock=%vs minute=%d, want 100s and minute 1", game.clock, game.Minute)
+	}
+
+	if got := scaledProbability(0.01); got < 0.0999 || got > 0.1001 {
+		t.Errorf("scaledProbability(0.01) at 10x = %v, want 0.1", got)
+	}
+	if got := scaledProbability(0.5); got != 1 {
+		t.Errorf("scaledProbability(0.5) at 10x = %v, want capped at 1", got)
+	}
+}
+
+func TestMatchEndsAtFullTimeAndExpires(t *testing.T) {
+	previous := endedGameTTL
+	defer func() { endedGameTTL = previous }()
+	endedGameTTL = time.Minute
+	game := testGame()
+	game.Status = statusLive
+	game.clock, game.addedTime = float64(fullTimeMinute*60)-1, 0
+
+	if _, ended := endMatch(game, 1000); ended {
+		t.Fatal("match ended a second before full time")
+	}
+	game.clock++
+	event, ended := endMatch(game, 1000)
+	if !ended || event.Type != eventEnded || game.Status != statusEnded || game.Minute != fullTimeMinute {
+		t.Fatalf("at full time ended=%v event=%q status=%q minute=%d, want an ended event at minute %d", ended, event.Type, game.Status, game.Minute, fullTimeMinute)
+	}
+	if _, again := endMatch(game, 2000); again {
+		t.Error("an ended match ended again")
+	}
+
+	if expired(game, 1000+time.Minute.Milliseconds()-1) {
+		t.Error("ended game expired before ENDED_GAME_TTL")
+	}
+	if !expired(game, 1000+time.Minute.Milliseconds()) {
+		t.Error("ended game didn't expire after ENDED_GAME_TTL")
+	}
+}
+
+func TestDriftOddsReflectsOffFloorAndReverts(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	// At the floor every step lands back above it
+	for i := 0; i < 1000; i++ {
+		if odds, ok := driftOdds(r, oddsFloor+0.01, 2, 0, 1); ok && odds <= oddsFloor {
+			t.Fatalf("drift from the floor landed on %v", odds)
+		}
+	}
+
+	// Away from the floor the walk has no drift of its own
+	sum := 0.0
+	for i := 0; i < 10000; i++ {
+		odds, _ := driftOdds(r, 3, 3, 0, 1)
+		sum += odds - 3
+	}
+	if mean := sum / 10000; mean < -0.01 || mean > 0.01 {
+		t.Errorf("mean step at strength 0 = %v, want about 0", mean)
+	}
+
+	// Mean reversion pulls the price back toward its target
+	odds := 6.0
+	for i := 0; i < 100; i++ {
+		odds, _ = driftOdds(r, odds, 2, 0.1, 0)
+	}
+	if odds < 1.99 || odds > 2.01 {
+		t.Errorf("after 100 steps at strength 0.1 odds = %v, want about the 2.0 target", odds)
+	}
+}
+
+func TestOddsFreezeWindsVolatilityDown(t *testing.T) {
+	previous := freezeMinute
+	defer func() { freezeMinute = previous }()
+	freezeMinute = 80
+	game := testGame()
+
+	for _, tt := range []struct {
+		minute float64
+		want   float64
+	}{
+		{70, 1},
+		{80, 1},
+		{85, 0.5},
+		{float64(fullTimeMinute), 0},
+		{float64(fullTimeMinute) + 3, 0},
+	} {
+		game.clock = tt.minute * 60
+		if got := lateVolatilityScale(game); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("volatility scale at minute %v = %v, want %v", tt.minute, got, tt.want)
+		}
+	}
+
+	freezeMinute = 0
+	if got := lateVolatilityScale(game); got != 1 {
+		t.Errorf("volatility scale without ODDS_FREEZE_MINUTE = %v, want 1", got)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+)
+
+// Channels of the snapshot request/reply pattern (SNAPSHOT_REQUESTS): a
+// client publishes a game ID on snapshotRequestChannel and the backend
+// answers on snapshotReplyChannel with the payload last published for the
+// game, the same as GET /games/{id}/latest. Replies are shared by every
+// requester, so clients match them up by the payload's game ID.
+const (
+	snapshotRequestChannel = "snapshot-request"
+	snapshotReplyChannel   = "snapshot-reply"
+)
+
+// snapshotError is the reply to a request for a game with no published state
+type snapshotError struct {
+	GameID string `json:"gameId"`
+	Error  string `json:"error"`
+}
+
+// serveSnapshots answers snapshot requests until the subscription closes
+func serveSnapshots() {
+	messages, closeSub := subscriber.Subscribe(ctx, []string{snapshotRequestChannel})
+	defer closeSub()
+
+	for msg := range messages {
+		if gameID := strings.TrimSpace(string(msg.Payload)); gameID != "" {
+			replySnapshot(gameID)
+		}
+	}
+}
+
+// replySnapshot publishes a game's latest payload, or why there isn't one
+func replySnapshot(gameID string) {
+	data, err := latest.GetLatest(ctx, gameID)
+	if err != nil {
+		reason := "no published state"
+		if !errors.Is(err, errNoLatest) {
+			reason = "error reading latest state: " + err.Error()
+		}
+		data, err = json.Marshal(snapshotError{GameID: gameID, Error: reason})
+		if err != nil {
+			log.Printf("Error encoding snapshot reply for %s: %v", gameID, err)
+			return
+		}
+		data = withOutputCase(data)
+	}
+	if err := publisher.Publish(ctx, snapshotReplyChannel, data); err != nil {
+		publishFailed(err, "snapshot reply for "+gameID)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRequestsGetTheLatestState(t *testing.T) {
+	broker := newInprocBroker()
+	previousPublisher, previousSubscriber, previousLatest := publisher, subscriber, latest
+	publisher, subscriber, latest = broker, broker, broker
+	t.Cleanup(func() { publisher, subscriber, latest = previousPublisher, previousSubscriber, previousLatest })
+
+	state := []byte(`{"type":"state","data":{"id":"game1"}}`)
+	broker.SetLatest(context.Background(), "game1", state)
+	replies, closeReplies := broker.Subscribe(context.Background(), []string{snapshotReplyChannel})
+	defer closeReplies()
+
+	go serveSnapshots()
+	waitFor(t, "the snapshot request subscription", func() bool { return broker.Subscriptions() == 2 })
+
+	reply := func(gameID string) []byte {
+		t.Helper()
+		broker.Publish(context.Background(), snapshotRequestChannel, []byte(" "+gameID+"\n"))
+		select {
+		case msg := <-replies:
+			return msg.Payload
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no snapshot reply for %s", gameID)
+			return nil
+		}
+	}
+
+	if got := reply("game1"); string(got) != string(state) {
+		t.Errorf("snapshot of game1 = %s, want the latest published %s", got, state)
+	}
+	var missing snapshotError
+	if err := json.Unmarshal(reply("game9"), &missing); err != nil {
+		t.Fatal(err)
+	}
+	if missing.GameID != "game9" || missing.Error != "no published state" {
+		t.Errorf("snapshot of an unpublished game = %+v, want a no published state error", missing)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const sportTennis = "tennis"
+
+// sportEvents is the event catalog of each sport that doesn't play football:
+// the per-tick probability of each event type, at real time. A sport listed
+// here gets only its own events, never goals or cards; any other sport plays
+// football's, set by GOAL_PROBABILITY and CARD_PROBABILITY. SPORT_EVENTS
+// adds sports and event types or overrides their probabilities.
+var sportEvents = map[string]map[string]float64{
+	sportTennis: {
+		"ace":         0.01,
+		"doubleFault": 0.004,
+		"breakPoint":  0.005,
+	},
+}
+
+// parseSportEvents parses "sport:event=probability,..." (e.g.
+// "tennis:ace=0.02,basketball:threePointer=0.05") over the built-in catalog
+func parseSportEvents(items []string) (map[string]map[string]float64, error) {
+	catalog := make(map[string]map[string]float64, len(sportEvents))
+	for sport, events := range sportEvents {
+		catalog[sport] = make(map[string]float64, len(events))
+		for event, p := range events {
+			catalog[sport][event] = p
+		}
+	}
+
+	for _, item := range items {
+		key, raw, ok := strings.Cut(item, "=")
+		sport, event, hasEvent := strings.Cut(strings.TrimSpace(key), ":")
+		if !ok || !hasEvent || sport == "" || event == "" {
+			return nil, fmt.Errorf("%q: expected sport:event=probability", item)
+		}
+		if sport == sportFootball {
+			return nil, fmt.Errorf("%q: football's events are set by GOAL_PROBABILITY and CARD_PROBABILITY", item)
+		}
+		p, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || p < 0 || p > 1 {
+			return nil, fmt.Errorf("%q: probability must be within [0,1]", item)
+		}
+		if catalog[sport] == nil {
+			catalog[sport] = map[string]float64{}
+		}
+		catalog[sport][event] = p
+	}
+	return catalog, nil
+}
+
+// playsFootball reports whether a sport gets goals and cards rather than
+// events from its own catalog
+func playsFootball(sport string) bool {
+	_, ok := sportEvents[sport]
+	return !ok
+}
+
+// sportEventTypes lists a sport's catalog event types in a fixed order, so a
+// seeded run rolls for them the same way every time
+func sportEventTypes(sport string) []string {
+	types := make([]string, 0, len(sportEvents[sport]))
+	for event := range sportEvents[sport] {
+		types = append(types, event)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// sportEventGenerator rolls for each of a non-football sport's catalog
+// events. They're credited to a side (and a player, when its squad is known)
+// but leave the score and odds alone.
+type sportEventGenerator struct{}
+
+func (sportEventGenerator) Generate(game *Game, r *rand.Rand) []MatchEvent {
+	if playsFootball(game.Sport) {
+		return nil
+	}
+
+	var events []MatchEvent
+	for _, eventType := range sportEventTypes(game.Sport) {
+		if r.Float64() >= eventProbability(game, sportEvents[game.Sport][eventType]) {
+			continue
+		}
+		team := teamHome
+		if r.Float64() < 0.5 {
+			team = teamAway
+		}
+		squad := game.squad(team)
+		event := MatchEvent{
+			GameID:    game.ID,
+			Type:      eventType,
+			Team:      team,
+			HomeScore: game.HomeScore,
+			AwayScore: game.AwayScore,
+			Minute:    game.Minute,
+			Timestamp: game.LastUpdated,
+		}
+		if len(squad) > 0 {
+			event.Player = squad[r.Intn(len(squad))]
+		}
+		events = append(events, event)
+	}
+	return events
+}
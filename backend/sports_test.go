@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestTennisOnlyEmitsItsOwnEvents(t *testing.T) {
+	catalog, err := parseSportEvents([]string{"tennis:ace=0.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousGenerators, previousCatalog := eventGenerators, sportEvents
+	previousGoal, previousCard := goalProbability, cardProbability
+	eventGenerators = []EventGenerator{goalGenerator{}, cardGenerator{}, sportEventGenerator{}}
+	sportEvents, goalProbability, cardProbability = catalog, 1, 1
+	t.Cleanup(func() {
+		eventGenerators, sportEvents = previousGenerators, previousCatalog
+		goalProbability, cardProbability = previousGoal, previousCard
+	})
+
+	game := testGame()
+	game.Sport = sportTennis
+	counts := map[string]int{}
+	for game.clock = 0; game.clock < 3000; game.clock += 10 {
+		for _, event := range generateEvents(game) {
+			counts[event.Type]++
+		}
+	}
+
+	for _, football := range []string{eventGoal, eventYellowCard, eventRedCard} {
+		if counts[football] > 0 {
+			t.Errorf("tennis game emitted %d %s events", counts[football], football)
+		}
+	}
+	if counts["ace"] == 0 {
+		t.Errorf("tennis game never served an ace at probability 0.5: %v", counts)
+	}
+	if game.HomeScore != 1 || game.AwayScore != 1 {
+		t.Errorf("tennis events changed the score to %d-%d", game.HomeScore, game.AwayScore)
+	}
+
+	for _, items := range [][]string{{"football:goal=0.1"}, {"tennis:ace=2"}, {"tennis=0.1"}} {
+		if _, err := parseSportEvents(items); err == nil {
+			t.Errorf("parseSportEvents(%q) = nil error, want one", items)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+)
+
+// With staggerStart (STAGGER_START) each game's first publish is held back by
+// an offset within staggerWindow (STAGGER_WINDOW) derived from its ID, so the
+// feed's first updates are spread out instead of landing in one burst. Games
+// created after the window has passed publish straight away.
+var (
+	staggerStart  bool
+	staggerWindow = time.Second
+)
+
+// feedStartedAt is when the publish loop started, in unix millis
+var feedStartedAt atomic.Int64
+
+// staggerOffset is a game's deterministic share of the stagger window
+func staggerOffset(gameID string) time.Duration {
+	h := fnv.New32a()
+	h.Write([]byte(gameID))
+	return time.Duration(h.Sum32()%uint32(staggerWindow.Milliseconds())) * time.Millisecond
+}
+
+// staggered reports whether a game is still waiting for its first publish
+func staggered(gameID string, now int64) bool {
+	if !staggerStart {
+		return false
+	}
+	return now < feedStartedAt.Load()+staggerOffset(gameID).Milliseconds()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestStaggerOffsetsAreDeterministicAndSpread(t *testing.T) {
+	previousStart, previousWindow := staggerStart, staggerWindow
+	staggerStart, staggerWindow = true, 2*time.Second
+	started := feedStartedAt.Load()
+	t.Cleanup(func() {
+		staggerStart, staggerWindow = previousStart, previousWindow
+		feedStartedAt.Store(started)
+	})
+
+	distinct := map[time.Duration]bool{}
+	for i := 1; i <= 50; i++ {
+		id := fmt.Sprintf("game%d", i)
+		offset := staggerOffset(id)
+		if offset != staggerOffset(id) {
+			t.Fatalf("staggerOffset(%s) changed between calls", id)
+		}
+		if offset < 0 || offset >= staggerWindow {
+			t.Errorf("staggerOffset(%s) = %s, want within [0, %s)", id, offset, staggerWindow)
+		}
+		distinct[offset] = true
+	}
+	if len(distinct) < 25 {
+		t.Errorf("50 games share only %d offsets", len(distinct))
+	}
+
+	feedStartedAt.Store(1000)
+	offset := staggerOffset("game1").Milliseconds()
+	if offset > 0 && !staggered("game1", 1000+offset-1) {
+		t.Error("game1 published before its offset was up")
+	}
+	if staggered("game1", 1000+offset) {
+		t.Error("game1 still staggered once its offset was up")
+	}
+	staggerStart = false
+	if staggered("game1", 1000) {
+		t.Error("game1 staggered with STAGGER_START off")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// startedAt is when the process started, for the run duration
+var startedAt = time.Now()
+
+// stateDumpFile receives the final games and metrics on graceful shutdown
+// (STATE_DUMP_FILE)
+var stateDumpFile string
+
+// StateDump is the end-of-run artifact written to stateDumpFile
+type StateDump struct {
+	StartedAt   int64                  `json:"startedAt"`
+	DumpedAt    int64                  `json:"dumpedAt"`
+	RunDuration string                 `json:"runDuration"`
+	Games       map[string]GameState   `json:"games"`
+	Metrics     map[string]interface{} `json:"metrics"`
+}
+
+// dumpState writes the games and metrics to path, through a temporary file
+// so a crash mid-write never leaves a truncated dump behind
+func dumpState(path string) error {
+	states := map[string]GameState{}
+	for _, state := range registry.Snapshot() {
+		states[state.ID] = state
+	}
+
+	now := time.Now()
+	data, err := json.MarshalIndent(StateDump{
+		StartedAt:   startedAt.UnixMilli(),
+		DumpedAt:    now.UnixMilli(),
+		RunDuration: now.Sub(startedAt).Round(time.Millisecond).String(),
+		Games:       states,
+		Metrics:     metricsSnapshot(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// writeStateDump dumps state on shutdown when STATE_DUMP_FILE is set
+func writeStateDump() {
+	if stateDumpFile == "" {
+		return
+	}
+	if err := dumpState(stateDumpFile); err != nil {
+		log.Printf("❌ Error writing state dump to %s: %v", stateDumpFile, err)
+		return
+	}
+	log.Printf("✅ Wrote state dump to %s", stateDumpFile)
+}
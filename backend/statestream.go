@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// StreamWriter is implemented by brokers that can append each published
+// state to a per-game Redis stream, so a consumer that was down can replay
+// what it missed with XRANGE or XREAD
+type StreamWriter interface {
+	AppendStream(ctx context.Context, gameID string, seq uint64, data []byte) error
+}
+
+// StreamReader is implemented by brokers that can read a game's stream back,
+// for ?since= on the history endpoint
+type StreamReader interface {
+	// ReadStream returns up to count states published after seq since,
+	// oldest first, and how many states the stream holds
+	ReadStream(ctx context.Context, gameID string, since uint64, count int64) ([]streamEntry, int64, error)
+}
+
+// streamEntry is a published state read back from a stream
+type streamEntry struct {
+	seq  uint64
+	data []byte
+}
+
+// streamWriter receives every published game state (STATE_STREAM), and
+// streamReader reads them back; both nil when disabled
+var (
+	streamWriter StreamWriter
+	streamReader StreamReader
+)
+
+// stateStreamMaxLen is roughly how many entries each game's stream keeps
+// (STATE_STREAM_MAXLEN); Redis trims the oldest past it
+var stateStreamMaxLen int64 = 1000
+
+// streamKey is the Redis stream of a game's published states, next to its
+// latestKey
+func streamKey(gameID string) string {
+	return gameID + ":stream"
+}
+
+// streamID is the entry ID of a state: 0-<seq>, so a range from a seq is a
+// range of IDs
+func streamID(seq uint64) string {
+	return "0-" + strconv.FormatUint(seq, 10)
+}
+
+// stateStreamArgs is the XADD of one published state: its payload under
+// "data" and, when sequence numbers are on, its seq under "seq" and in its
+// entry ID. Without them Redis picks the IDs.
+func stateStreamArgs(gameID string, seq uint64, data []byte) *redis.XAddArgs {
+	args := &redis.XAddArgs{Stream: streamKey(gameID), MaxLen: stateStreamMaxLen, Approx: true, Values: map[string]interface{}{"data": data}}
+	if seq != 0 {
+		args.ID = streamID(seq)
+		args.Values = map[string]interface{}{"data": data, "seq": seq}
+	}
+	return args
+}
+
+// queueStreamAppend queues the commands appending a state to its stream. A
+// game's first state (seq 1) starts its sequence over, e.g. after it was
+// recreated, so the old stream goes first.
+func queueStreamAppend(ctx context.Context, pipe redis.Pipeliner, gameID string, seq uint64, data []byte) []redis.Cmder {
+	var cmds []redis.Cmder
+	if seq == 1 {
+		cmds = append(cmds, pipe.Del(ctx, streamKey(gameID)))
+	}
+	return append(cmds, pipe.XAdd(ctx, stateStreamArgs(gameID, seq, data)))
+}
+
+// appendStateStream adds a published state to the game's stream. Like the
+// latest cache, a failure is logged but doesn't fail the publish.
+func appendStateStream(gameID string, seq uint64, data []byte) {
+	if streamWriter == nil || skipsKey(streamKey(gameID)) {
+		return
+	}
+	if err := streamWriter.AppendStream(ctx, gameID, seq, data); isOOM(err) {
+		noteOOM(err)
+	} else if isWrongType(err) {
+		noteWrongType(err, streamKey(gameID))
+	} else if err != nil {
+		log.Printf("Error appending state stream for %s: %v", gameID, err)
+	}
+}
+
+func (p *redisPublisher) AppendStream(ctx context.Context, gameID string, seq uint64, data []byte) error {
+	pipe := p.client.Pipeline()
+	queueStreamAppend(ctx, pipe, gameID, seq, data)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (p *routedPublisher) AppendStream(ctx context.Context, gameID string, seq uint64, data []byte) error {
+	return p.forGame(gameID).AppendStream(ctx, gameID, seq, data)
+}
+
+// ReadStream reads XRANGE <id>:stream 0-<since+1> + with XLEN, in one round
+// trip
+func (p *redisPublisher) ReadStream(ctx context.Context, gameID string, since uint64, count int64) ([]streamEntry, int64, error) {
+	pipe := p.client.Pipeline()
+	rangeCmd := pipe.XRangeN(ctx, streamKey(gameID), streamID(since+1), "+", count)
+	lenCmd := pipe.XLen(ctx, streamKey(gameID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	messages := rangeCmd.Val()
+	entries := make([]streamEntry, 0, len(messages))
+	for _, msg := range messages {
+		_, rawSeq, _ := strings.Cut(msg.ID, "-")
+		seq, err := strconv.ParseUint(rawSeq, 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("stream entry %s: %w", msg.ID, err)
+		}
+		data, _ := msg.Values["data"].(string)
+		entries = append(entries, streamEntry{seq: seq, data: []byte(data)})
+	}
+	return entries, lenCmd.Val(), nil
+}
+
+func (p *routedPublisher) ReadStream(ctx context.Context, gameID string, since uint64, count int64) ([]streamEntry, int64, error) {
+	return p.forGame(gameID).ReadStream(ctx, gameID, since, count)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// useStateStream turns STATE_STREAM on against a miniredis server
+func useStateStream(t *testing.T) *redis.Client {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	broker := &redisPublisher{client: client}
+	streamWriter, streamReader = broker, broker
+	t.Cleanup(func() { streamWriter, streamReader = nil, nil })
+	return client
+}
+
+// appendStates appends game1 states with seqs from to to, as published
+func appendStates(t *testing.T, from, to uint64) {
+	t.Helper()
+	game := testGame()
+	for seq := from; seq <= to; seq++ {
+		data, err := encodeMessage(game, seq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := streamWriter.AppendStream(context.Background(), "game1", seq, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// historySince calls GET /games/game1/history?since=
+func historySince(t *testing.T, since string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handleGameHistory(rec, httptest.NewRequest(http.MethodGet, "/games/game1/history?since="+since, nil), "game1")
+	return rec
+}
+
+func TestHistorySinceReadsStateStream(t *testing.T) {
+	useGames(t)
+	useStateStream(t)
+	appendStates(t, 1, 5)
+
+	rec := historySince(t, "2")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var states []Envelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &states); err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != 3 || states[0].Seq != 3 || states[2].Seq != 5 {
+		t.Errorf("since=2 returned %d states from seq %d, want seqs 3-5", len(states), states[0].Seq)
+	}
+	if got := rec.Header().Get("X-Total-Count"); got != "5" {
+		t.Errorf("X-Total-Count = %s, want the 5 held", got)
+	}
+
+	if rec := historySince(t, "5"); rec.Body.String() != "[]\n" {
+		t.Errorf("since=5 = %s, want []", rec.Body)
+	}
+}
+
+func TestHistorySinceGoneWhenStreamTrimmed(t *testing.T) {
+	useGames(t)
+	client := useStateStream(t)
+	appendStates(t, 1, 5)
+	client.XTrimMaxLen(context.Background(), streamKey("game1"), 2)
+
+	if rec := historySince(t, "2"); rec.Code != http.StatusGone {
+		t.Errorf("since=2 with only seqs 4-5 kept: status %d, want 410", rec.Code)
+	}
+	if rec := historySince(t, "3"); rec.Code != http.StatusOK {
+		t.Errorf("since=3 with seqs 4-5 kept: status %d, want 200", rec.Code)
+	}
+}
+
+func TestStateStreamRestartsWithTheSequence(t *testing.T) {
+	client := useStateStream(t)
+	appendStates(t, 1, 5)
+	appendStates(t, 1, 2) // the game was recreated
+
+	entries, err := client.XRange(context.Background(), streamKey("game1"), "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].ID != "0-1" || entries[1].ID != "0-2" {
+		t.Errorf("stream after a restart = %v, want entries 0-1 and 0-2", entries)
+	}
+}
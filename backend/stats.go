@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Match stats keys. Possession is a percentage, home and away always summing
+// to 100; shots and corners are counts.
+const (
+	statHomePossession = "homePossession"
+	statAwayPossession = "awayPossession"
+	statHomeShots      = "homeShots"
+	statAwayShots      = "awayShots"
+	statHomeCorners    = "homeCorners"
+	statAwayCorners    = "awayCorners"
+)
+
+var statNames = []string{statHomePossession, statAwayPossession, statHomeShots, statAwayShots, statHomeCorners, statAwayCorners}
+
+// Per-tick chances of a shot and a corner for each team, at real time
+const (
+	shotProbability   = 0.012
+	cornerProbability = 0.005
+)
+
+// Possession stays within these bounds, however one-sided the game
+const (
+	minPossession = 25.0
+	maxPossession = 75.0
+)
+
+// initStats fills in the stats a game wasn't given: an even split of
+// possession (or the complement of the side that was given) and zero counts
+func (g *GameState) initStats() {
+	if g.Stats == nil {
+		g.Stats = make(map[string]float64, len(statNames))
+	}
+	home, hasHome := g.Stats[statHomePossession]
+	away, hasAway := g.Stats[statAwayPossession]
+	switch {
+	case hasHome && !hasAway:
+		g.Stats[statAwayPossession] = 100 - home
+	case hasAway && !hasHome:
+		g.Stats[statHomePossession] = 100 - away
+	case !hasHome && !hasAway:
+		g.Stats[statHomePossession], g.Stats[statAwayPossession] = 50, 50
+	}
+	for _, name := range statNames {
+		if _, ok := g.Stats[name]; !ok {
+			g.Stats[name] = 0
+		}
+	}
+}
+
+// validateStats rejects unknown stats, possession outside 0-100 or not
+// summing to 100, and negative counts
+func validateStats(stats map[string]float64) error {
+	for name, value := range stats {
+		switch name {
+		case statHomePossession, statAwayPossession:
+			if value < 0 || value > 100 {
+				return fmt.Errorf("stat %q must be between 0 and 100", name)
+			}
+		case statHomeShots, statAwayShots, statHomeCorners, statAwayCorners:
+			if value < 0 {
+				return fmt.Errorf("stat %q must not be negative", name)
+			}
+		default:
+			return fmt.Errorf("unknown stat %q", name)
+		}
+	}
+	home, hasHome := stats[statHomePossession]
+	away, hasAway := stats[statAwayPossession]
+	if hasHome && hasAway && math.Abs(home+away-100) > 0.01 {
+		return fmt.Errorf("homePossession and awayPossession must sum to 100")
+	}
+	return nil
+}
+
+// statsGenerator drifts possession and now and then adds a shot or corner.
+// It produces no events of its own.
+type statsGenerator struct{}
+
+func (statsGenerator) Generate(game *Game, r *rand.Rand) []MatchEvent {
+	if game.Stats == nil {
+		return nil
+	}
+
+	home := game.Stats[statHomePossession] + (r.Float64()-0.5)*scaledProbability(1)
+	home = math.Round(math.Min(maxPossession, math.Max(minPossession, home))*10) / 10
+	game.Stats[statHomePossession] = home
+	game.Stats[statAwayPossession] = math.Round((100-home)*10) / 10
+
+	for _, side := range []struct{ shots, corners string }{
+		{statHomeShots, statHomeCorners},
+		{statAwayShots, statAwayCorners},
+	} {
+		if r.Float64() < scaledProbability(shotProbability) {
+			game.Stats[side.shots]++
+		}
+		if r.Float64() < scaledProbability(cornerProbability) {
+			game.Stats[side.corners]++
+		}
+	}
+	return nil
+}
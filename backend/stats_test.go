@@ -0,0 +1,32 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestPossessionAlwaysSumsTo100(t *testing.T) {
+	game := testGame()
+	game.Stats = map[string]float64{statHomePossession: 62.5}
+	game.initStats()
+	if got := game.Stats[statAwayPossession]; got != 37.5 {
+		t.Fatalf("initStats filled awayPossession = %v, want 37.5", got)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		(statsGenerator{}).Generate(game, r)
+		home, away := game.Stats[statHomePossession], game.Stats[statAwayPossession]
+		if math.Abs(home+away-100) > 1e-9 {
+			t.Fatalf("tick %d: possession %v + %v = %v, want 100", i, home, away, home+away)
+		}
+		if home < minPossession || home > maxPossession {
+			t.Fatalf("tick %d: home possession %v outside %v-%v", i, home, minPossession, maxPossession)
+		}
+	}
+
+	if err := validateStats(map[string]float64{statHomePossession: 60, statAwayPossession: 50}); err == nil {
+		t.Error("possession summing to 110 accepted")
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// StatsdClient is a minimal fire-and-forget statsd client over UDP. Counters
+// are tracked as lifetime totals locally and sent as per-interval deltas.
+type StatsdClient struct {
+	conn   net.Conn
+	prefix string
+	last   map[string]int64
+}
+
+func newStatsdClient(addr string) (*StatsdClient, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &StatsdClient{conn: conn, prefix: "websocket_poc.", last: map[string]int64{}}, nil
+}
+
+// Counters sends the change in each lifetime total since the previous call
+// in a single datagram
+func (s *StatsdClient) Counters(totals map[string]int64) error {
+	var lines []string
+	for name, total := range totals {
+		delta := total - s.last[name]
+		s.last[name] = total
+		if delta != 0 {
+			lines = append(lines, fmt.Sprintf("%s%s:%d|c", s.prefix, name, delta))
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	_, err := s.conn.Write([]byte(strings.Join(lines, "\n")))
+	return err
+}
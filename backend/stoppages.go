@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// Referee stoppages (injuries, VAR checks) hold a live game's clock.
+// stoppageProbability is the per-tick chance one starts (STOPPAGE_PROBABILITY)
+// and stoppageDuration its typical length in match time, from half to one and
+// a half times it (STOPPAGE_DURATION). Time lost to stoppages is added on
+// before full time.
+var (
+	stoppageProbability float64
+	stoppageDuration    = time.Minute
+)
+
+// updateStoppage starts or runs down a game's stoppage for one tick,
+// reporting whether the clock just stopped or restarted. Call under the
+// registry lock, before advanceClock.
+func updateStoppage(game *Game, tick time.Duration) bool {
+	if game.Stopped {
+		elapsed := tick.Seconds() * timeScale
+		game.stoppageLeft -= elapsed
+		game.addedTime += elapsed
+		if game.stoppageLeft > 0 {
+			return false
+		}
+		game.Stopped = false
+		if logEvents {
+			log.Printf("⏱️  %s: play resumes at %d', %.0fs added so far", game.ID, game.Minute, game.addedTime)
+		}
+		return true
+	}
+
+	if stoppageProbability <= 0 || game.Status != statusLive || game.rng.Float64() >= scaledProbability(stoppageProbability) {
+		return false
+	}
+	game.Stopped = true
+	game.stoppageLeft = stoppageDuration.Seconds() * (0.5 + game.rng.Float64())
+	if logEvents {
+		log.Printf("⏱️  %s: clock stopped at %d'", game.ID, game.Minute)
+	}
+	return true
+}
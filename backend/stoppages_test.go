@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStoppageHoldsClockAndAddsTime(t *testing.T) {
+	previous := stoppageProbability
+	stoppageProbability = 1
+	defer func() { stoppageProbability = previous }()
+	game := testGame()
+	game.Status = statusLive
+	start := game.clock
+
+	if !updateStoppage(game, time.Second) || !game.Stopped {
+		t.Fatal("no stoppage at STOPPAGE_PROBABILITY=1")
+	}
+	length := game.stoppageLeft
+	if length < stoppageDuration.Seconds()/2 || length > stoppageDuration.Seconds()*1.5 {
+		t.Errorf("stoppage of %vs, want between half and 1.5x %s", length, stoppageDuration)
+	}
+
+	// Play on until the stoppage runs out: the clock holds and the time is added
+	stoppageProbability = 0
+	ticks := 0
+	for game.Stopped {
+		updateStoppage(game, time.Second)
+		advanceClock(game, time.Second)
+		ticks++
+	}
+	// The clock restarts on the tick the stoppage runs out
+	if game.clock != start+1 {
+		t.Errorf("clock moved %vs over %d ticks of stoppage, want only the restart tick's 1s", game.clock-start, ticks)
+	}
+	if game.addedTime != float64(ticks) || game.addedTime < length {
+		t.Errorf("added time %vs after %d stopped ticks, want %ds covering the %vs stoppage", game.addedTime, ticks, ticks, length)
+	}
+}
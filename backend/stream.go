@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// warmupSnapshots is how many historical states a new stream subscriber is
+// sent before live updates (WARMUP_SNAPSHOTS); 0 starts with live updates
+var warmupSnapshots int
+
+// streamHub fans published game states out to in-process subscribers
+type streamHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan GameState]struct{}
+}
+
+// closeStreams is closed on shutdown so open streams end instead of holding
+// the HTTP server open
+var closeStreams = make(chan struct{})
+
+var hub = &streamHub{subs: make(map[string]map[chan GameState]struct{})}
+
+func (h *streamHub) subscribe(gameID string) chan GameState {
+	ch := make(chan GameState, 16)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[gameID] == nil {
+		h.subs[gameID] = make(map[chan GameState]struct{})
+	}
+	h.subs[gameID][ch] = struct{}{}
+	return ch
+}
+
+func (h *streamHub) unsubscribe(gameID string, ch chan GameState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs[gameID], ch)
+	if len(h.subs[gameID]) == 0 {
+		delete(h.subs, gameID)
+	}
+}
+
+// broadcast hands a state to every subscriber of its game, skipping
+// subscribers too slow to keep up rather than stalling the publisher
+func (h *streamHub) broadcast(state GameState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[state.ID] {
+		select {
+		case ch <- state:
+		default:
+		}
+	}
+}
+
+// recordState keeps a published state in history and streams it live
+func recordState(state GameState, seq uint64) {
+	history.Add(state, seq)
+	hub.broadcast(state)
+}
+
+// GET /games/{id}/stream streams the game's states as server-sent events,
+// starting with the last warmupSnapshots states from history
+func handleGameStream(w http.ResponseWriter, r *http.Request, gameID string) {
+	if !registry.Has(gameID) {
+		writeError(w, http.StatusNotFound, "game not found: "+gameID)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	// Subscribe before reading history so no update falls in between
+	ch := hub.subscribe(gameID)
+	defer hub.unsubscribe(gameID, ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	for _, state := range history.Last(gameID, warmupSnapshots) {
+		if err := writeEvent(w, state); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-closeStreams:
+			return
+		case state := <-ch:
+			if err := writeEvent(w, state); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes one state as a server-sent event
+func writeEvent(w http.ResponseWriter, state GameState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		log.Printf("Error encoding stream state for %s: %v", state.ID, err)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", withOutputCase(data))
+	return err
+}
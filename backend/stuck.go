@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+// stuckThreshold is how many consecutive publishes a live game's open odds
+// may go unchanged before it's reported as stuck (STUCK_ODDS_THRESHOLD); 0
+// disables the check
+var stuckThreshold = 50
+
+// noteOdds tracks how long a live game's odds have gone unchanged across
+// publishes, warning once each time it gets stuck. Games with every market
+// suspended are expected to hold their price. Call under the registry lock.
+func (g *Game) noteOdds() {
+	if stuckThreshold <= 0 {
+		return
+	}
+	odds := triggerKeys(&g.GameState)[triggerOdds]
+	if odds != g.lastOdds || g.Status != statusLive || !g.anyMarketOpen() {
+		g.lastOdds = odds
+		g.unchangedPublishes = 0
+		return
+	}
+
+	g.unchangedPublishes++
+	if g.unchangedPublishes == stuckThreshold {
+		atomic.AddInt64(&metrics.stuckGames, 1)
+		log.Printf("⚠️  WARN: %s odds unchanged for %d publishes while live", g.ID, g.unchangedPublishes)
+	}
+}
+
+// anyMarketOpen reports whether any of the game's markets is taking bets
+func (g *GameState) anyMarketOpen() bool {
+	for _, m := range g.Markets {
+		if m.Status == marketOpen {
+			return true
+		}
+	}
+	return false
+}
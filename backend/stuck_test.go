@@ -0,0 +1,42 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestStuckOddsWarnOncePerStall(t *testing.T) {
+	previous := stuckThreshold
+	stuckThreshold = 3
+	defer func() { stuckThreshold = previous }()
+	game := testGame()
+	game.Status = statusLive
+	before := atomic.LoadInt64(&metrics.stuckGames)
+
+	for i := 0; i < 10; i++ {
+		game.noteOdds()
+	}
+	if got := atomic.LoadInt64(&metrics.stuckGames) - before; got != 1 {
+		t.Fatalf("stuckGames grew by %d over one long stall, want 1", got)
+	}
+
+	// A move resets the count, and the next stall is reported again
+	game.setOdds(marketHome, game.HomeOdds+0.1, 1)
+	for i := 0; i < 4; i++ {
+		game.noteOdds()
+	}
+	if got := atomic.LoadInt64(&metrics.stuckGames) - before; got != 2 {
+		t.Errorf("stuckGames grew by %d over two stalls, want 2", got)
+	}
+
+	// Suspended markets are expected to hold their price
+	for _, m := range game.Markets {
+		m.Status = marketSuspended
+	}
+	for i := 0; i < 10; i++ {
+		game.noteOdds()
+	}
+	if got := atomic.LoadInt64(&metrics.stuckGames) - before; got != 2 {
+		t.Errorf("stuckGames grew to %d with every market suspended, want 2", got)
+	}
+}
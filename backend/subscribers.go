@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// SubscriberCounter is implemented by brokers that can report how many
+// subscribers each channel has (Redis PUBSUB NUMSUB)
+type SubscriberCounter interface {
+	NumSub(ctx context.Context, channels []string) (map[string]int64, error)
+}
+
+// subscriberCounts holds the latest per-channel subscriber counts, and
+// subscribersStale is set while the last query failed or timed out, so the
+// counts are from an earlier poll
+var (
+	subscriberCounts atomic.Value
+	subscribersStale atomic.Bool
+)
+
+// subscriberQueryTimeout bounds each PUBSUB NUMSUB query, so a slow Redis
+// only delays fresh counts (SUBSCRIBER_QUERY_TIMEOUT)
+var subscriberQueryTimeout = time.Second
+
+// pollSubscribers refreshes subscriberCounts for every active channel each
+// interval, when the broker can count subscribers
+func pollSubscribers(interval time.Duration) {
+	broker := publisher
+	if partition != nil {
+		broker = partition.Publisher
+	}
+	counter, ok := broker.(SubscriberCounter)
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		refreshSubscribers(counter)
+	}
+}
+
+// refreshSubscribers runs one bounded count of every active channel's
+// subscribers, keeping the last counts and flagging them stale when it fails
+func refreshSubscribers(counter SubscriberCounter) {
+	var channels []string
+	for _, info := range activeChannels() {
+		channels = append(channels, info.Channel)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, subscriberQueryTimeout)
+	counts, err := counter.NumSub(reqCtx, channels)
+	cancel()
+	if err != nil {
+		subscribersStale.Store(true)
+		log.Printf("Error counting channel subscribers, keeping the last counts: %v", err)
+		return
+	}
+	subscriberCounts.Store(counts)
+	subscribersStale.Store(false)
+}
+
+func (p *redisPublisher) NumSub(ctx context.Context, channels []string) (map[string]int64, error) {
+	return p.client.PubSubNumSub(ctx, channels...).Result()
+}
+
+func (p *routedPublisher) NumSub(ctx context.Context, channels []string) (map[string]int64, error) {
+	return p.fallback.NumSub(ctx, channels)
+}
+
+func (b *inprocBroker) NumSub(ctx context.Context, channels []string) (map[string]int64, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	counts := make(map[string]int64, len(channels))
+	for _, channel := range channels {
+		counts[channel] = int64(len(b.subs[channel]))
+	}
+	return counts, nil
+}
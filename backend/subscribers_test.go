@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// hungCounter never answers before its context is done
+type hungCounter struct{}
+
+func (hungCounter) NumSub(ctx context.Context, channels []string) (map[string]int64, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestSlowSubscriberCountsGoStale(t *testing.T) {
+	useGames(t)
+	previous := subscriberQueryTimeout
+	subscriberQueryTimeout = 20 * time.Millisecond
+	t.Cleanup(func() { subscriberQueryTimeout = previous; subscribersStale.Store(false) })
+
+	broker := newInprocBroker()
+	_, closeSub := broker.Subscribe(context.Background(), []string{"game1"})
+	defer closeSub()
+	refreshSubscribers(broker)
+	if subscribersStale.Load() {
+		t.Fatal("counts flagged stale after a successful query")
+	}
+	counts := subscriberCounts.Load().(map[string]int64)
+
+	start := time.Now()
+	refreshSubscribers(hungCounter{})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("a hung query took %s, want it cut off at %s", elapsed, subscriberQueryTimeout)
+	}
+	if !subscribersStale.Load() {
+		t.Error("counts not flagged stale after a query timed out")
+	}
+	if kept := subscriberCounts.Load().(map[string]int64); kept["game1"] != 1 || len(kept) != len(counts) {
+		t.Errorf("counts after a timeout = %v, want the last ones %v", kept, counts)
+	}
+}
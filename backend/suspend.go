@@ -0,0 +1,41 @@
+package main
+
+import "net/http"
+
+// suspendedUpdate handles a suspended game, or one just resumed: the change
+// publishes once, then a suspended game is frozen, with no clock, drift,
+// events or publishes, while staying listed. ok is false when the game
+// isn't suspended and nothing changed. Call under the registry lock.
+func suspendedUpdate(game *Game, now int64) (update gameUpdate, publish, ok bool) {
+	if game.Suspended != game.publishedSuspended {
+		game.publishedSuspended = game.Suspended
+		game.LastUpdated = now
+		update, publish = forcedUpdate(game, "suspension state for "+game.ID)
+		return update, publish, true
+	}
+	return gameUpdate{}, false, game.Suspended
+}
+
+// POST /games/{id}/suspend and /games/{id}/resume stop and restart a game's
+// updates; its next tick publishes the change
+func handleSetGameSuspended(w http.ResponseWriter, gameID string, suspended bool) {
+	var state GameState
+	ended := false
+	if !registry.Update(gameID, func(game *Game) {
+		if ended = game.Status == statusEnded; ended {
+			return
+		}
+		game.Suspended = suspended
+		game.LastUpdated = game.now()
+		state = game.clone()
+	}) {
+		writeError(w, http.StatusNotFound, "game not found: "+gameID)
+		return
+	}
+	if ended {
+		writeError(w, http.StatusConflict, "game has ended: "+gameID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, state)
+}
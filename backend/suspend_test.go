@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSuspendStatusCodes(t *testing.T) {
+	useGames(t)
+	registry.Update("game3", func(game *Game) { game.Status = statusEnded })
+
+	for _, tt := range []struct {
+		target string
+		want   int
+	}{
+		{"/games/game1/suspend", http.StatusOK},
+		{"/games/game1/suspend", http.StatusOK},
+		{"/games/game1/resume", http.StatusOK},
+		{"/games/ghost/suspend", http.StatusNotFound},
+		{"/games/game3/suspend", http.StatusConflict},
+		{"/games/game3/resume", http.StatusConflict},
+	} {
+		if rec := serve(handleGameRoutes, http.MethodPost, tt.target, ""); rec.Code != tt.want {
+			t.Errorf("POST %s = %d, want %d: %s", tt.target, rec.Code, tt.want, rec.Body)
+		}
+	}
+
+	rec := serve(handleGameRoutes, http.MethodPost, "/games/game2/suspend", "")
+	var state GameState
+	if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil || !state.Suspended {
+		t.Errorf("suspend responded %s (%v), want the game with suspended set", rec.Body, err)
+	}
+}
+
+func TestCreateRejectsSuspended(t *testing.T) {
+	useGames(t)
+	body := `{"id":"game9","homeTeam":"Ajax","awayTeam":"PSV","homeOdds":2.1,"awayOdds":3.4,"drawOdds":3.2,"suspended":true}`
+	rec := serve(handleGames, http.MethodPost, "/games", body)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "suspended") {
+		t.Errorf("POST /games with suspended = %d %s, want 400 naming the field", rec.Code, rec.Body)
+	}
+	if registry.Has("game9") {
+		t.Error("game9 created suspended")
+	}
+}
+
+// tick advances game1 once the way the publish loop does
+func tick(t *testing.T) (gameUpdate, bool) {
+	t.Helper()
+	for _, game := range registry.Games() {
+		if game.ID == "game1" {
+			return advanceGame(game)
+		}
+	}
+	t.Fatal("game1 isn't registered")
+	return gameUpdate{}, false
+}
+
+func publishedSuspended(t *testing.T, update gameUpdate) bool {
+	t.Helper()
+	var env struct {
+		Data GameState `json:"data"`
+	}
+	if err := json.Unmarshal(update.data, &env); err != nil {
+		t.Fatal(err)
+	}
+	return env.Data.Suspended
+}
+
+func TestSuspendedGameHoldsOddsAndPublishesTheChange(t *testing.T) {
+	useGames(t)
+	if rec := serve(handleGameRoutes, http.MethodPost, "/games/game1/suspend", ""); rec.Code != http.StatusOK {
+		t.Fatalf("suspend = %d: %s", rec.Code, rec.Body)
+	}
+
+	update, ok := tick(t)
+	if !ok || !publishedSuspended(t, update) {
+		t.Fatalf("first tick after suspending published %v, want a state with suspended set", ok)
+	}
+	held, _ := registry.Get("game1")
+	for i := 0; i < 200; i++ {
+		if _, ok := tick(t); ok {
+			t.Fatalf("tick %d published a suspended game", i)
+		}
+	}
+	state, _ := registry.Get("game1")
+	if state.HomeOdds != held.HomeOdds || state.AwayOdds != held.AwayOdds || state.DrawOdds != held.DrawOdds || state.Minute != held.Minute {
+		t.Errorf("suspended game moved: odds %v/%v/%v minute %d, held at %v/%v/%v minute %d",
+			state.HomeOdds, state.AwayOdds, state.DrawOdds, state.Minute, held.HomeOdds, held.AwayOdds, held.DrawOdds, held.Minute)
+	}
+	for name, m := range state.Markets {
+		if m.Odds != held.Markets[name].Odds {
+			t.Errorf("market %s moved from %v to %v while suspended", name, held.Markets[name].Odds, m.Odds)
+		}
+	}
+
+	serve(handleGameRoutes, http.MethodPost, "/games/game1/resume", "")
+	if update, ok := tick(t); !ok || publishedSuspended(t, update) {
+		t.Errorf("first tick after resuming published %v, want a state without suspended", ok)
+	}
+}
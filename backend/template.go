@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"text/template"
+)
+
+// payloadTemplate, when set, renders every game state publish instead of the
+// default JSON encoding (PAYLOAD_TEMPLATE), e.g.
+// {"match": {{json .ID}}, "home": {{round .HomeOdds 2}}}. The output must be
+// valid JSON.
+var payloadTemplate *template.Template
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"round": roundOdds,
+}
+
+// parsePayloadTemplate parses a payload template and trial-renders it
+// against a sample game, so field typos and malformed output fail at startup
+func parsePayloadTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("payload").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := GameState{ID: "sample", HomeTeam: "Home", AwayTeam: "Away", Sport: sportFootball, Status: statusLive, HomeOdds: 2, AwayOdds: 3, DrawOdds: 3.5}
+	sample.initStats()
+	sample.initMarkets()
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, sample); err != nil {
+		return nil, err
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("output is not valid JSON: %s", buf.Bytes())
+	}
+	return tmpl, nil
+}
+
+// templateError is published in place of a state the template failed on
+type templateError struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// renderPayload renders a game through payloadTemplate. A game it fails on
+// publishes a templateError instead, so clients see why the state is missing.
+func renderPayload(game *GameState) interface{} {
+	var buf bytes.Buffer
+	err := payloadTemplate.Execute(&buf, game)
+	if err == nil && !json.Valid(buf.Bytes()) {
+		err = errors.New("output is not valid JSON")
+	}
+	if err != nil {
+		atomic.AddInt64(&metrics.templateErrors, 1)
+		log.Printf("Error rendering payload template for %s: %v", game.ID, err)
+		return templateError{ID: game.ID, Error: fmt.Sprintf("payload template: %v", err)}
+	}
+	return rawPayload(buf.Bytes())
+}
+
+// rawPayload is rendered JSON, encoded as-is rather than as a string
+type rawPayload []byte
+
+func (p rawPayload) MarshalJSON() ([]byte, error) {
+	return p, nil
+}
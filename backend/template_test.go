@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"text/template"
+)
+
+func TestPayloadTemplateRendersStates(t *testing.T) {
+	tmpl, err := parsePayloadTemplate(`{"match": {{json .ID}}, "home": {{round .HomeOdds 2}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := payloadTemplate
+	payloadTemplate = tmpl
+	t.Cleanup(func() { payloadTemplate = previous })
+
+	game := testGame()
+	data, ok := game.encodeState("state for game1")
+	if !ok {
+		t.Fatal("encodeState failed with a valid template")
+	}
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Match string
+		Home  float64
+	}
+	if err := json.Unmarshal(env.Data, &got); err != nil {
+		t.Fatalf("rendered payload %s is not the template's JSON: %v", env.Data, err)
+	}
+	if got.Match != "game1" || got.Home != game.HomeOdds {
+		t.Errorf("rendered %s, want match game1 and home %v", env.Data, game.HomeOdds)
+	}
+
+	// A state the template fails on publishes why instead
+	payloadTemplate = template.Must(template.New("payload").Parse(`{{.HomeTeam}}`))
+	before := atomic.LoadInt64(&metrics.templateErrors)
+	rendered, err := json.Marshal(renderPayload(&game.GameState))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(rendered), `"error":"payload template:`) || atomic.LoadInt64(&metrics.templateErrors) != before+1 {
+		t.Errorf("failed render published %s, want a counted templateError", rendered)
+	}
+}
+
+func TestParsePayloadTemplateRejectsBadTemplates(t *testing.T) {
+	for _, text := range []string{`{"id": {{json .Nope}}}`, `{"id": {{json .ID}}`, `{{json .ID`, `{{.HomeTeam}}`} {
+		if _, err := parsePayloadTemplate(text); err == nil {
+			t.Errorf("parsePayloadTemplate(%s) = nil error, want one", text)
+		}
+	}
+}
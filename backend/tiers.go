@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tierIntervals maps each feed tier to how often its games publish
+// (TIER_INTERVALS, or defaultTierIntervals once PUBLISH_INTERVAL is read).
+// Games without a tier publish every tick.
+var tierIntervals map[string]time.Duration
+
+// defaultTierIntervals are the tiers without TIER_INTERVALS: premium games
+// publish every tick, standard ones every second but never faster than the
+// tick
+func defaultTierIntervals(tick time.Duration) map[string]time.Duration {
+	return map[string]time.Duration{
+		"premium":  tick,
+		"standard": max(time.Second, tick),
+	}
+}
+
+// parseTierIntervals parses "name=interval,..." (e.g. "premium=200ms,standard=1s").
+// Intervals can't be shorter than the publish loop's tick.
+func parseTierIntervals(items []string) (map[string]time.Duration, error) {
+	tiers := make(map[string]time.Duration, len(items))
+	for _, item := range items {
+		name, raw, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%q: expected name=interval", item)
+		}
+		interval, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("tier %q: %v", name, err)
+		}
+		if interval < publishInterval() {
+			return nil, fmt.Errorf("tier %q: interval %s is shorter than the %s tick", name, interval, publishInterval())
+		}
+		tiers[name] = interval
+	}
+	return tiers, nil
+}
+
+// tierNames lists the configured tiers, sorted
+func tierNames() []string {
+	names := make([]string, 0, len(tierIntervals))
+	for name := range tierIntervals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tierChannel is the Redis channel carrying the states of every game in a tier
+func tierChannel(tier string) string {
+	return "tier:" + tier
+}
+
+// tierDue reports whether a tiered game's interval has elapsed, scheduling
+// its next publish when it has. Games without a tier are always due.
+func tierDue(game *Game, now int64) bool {
+	if game.Tier == "" {
+		return true
+	}
+	if now < game.nextPublishAt {
+		return false
+	}
+	game.nextPublishAt = now + tierIntervals[game.Tier].Milliseconds()
+	return true
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDefaultTierIntervalsFollowTheTick(t *testing.T) {
+	tiers := defaultTierIntervals(2 * time.Second)
+	if tiers["premium"] != 2*time.Second {
+		t.Errorf("premium = %s with a 2s tick, want 2s", tiers["premium"])
+	}
+	if tiers["standard"] != 2*time.Second {
+		t.Errorf("standard = %s with a 2s tick, want no faster than the tick", tiers["standard"])
+	}
+	if tiers := defaultTierIntervals(200 * time.Millisecond); tiers["premium"] != 200*time.Millisecond || tiers["standard"] != time.Second {
+		t.Errorf("tiers with a 200ms tick = %v, want premium 200ms, standard 1s", tiers)
+	}
+}
+
+func TestParseTierIntervalsRejectsIntervalsUnderTheTick(t *testing.T) {
+	previous := publishInterval()
+	setPublishInterval(500 * time.Millisecond)
+	defer setPublishInterval(previous)
+
+	if _, err := parseTierIntervals([]string{"premium=200ms"}); err == nil {
+		t.Error("premium=200ms accepted with a 500ms tick")
+	}
+	tiers, err := parseTierIntervals([]string{"premium=500ms", "standard=2s"})
+	if err != nil || tiers["premium"] != 500*time.Millisecond || tiers["standard"] != 2*time.Second {
+		t.Errorf("parseTierIntervals = %v, %v", tiers, err)
+	}
+}
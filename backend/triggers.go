@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Publish trigger categories (PUBLISH_TRIGGERS): which kinds of change make
+// a game publish its state
+const (
+	triggerOdds   = "odds"
+	triggerScore  = "score"
+	triggerStatus = "status"
+	triggerClock  = "clock"
+)
+
+var triggerNames = []string{triggerOdds, triggerScore, triggerStatus, triggerClock}
+
+// publishTriggers is the set of categories that trigger a publish; nil means
+// every tick that passes the update gate publishes, as by default
+var publishTriggers map[string]bool
+
+// parsePublishTriggers validates a PUBLISH_TRIGGERS list, returning nil when
+// it names every category
+func parsePublishTriggers(items []string) (map[string]bool, error) {
+	triggers := make(map[string]bool, len(items))
+	for _, item := range items {
+		valid := false
+		for _, name := range triggerNames {
+			valid = valid || item == name
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown trigger %q (expected %s)", item, strings.Join(triggerNames, ", "))
+		}
+		triggers[item] = true
+	}
+	if len(triggers) == len(triggerNames) {
+		return nil, nil
+	}
+	return triggers, nil
+}
+
+// triggerKeys summarises each trigger category of a game's state, so two
+// states differ in a category exactly when its keys differ
+func triggerKeys(g *GameState) map[string]string {
+	odds, statuses := "", g.Status
+	for _, name := range marketNames {
+		if m, ok := g.Markets[name]; ok {
+			odds += fmt.Sprintf("|%s:%v", name, m.Odds)
+			statuses += "|" + name + ":" + m.Status
+		}
+	}
+	return map[string]string{
+		triggerOdds:   odds,
+		triggerScore:  fmt.Sprintf("%d-%d", g.HomeScore, g.AwayScore),
+		triggerStatus: statuses,
+		triggerClock:  fmt.Sprint(g.Minute),
+	}
+}
+
+// triggered reports whether a triggering category changed since the game
+// last published its state. Call under the registry lock.
+func (g *Game) triggered() bool {
+	if publishTriggers == nil {
+		return true
+	}
+	keys := triggerKeys(&g.GameState)
+	for category := range publishTriggers {
+		if keys[category] != g.lastTriggers[category] {
+			return true
+		}
+	}
+	return false
+}
+
+// notePublished records the state a game just published for triggered and
+// the stuck odds check
+func (g *Game) notePublished() {
+	g.publishes++
+	g.scheduleAdaptive()
+	if publishTriggers != nil {
+		g.lastTriggers = triggerKeys(&g.GameState)
+	}
+	g.noteOdds()
+}
@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestPublishTriggersOnlyFireOnChosenChanges(t *testing.T) {
+	triggers, err := parsePublishTriggers([]string{triggerScore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishTriggers = triggers
+	defer func() { publishTriggers = nil }()
+	game := testGame()
+	game.notePublished()
+
+	game.Markets[marketHome].Odds += 0.5
+	game.Minute++
+	if game.triggered() {
+		t.Error("odds and clock changes triggered a publish with PUBLISH_TRIGGERS=score")
+	}
+	game.HomeScore++
+	if !game.triggered() {
+		t.Error("a score change didn't trigger a publish with PUBLISH_TRIGGERS=score")
+	}
+	game.notePublished()
+	if game.triggered() {
+		t.Error("still triggered right after publishing")
+	}
+}
+
+func TestParsePublishTriggers(t *testing.T) {
+	if triggers, err := parsePublishTriggers(triggerNames); err != nil || triggers != nil {
+		t.Errorf("every trigger = %v, %v; want nil, nil", triggers, err)
+	}
+	if _, err := parsePublishTriggers([]string{triggerOdds, "weather"}); err == nil {
+		t.Error("unknown trigger accepted")
+	}
+}
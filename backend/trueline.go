@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// trueLineStrength is how strongly odds are pulled toward the external true
+// line each step (TRUE_LINE_STRENGTH)
+var trueLineStrength = 0.1
+
+// trueLine holds the latest targets polled from TRUE_LINE_URL, keyed by game
+// ID then market. Empty when no feed is configured or the last poll failed.
+var trueLine = struct {
+	sync.RWMutex
+	targets map[string]map[string]float64
+}{}
+
+// trueLineTarget returns the polled target odds for a game's market
+func trueLineTarget(gameID, market string) (float64, bool) {
+	trueLine.RLock()
+	defer trueLine.RUnlock()
+	odds, ok := trueLine.targets[gameID][market]
+	return odds, ok
+}
+
+// pollTrueLine fetches the true line from url every interval. The feed is a
+// JSON object of game ID to market odds, e.g. {"game1": {"home": 2.1}}.
+// A failed poll clears the targets so drift falls back to the random walk.
+func pollTrueLine(url string, interval time.Duration) {
+	client := &http.Client{Timeout: interval}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	healthy := true
+	for {
+		targets, err := fetchTrueLine(client, url)
+		trueLine.Lock()
+		trueLine.targets = targets
+		trueLine.Unlock()
+
+		switch {
+		case err != nil && healthy:
+			log.Printf("⚠️  True line unavailable, falling back to random walk: %v", err)
+		case err == nil && !healthy:
+			log.Printf("✅ True line restored (%d games)", len(targets))
+		}
+		healthy = err == nil
+
+		<-ticker.C
+	}
+}
+
+func fetchTrueLine(client *http.Client, url string) (map[string]map[string]float64, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var targets map[string]map[string]float64
+	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
+		return nil, fmt.Errorf("decoding true line: %v", err)
+	}
+
+	// Only sane prices are used as targets
+	for gameID, markets := range targets {
+		for market, odds := range markets {
+			if !isMarket(market) || odds <= oddsFloor {
+				delete(markets, market)
+			}
+		}
+		if len(markets) == 0 {
+			delete(targets, gameID)
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Freshness hints for clients: how long published odds should be treated as
+// current before they're greyed out. oddsTTL (ODDS_TTL) applies to every
+// market unless marketTTLs (MARKET_TTL) has its own; zero leaves ttlMs out.
+// The feed never expires anything itself.
+var (
+	oddsTTL    time.Duration
+	marketTTLs = map[string]time.Duration{}
+)
+
+// parseMarketTTLs parses "market=ttl,..." (e.g. "draw=5s,home=2s")
+func parseMarketTTLs(items []string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration, len(items))
+	for _, item := range items {
+		market, raw, ok := strings.Cut(item, "=")
+		market = strings.TrimSpace(market)
+		if !ok || market == "" {
+			return nil, fmt.Errorf("%q: expected market=ttl", item)
+		}
+		if !isMarket(market) {
+			return nil, fmt.Errorf("unknown market %q", market)
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("market %q: %v", market, err)
+		}
+		if ttl < time.Millisecond {
+			return nil, fmt.Errorf("market %q: ttl %s must be at least 1ms", market, ttl)
+		}
+		ttls[market] = ttl
+	}
+	return ttls, nil
+}
+
+// marketTTL is a market's ttlMs, 0 when none is configured
+func marketTTL(market string) int64 {
+	if ttl, ok := marketTTLs[market]; ok {
+		return ttl.Milliseconds()
+	}
+	return oddsTTL.Milliseconds()
+}
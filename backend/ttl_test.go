@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarketTTLsAnnotatePublishedOdds(t *testing.T) {
+	previousTTL, previousMarkets := oddsTTL, marketTTLs
+	t.Cleanup(func() { oddsTTL, marketTTLs = previousTTL, previousMarkets })
+
+	ttls, err := parseMarketTTLs([]string{"draw=5s", " home = 2s "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oddsTTL, marketTTLs = 3*time.Second, ttls
+
+	game := testGame()
+	if game.TTLMs != 3000 {
+		t.Errorf("game ttlMs = %d, want 3000", game.TTLMs)
+	}
+	for market, want := range map[string]int64{marketHome: 2000, marketDraw: 5000, marketAway: 3000} {
+		if got := game.Markets[market].TTLMs; got != want {
+			t.Errorf("%s ttlMs = %d, want %d", market, got, want)
+		}
+	}
+
+	oddsTTL, marketTTLs = 0, map[string]time.Duration{}
+	data, err := json.Marshal(testGame())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "ttlMs") {
+		t.Errorf("ttlMs published with no TTL configured: %s", data)
+	}
+
+	for _, items := range [][]string{{"draw"}, {"corners=1s"}, {"draw=soon"}, {"draw=0s"}} {
+		if _, err := parseMarketTTLs(items); err == nil {
+			t.Errorf("parseMarketTTLs(%q) = nil error, want one", items)
+		}
+	}
+}
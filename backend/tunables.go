@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// Tunables are the simulation knobs that can change while the feed runs,
+// read from TUNABLES_FILE at startup and again on every SIGHUP. Fields left
+// out keep their current value.
+type Tunables struct {
+	PublishInterval  *string  `json:"publishInterval,omitempty"` // a duration, e.g. "500ms"
+	GameUpdateProb   *float64 `json:"gameUpdateProb"`
+	MarketUpdateProb *float64 `json:"marketUpdateProb"`
+	VolatilityScale  *float64 `json:"volatilityScale"`
+	MeanReversion    *float64 `json:"meanReversion"`
+	GoalProbability  *float64 `json:"goalProbability"`
+	CardProbability  *float64 `json:"cardProbability"`
+	ShockProbability *float64 `json:"shockProbability"`
+}
+
+// tunablesFile is the tunables config (TUNABLES_FILE); empty disables it
+var tunablesFile string
+
+// intervalChanges hands a reloaded tick rate to the publish loop, which owns
+// its ticker
+var intervalChanges = make(chan time.Duration, 1)
+
+// loadTunables reads and validates a tunables config, returning the new
+// tick rate separately (0 when unchanged)
+func loadTunables(path string) (Tunables, time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Tunables{}, 0, err
+	}
+	defer f.Close()
+
+	var t Tunables
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&t); err != nil {
+		return Tunables{}, 0, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	var interval time.Duration
+	if t.PublishInterval != nil {
+		interval, err = time.ParseDuration(*t.PublishInterval)
+		switch {
+		case err != nil:
+			return Tunables{}, 0, fmt.Errorf("publishInterval: %w", err)
+		case interval <= 0:
+			return Tunables{}, 0, fmt.Errorf("publishInterval %s must be positive", interval)
+		case interval > watchdogTimeout:
+			return Tunables{}, 0, fmt.Errorf("publishInterval %s is longer than FEED_WATCHDOG_TIMEOUT (%s)", interval, watchdogTimeout)
+		}
+		for name, tier := range tierIntervals {
+			if tier < interval {
+				return Tunables{}, 0, fmt.Errorf("publishInterval %s is longer than tier %q's %s", interval, name, tier)
+			}
+		}
+	}
+	for name, p := range map[string]*float64{
+		"gameUpdateProb":   t.GameUpdateProb,
+		"marketUpdateProb": t.MarketUpdateProb,
+		"meanReversion":    t.MeanReversion,
+		"goalProbability":  t.GoalProbability,
+		"cardProbability":  t.CardProbability,
+		"shockProbability": t.ShockProbability,
+	} {
+		if p != nil && (*p < 0 || *p > 1) {
+			return Tunables{}, 0, fmt.Errorf("%s %v must be within [0,1]", name, *p)
+		}
+	}
+	if t.VolatilityScale != nil && *t.VolatilityScale < 0 {
+		return Tunables{}, 0, fmt.Errorf("volatilityScale %v must not be negative", *t.VolatilityScale)
+	}
+	return t, interval, nil
+}
+
+// currentTunables is the tunables' current values, except the tick rate,
+// which only the publish loop reads
+func currentTunables() Tunables {
+	var t Tunables
+	registry.Read(func(map[string]*Game) {
+		value := func(v float64) *float64 { return &v }
+		t = Tunables{
+			GameUpdateProb:   value(gameUpdateProb),
+			MarketUpdateProb: value(marketUpdateProb),
+			VolatilityScale:  value(volatilityScale),
+			MeanReversion:    value(meanReversion),
+			GoalProbability:  value(goalProbability),
+			CardProbability:  value(cardProbability),
+			ShockProbability: value(shockProbability),
+		}
+	})
+	return t
+}
+
+// reloadTunables applies the tunables config at path, logging each value
+// that changes. An invalid config is rejected as a whole.
+func reloadTunables(path string) error {
+	t, interval, err := loadTunables(path)
+	if err != nil {
+		return err
+	}
+
+	// The simulation reads these under the registry lock
+	registry.Batch(func(map[string]*Game) {
+		for _, knob := range []struct {
+			name  string
+			value *float64
+			next  *float64
+		}{
+			{"gameUpdateProb", &gameUpdateProb, t.GameUpdateProb},
+			{"marketUpdateProb", &marketUpdateProb, t.MarketUpdateProb},
+			{"volatilityScale", &volatilityScale, t.VolatilityScale},
+			{"meanReversion", &meanReversion, t.MeanReversion},
+			{"goalProbability", &goalProbability, t.GoalProbability},
+			{"cardProbability", &cardProbability, t.CardProbability},
+			{"shockProbability", &shockProbability, t.ShockProbability},
+		} {
+			if knob.next != nil && *knob.next != *knob.value {
+				log.Printf("Tunable %s: %v -> %v", knob.name, *knob.value, *knob.next)
+				*knob.value = *knob.next
+			}
+		}
+	})
+
+	if interval > 0 {
+		// Only the latest change matters if the loop hasn't picked one up yet
+		select {
+		case <-intervalChanges:
+		default:
+		}
+		intervalChanges <- interval
+	}
+	log.Printf("✅ Applied tunables from %s", path)
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReloadTunablesAppliesOrRejectsAsAWhole(t *testing.T) {
+	useGames(t)
+	saved := currentTunables()
+	previousWatchdog := watchdogTimeout
+	watchdogTimeout = time.Minute
+	t.Cleanup(func() {
+		gameUpdateProb, marketUpdateProb, volatilityScale = *saved.GameUpdateProb, *saved.MarketUpdateProb, *saved.VolatilityScale
+		meanReversion, goalProbability = *saved.MeanReversion, *saved.GoalProbability
+		cardProbability, shockProbability = *saved.CardProbability, *saved.ShockProbability
+		watchdogTimeout = previousWatchdog
+		select {
+		case <-intervalChanges:
+		default:
+		}
+	})
+
+	path := filepath.Join(t.TempDir(), "tunables.json")
+	write := func(config string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"publishInterval":"150ms","gameUpdateProb":0.25,"volatilityScale":2}`)
+	if err := reloadTunables(path); err != nil {
+		t.Fatal(err)
+	}
+	if gameUpdateProb != 0.25 || volatilityScale != 2 {
+		t.Errorf("gameUpdateProb, volatilityScale = %v, %v after reload, want 0.25, 2", gameUpdateProb, volatilityScale)
+	}
+	if marketUpdateProb != *saved.MarketUpdateProb {
+		t.Errorf("marketUpdateProb = %v, want it left at %v", marketUpdateProb, *saved.MarketUpdateProb)
+	}
+	select {
+	case interval := <-intervalChanges:
+		if interval != 150*time.Millisecond {
+			t.Errorf("publish loop handed %s, want 150ms", interval)
+		}
+	default:
+		t.Error("publish interval change never reached the publish loop")
+	}
+
+	for _, config := range []string{
+		`{"gameUpdateProb":0.5,"goalProbability":1.5}`,
+		`{"gameUpdateProb":0.5,"publishInterval":"2m"}`,
+		`{"gameUpdateProb":0.5,"volatilityScale":-1}`,
+		`{"gameUpdateProb":0.5,"colour":"red"}`,
+	} {
+		write(config)
+		if err := reloadTunables(path); err == nil {
+			t.Errorf("reloadTunables(%s) = nil, want an error", config)
+		}
+		if gameUpdateProb != 0.25 {
+			t.Fatalf("rejected config %s still changed gameUpdateProb to %v", config, gameUpdateProb)
+		}
+	}
+}
+
+func TestIntervalChangeIsSafeToReadAnywhere(t *testing.T) {
+	useGames(t)
+	useFeedChannels(t)
+	captureQueue(t)
+	previous := publishInterval()
+	defer setPublishInterval(previous)
+
+	go runFeed(false, 0)
+	defer func() { close(stopFeed); <-feedStopped }()
+
+	// Readers off the feed goroutine, like the watchdog and /config, while
+	// the loop takes the change
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_ = publishInterval()
+			}
+		}
+	}()
+
+	intervalChanges <- 50 * time.Millisecond
+	waitFor(t, "the publish loop to take the new interval", func() bool {
+		return publishInterval() == 50*time.Millisecond
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Video reviews (VAR): varProbability is the chance a goal is overturned
+// (VAR_PROBABILITY); off by default. The goal stands until varDelay of match
+// time has passed (VAR_DELAY), then the score goes back and a goalDisallowed
+// event is published.
+var (
+	varProbability float64
+	varDelay       = 30 * time.Second
+)
+
+const eventGoalDisallowed = "goalDisallowed"
+
+// varReview is a goal that will be disallowed once the match clock reaches at
+type varReview struct {
+	goal       MatchEvent
+	before     int                // the scoring side's score before the goal
+	oddsBefore map[string]float64 // the prices before the goal moved them
+	oddsAfter  map[string]float64 // and right after
+	at         float64
+}
+
+// reviewGoal may send a just-scored goal to VAR, unless one is already
+// under review. before is the scorer's score and oddsBefore the prices
+// before the goal.
+func reviewGoal(game *Game, goal MatchEvent, before int, oddsBefore map[string]float64, r *rand.Rand) {
+	if varProbability <= 0 || game.pendingVAR != nil || r.Float64() >= varProbability {
+		return
+	}
+	game.pendingVAR = &varReview{
+		goal:       goal,
+		before:     before,
+		oddsBefore: oddsBefore,
+		oddsAfter:  game.prices(),
+		at:         game.clock + varDelay.Seconds(),
+	}
+}
+
+// prices copies every market's current odds
+func (g *GameState) prices() map[string]float64 {
+	prices := make(map[string]float64, len(g.Markets))
+	for name, m := range g.Markets {
+		prices[name] = m.Odds
+	}
+	return prices
+}
+
+// varGenerator disallows a goal under review once its delay is up, taking
+// the goal off the score (never below where it stood before) and undoing
+// its odds move
+type varGenerator struct{}
+
+func (varGenerator) Generate(game *Game, r *rand.Rand) []MatchEvent {
+	review := game.pendingVAR
+	if review == nil || game.clock < review.at {
+		return nil
+	}
+	game.pendingVAR = nil
+
+	now := game.LastUpdated
+	if review.goal.Team == teamHome {
+		game.HomeScore = max(review.before, game.HomeScore-1)
+		lengthenOdds(&game.GameState, review, marketHome, marketAway, now)
+	} else {
+		game.AwayScore = max(review.before, game.AwayScore-1)
+		lengthenOdds(&game.GameState, review, marketAway, marketHome, now)
+	}
+
+	event := review.goal
+	event.Type = eventGoalDisallowed
+	event.AssistBy = ""
+	event.HomeScore, event.AwayScore = game.HomeScore, game.AwayScore
+	event.Minute = game.Minute
+	event.Timestamp = now
+	return []MatchEvent{event}
+}
+
+// lengthenOdds reverses shortenOdds for a disallowed goal. A market that
+// hasn't moved since the goal goes straight back to its price before it;
+// one that has drifted is lengthened by the goal's factor and snapped to the
+// tick by setOdds.
+func lengthenOdds(game *GameState, review *varReview, scorer, opponent string, now int64) {
+	revert := func(market string, factor float64) {
+		m, ok := game.Markets[market]
+		if !ok || m.Status != marketOpen {
+			return
+		}
+		odds := m.Odds * factor
+		if after, ok := review.oddsAfter[market]; ok && m.Odds == after {
+			odds = review.oddsBefore[market]
+		}
+		if odds > oddsFloor {
+			game.setOdds(market, odds, now)
+		}
+	}
+	revert(scorer, 1/defaultGoalShift.Shorten)
+	revert(opponent, 1/defaultGoalShift.Lengthen)
+	revert(marketDraw, 1/defaultGoalShift.Lengthen)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// overturnedGoal scores a goal that VAR will disallow in game1, on a seeded
+// RNG with every goal scored and reviewed, returning the game and the prices
+// before the goal
+func overturnedGoal(t *testing.T) (*Game, *rand.Rand, map[string]float64) {
+	t.Helper()
+	oddsTickSize = 0.05
+	previousGoal := goalProbability
+	varProbability, goalProbability = 1, 1
+	t.Cleanup(func() { oddsTickSize, varProbability, goalProbability = 0, 0, previousGoal })
+
+	state := defaultGames()[0]
+	state.applyDefaults()
+	state.initMarkets()
+	game := newGame(state)
+	r := rand.New(rand.NewSource(42))
+
+	before := game.prices()
+	events := goalGenerator{}.Generate(game, r)
+	if len(events) != 1 || events[0].Type != eventGoal {
+		t.Fatalf("goalGenerator events = %v, want one goal", events)
+	}
+	if game.pendingVAR == nil {
+		t.Fatal("goal wasn't sent to VAR with VAR_PROBABILITY=1")
+	}
+	return game, r, before
+}
+
+func TestOverturnedGoalRevertsScoreAndOdds(t *testing.T) {
+	game, r, before := overturnedGoal(t)
+	scoreBefore := [2]int{1, 1}
+
+	if events := (varGenerator{}).Generate(game, r); events != nil {
+		t.Fatalf("VAR decided before VAR_DELAY: %v", events)
+	}
+	game.clock = game.pendingVAR.at
+	events := varGenerator{}.Generate(game, r)
+	if len(events) != 1 || events[0].Type != eventGoalDisallowed {
+		t.Fatalf("varGenerator events = %v, want one goalDisallowed", events)
+	}
+
+	if got := [2]int{game.HomeScore, game.AwayScore}; got != scoreBefore {
+		t.Errorf("score = %v after the goal was disallowed, want %v", got, scoreBefore)
+	}
+	for name, odds := range before {
+		if got := game.Markets[name].Odds; got != odds {
+			t.Errorf("%s odds = %v after the goal was disallowed, want %v back", name, got, odds)
+		}
+	}
+	checkOnTick(t, "VAR", &game.GameState)
+}
+
+func TestOverturnedGoalRevertsDriftedOddsOnTick(t *testing.T) {
+	game, r, _ := overturnedGoal(t)
+
+	// The market drifts off its post-goal price while the goal is reviewed
+	game.setOdds(marketDraw, game.Markets[marketDraw].Odds+0.35, game.LastUpdated)
+	drifted := game.Markets[marketDraw].Odds
+
+	game.clock = game.pendingVAR.at
+	varGenerator{}.Generate(game, r)
+	if got := game.Markets[marketDraw].Odds; got >= drifted {
+		t.Errorf("drifted draw odds %v weren't moved back, now %v", drifted, got)
+	}
+	checkOnTick(t, "VAR", &game.GameState)
+}
@@ -0,0 +1,48 @@
+package main
+
+import "log"
+
+// Voided markets are settled by returning stakes instead of resolving them.
+// voidProbability is the per-tick chance one of a game's open markets is
+// voided (VOID_PROBABILITY); off by default. A voided market keeps its last
+// odds, never moves or reopens again, and is published with "voided": true.
+var voidProbability float64
+
+const eventMarketVoided = "marketVoided"
+
+// applyVoid may void one open market of a game, returning the void event.
+// Call under the registry lock.
+func applyVoid(game *Game, now int64) (MatchEvent, bool) {
+	if voidProbability <= 0 || game.rng.Float64() >= scaledProbability(voidProbability) {
+		return MatchEvent{}, false
+	}
+
+	var open []string
+	for _, name := range marketNames {
+		if m, ok := game.Markets[name]; ok && m.Status == marketOpen {
+			open = append(open, name)
+		}
+	}
+	if len(open) == 0 {
+		return MatchEvent{}, false
+	}
+
+	market := open[game.rng.Intn(len(open))]
+	m := game.Markets[market]
+	m.Status = marketVoided
+	m.Voided = true
+	m.LastUpdated = now
+	game.LastUpdated = now
+	if logEvents {
+		log.Printf("🚫 %s: %s market voided at %.2f", game.ID, market, m.Odds)
+	}
+	return MatchEvent{
+		GameID:    game.ID,
+		Type:      eventMarketVoided,
+		Market:    market,
+		HomeScore: game.HomeScore,
+		AwayScore: game.AwayScore,
+		Minute:    game.Minute,
+		Timestamp: now,
+	}, true
+}
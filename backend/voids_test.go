@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestVoidedMarketStopsDriftingAndPublishesMarker(t *testing.T) {
+	useGames(t)
+	previousVoid, previousUpdate := voidProbability, marketUpdateProb
+	voidProbability, marketUpdateProb = 1, 1
+	t.Cleanup(func() { voidProbability, marketUpdateProb = previousVoid, previousUpdate })
+
+	var market string
+	var odds float64
+	registry.Update("game1", func(game *Game) {
+		event, ok := applyVoid(game, game.now())
+		if !ok || event.Type != eventMarketVoided {
+			t.Fatalf("applyVoid = %+v, %v at VOID_PROBABILITY=1", event, ok)
+		}
+		market, odds = event.Market, game.Markets[event.Market].Odds
+
+		voidProbability = 0
+		for i := 0; i < 50; i++ {
+			driftMarkets(game, game.now())
+		}
+		if m := game.Markets[market]; m.Odds != odds || m.Status != marketVoided {
+			t.Errorf("voided %s market moved to %v (%s), want it held at %v", market, m.Odds, m.Status, odds)
+		}
+	})
+
+	state, _ := registry.Get("game1")
+	data, err := json.Marshal(state.Markets[market])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"voided":true`) {
+		t.Errorf("voided market publishes %s, want a voided marker", data)
+	}
+	if rec := serve(handleGameRoutes, http.MethodPost, "/games/game1/markets/"+market+"/resume", ""); rec.Code != http.StatusConflict {
+		t.Errorf("resuming a voided market = %d, want 409", rec.Code)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// webhookEvents are the match events mirrored to WEBHOOK_URL: goals (and
+// disallowed goals) and status changes
+var webhookEvents = map[string]bool{
+	eventGoal:             true,
+	eventGoalDisallowed:   true,
+	eventEnded:            true,
+	eventMarketsSuspended: true,
+	eventMarketsResumed:   true,
+	eventMarketVoided:     true,
+}
+
+// Webhook POSTs match events as JSON to an external endpoint from its own
+// worker, so a slow endpoint never holds up the publish loop. Events that
+// don't fit in the buffer are dropped and counted as errors.
+type Webhook struct {
+	url     string
+	client  *http.Client
+	retries int
+	ch      chan MatchEvent
+	pending atomic.Int64  // queued or being delivered
+	done    chan struct{} // closed once run has returned
+}
+
+// webhook is nil unless WEBHOOK_URL is set
+var webhook *Webhook
+
+// webhookDrainTimeout bounds how long shutdown waits for queued events to be
+// delivered (WEBHOOK_DRAIN_TIMEOUT)
+var webhookDrainTimeout = 10 * time.Second
+
+func newWebhook(url string, timeout time.Duration, retries, size int) *Webhook {
+	return &Webhook{url: url, client: &http.Client{Timeout: timeout}, retries: retries, ch: make(chan MatchEvent, size), done: make(chan struct{})}
+}
+
+// webhookRetryBackoff is the wait before the first retry, doubling after
+const webhookRetryBackoff = 500 * time.Millisecond
+
+// Send queues an event for delivery without ever blocking
+func (h *Webhook) Send(event MatchEvent) {
+	select {
+	case h.ch <- event:
+		h.pending.Add(1)
+	default:
+		atomic.AddInt64(&metrics.webhookErrors, 1)
+		log.Printf("⚠️  WARN: webhook buffer full, dropping %s event for %s", event.Type, event.GameID)
+	}
+}
+
+// run delivers queued events in order
+func (h *Webhook) run() {
+	defer close(h.done)
+	for event := range h.ch {
+		err := h.deliver(event)
+		h.pending.Add(-1)
+		if err != nil {
+			atomic.AddInt64(&metrics.webhookErrors, 1)
+			log.Printf("Error delivering %s event for %s to webhook: %v", event.Type, event.GameID, err)
+			continue
+		}
+		atomic.AddInt64(&metrics.webhookDeliveries, 1)
+	}
+}
+
+// Close stops accepting events and waits up to timeout for the queued ones to
+// be delivered; whatever is still pending then is dropped and counted as
+// errors. Nothing may Send after Close.
+func (h *Webhook) Close(timeout time.Duration) {
+	queued := h.pending.Load()
+	close(h.ch)
+	select {
+	case <-h.done:
+	case <-time.After(timeout):
+	}
+
+	dropped := h.pending.Load()
+	atomic.AddInt64(&metrics.webhookErrors, dropped)
+	if dropped > 0 {
+		log.Printf("⚠️  WARN: webhook drain timed out after %s: flushed %d events, dropped %d", timeout, queued-dropped, dropped)
+		return
+	}
+	log.Printf("Webhook drained: flushed %d events", queued)
+}
+
+// deliver POSTs one event, retrying failures and non-2xx responses
+func (h *Webhook) deliver(event MatchEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	body = withOutputCase(body)
+
+	backoff := webhookRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = h.post(body)
+		if err == nil || attempt >= h.retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (h *Webhook) post(body []byte) error {
+	resp, err := h.client.Post(h.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// mirrorToWebhook hands goal and status events to the webhook, if any
+func mirrorToWebhook(event MatchEvent) {
+	if webhook != nil && webhookEvents[event.Type] {
+		webhook.Send(event)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookCloseDrainsQueuedEvents(t *testing.T) {
+	var received atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		received.Add(1)
+	}))
+	defer server.Close()
+
+	h := newWebhook(server.URL, time.Second, 0, 16)
+	for i := 0; i < 10; i++ {
+		h.Send(MatchEvent{GameID: "game1", Type: "goal"})
+	}
+	go h.run()
+	h.Close(5 * time.Second)
+
+	if got := received.Load(); got != 10 {
+		t.Errorf("webhook received %d of 10 queued events before Close returned", got)
+	}
+}
+
+func TestWebhookCloseDropsWhatTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-release }))
+	defer server.Close()
+	defer close(release)
+
+	h := newWebhook(server.URL, time.Minute, 0, 16)
+	for i := 0; i < 3; i++ {
+		h.Send(MatchEvent{GameID: "game1", Type: "goal"})
+	}
+	go h.run()
+	before := atomic.LoadInt64(&metrics.webhookErrors)
+
+	start := time.Now()
+	h.Close(50 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Close took %s with a 50ms drain timeout", elapsed)
+	}
+	if got := atomic.LoadInt64(&metrics.webhookErrors) - before; got != 3 {
+		t.Errorf("webhookErrors grew by %d for 3 undelivered events, want 3", got)
+	}
+}
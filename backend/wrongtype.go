@@ -0,0 +1,44 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// skipWrongType stops writing to a key once Redis has refused it as the wrong
+// type, instead of failing on every publish (SKIP_WRONGTYPE_KEYS)
+var skipWrongType bool
+
+// wrongTypeKeys are the keys Redis has reported holding another type
+var wrongTypeKeys sync.Map
+
+// isWrongType reports whether err is Redis refusing a command because the
+// key holds another type, e.g. a game ID colliding with an existing string key
+func isWrongType(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "WRONGTYPE ")
+}
+
+// noteWrongType counts a WRONGTYPE error, logging once per key what to do
+// about it
+func noteWrongType(err error, key string) {
+	atomic.AddInt64(&metrics.wrongTypeErrors, 1)
+	if _, seen := wrongTypeKeys.LoadOrStore(key, true); seen {
+		return
+	}
+	action := "Every write to it will keep failing"
+	if skipWrongType {
+		action = "Skipping further writes to it"
+	}
+	log.Printf("❌ Redis key %s already exists with another type (%v). %s; delete or rename the key, or give the game another ID", key, err, action)
+}
+
+// skipsKey reports whether writes to key are skipped after a WRONGTYPE error
+func skipsKey(key string) bool {
+	if !skipWrongType {
+		return false
+	}
+	_, seen := wrongTypeKeys.Load(key)
+	return seen
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestWrongTypeKeysAreCountedAndSkipped(t *testing.T) {
+	server, broker := redisBroker(t)
+	previousSkip := skipWrongType
+	hashWriter = broker
+	t.Cleanup(func() {
+		hashWriter, skipWrongType = nil, previousSkip
+		wrongTypeKeys.Delete(hashKey("game1"))
+	})
+
+	// game1's hash key is already taken by a string
+	server.Set(hashKey("game1"), "not a hash")
+	state := testGame().clone()
+
+	write := func(times int) int64 {
+		before := atomic.LoadInt64(&metrics.wrongTypeErrors)
+		for i := 0; i < times; i++ {
+			writeGameHash(state.ID, stateHash(state))
+		}
+		return atomic.LoadInt64(&metrics.wrongTypeErrors) - before
+	}
+
+	skipWrongType = false
+	if got := write(3); got != 3 {
+		t.Errorf("wrongTypeErrors grew by %d over 3 writes, want 3", got)
+	}
+	if _, err := broker.client.HGet(ctx, hashKey("game1"), "id").Result(); !isWrongType(err) {
+		t.Errorf("isWrongType(%v) = false for Redis's reply", err)
+	}
+
+	wrongTypeKeys.Delete(hashKey("game1"))
+	skipWrongType = true
+	if got := write(3); got != 1 {
+		t.Errorf("wrongTypeErrors grew by %d over 3 writes with SKIP_WRONGTYPE_KEYS, want 1", got)
+	}
+	if got, _ := server.Get(hashKey("game1")); got != "not a hash" {
+		t.Errorf("%s = %q, want the string left alone", hashKey("game1"), got)
+	}
+}
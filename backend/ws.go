@@ -0,0 +1,427 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// subscriber serves /ws from whichever broker the feed publishes to
+var subscriber Subscriber
+
+var upgrader = websocket.Upgrader{
+	// The POC serves any origin, same as the Socket.IO server
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// Slow client policies (SLOW_CLIENT_POLICY) for a /ws connection whose
+// outbound buffer is full: drop the frame, or disconnect the client
+const (
+	slowClientDrop       = "drop"
+	slowClientDisconnect = "disconnect"
+)
+
+var (
+	slowClientPolicy = slowClientDrop
+	wsClientBuffer   = 256 // frames buffered per connection (WS_CLIENT_BUFFER)
+
+	// wsPingInterval is how often /ws connections are pinged (WS_PING_INTERVAL);
+	// one that hasn't answered within two intervals is closed as dead
+	wsPingInterval = 30 * time.Second
+)
+
+func validateSlowClientPolicy(policy string) error {
+	switch policy {
+	case slowClientDrop, slowClientDisconnect:
+		return nil
+	default:
+		return fmt.Errorf("unknown policy %q (expected drop or disconnect)", policy)
+	}
+}
+
+// wsMessage is one frame sent to a /ws client. Data carries JSON payloads
+// as-is; compressed payloads are sent base64-encoded in Binary instead. Frames
+// with only Error reject a subscribe message.
+type wsMessage struct {
+	Channel string          `json:"channel,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Binary  []byte          `json:"binary,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// GET /ws?channels=game1,game1:events streams the named channels over a
+// WebSocket, or every game state channel when none are named;
+// /ws?games=game1,game2 subscribes to those games' state channels instead. A
+// client can send a wsSubscription at any time to replace what it receives.
+// Connections on every game, by default or with a subscription naming none,
+// pick up games added after they connected and drop removed ones.
+//
+// Every connection is fed from feedHub's one broker subscription, and frames
+// are written from a per-connection buffer, so a slow client only ever holds
+// up itself; when its buffer is full slowClientPolicy applies. Connections
+// are pinged every wsPingInterval and dropped when they stop answering.
+func handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	var filter *wsFilter
+	query := r.URL.Query().Get("channels")
+	channels := wsChannels(query)
+	// refollow re-resolves a connection that follows every game, nil when its
+	// channels are fixed
+	var refollow func() (*wsFilter, []string)
+	if strings.Trim(query, ", ") == "" {
+		refollow = func() (*wsFilter, []string) { return nil, wsChannels("") }
+	}
+	if games := r.URL.Query().Get("games"); games != "" {
+		var err error
+		filter, channels, err = wsSubscription{Games: strings.Split(strings.ReplaceAll(games, " ", ""), ",")}.resolve()
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		refollow = nil
+	}
+	if len(channels) == 0 {
+		writeError(w, http.StatusNotFound, "no channels to subscribe to")
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	atomic.AddInt64(&metrics.activeConnections, 1)
+	defer atomic.AddInt64(&metrics.activeConnections, -1)
+
+	client := newWSClient()
+	feedHub.join(client, channels, refollow != nil)
+	defer feedHub.leave(client)
+	current := channels
+
+	// Distinct games subscribed to, summed over connections
+	subscriptions := subscribedGames(channels)
+	atomic.AddInt64(&metrics.totalSubscriptions, int64(subscriptions))
+	defer func() { atomic.AddInt64(&metrics.totalSubscriptions, -int64(subscriptions)) }()
+	resubscribe := func(next *wsFilter, channels []string) {
+		if !slices.Equal(channels, current) {
+			feedHub.join(client, channels, refollow != nil)
+			current = channels
+		}
+		filter = next
+		games := subscribedGames(channels)
+		atomic.AddInt64(&metrics.totalSubscriptions, int64(games-subscriptions))
+		subscriptions = games
+	}
+
+	// Reads notice the client going away and pick up subscribe messages
+	done := make(chan struct{})
+	defer close(done)
+	gone := make(chan struct{})
+	requests := make(chan wsSubscription, 1)
+
+	// The writer owns every data frame write; close frames are control
+	// frames, which gorilla allows alongside it
+	outbox := make(chan wsMessage, wsClientBuffer)
+	writeFailed := make(chan struct{})
+	defer close(outbox)
+	go func() {
+		defer close(writeFailed)
+		for frame := range outbox {
+			if err := conn.WriteJSON(frame); err != nil {
+				return
+			}
+		}
+	}()
+	tooSlow := func() {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "client too slow"), time.Now().Add(time.Second))
+	}
+	forward := func(frame wsMessage) bool {
+		select {
+		case outbox <- frame:
+			return true
+		default:
+		}
+		client.overflowed()
+		if slowClientPolicy == slowClientDrop {
+			return true
+		}
+		tooSlow()
+		return false
+	}
+	// Any frame, pongs included, shows the client is still there
+	alive := func(string) error { return conn.SetReadDeadline(time.Now().Add(2 * wsPingInterval)) }
+	alive("")
+	conn.SetPongHandler(alive)
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	go func() {
+		defer close(gone)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			alive("")
+			var sub wsSubscription
+			if err := json.Unmarshal(data, &sub); err != nil {
+				sub = wsSubscription{err: "invalid subscribe message: " + err.Error()}
+			}
+			select {
+			case requests <- sub:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case <-writeFailed:
+			return
+		case <-client.slow:
+			tooSlow()
+			return
+		case <-closeStreams:
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "shutting down"), time.Now().Add(time.Second))
+			return
+		case <-ping.C:
+			if conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)) != nil {
+				return
+			}
+		case sub := <-requests:
+			next, channels, err := sub.resolve()
+			if err != nil {
+				if !forward(wsMessage{Error: err.Error()}) {
+					return
+				}
+				continue
+			}
+			// A repeated subscribe keeps the current stream rather than
+			// resubscribing, so nothing is missed or delivered twice
+			if next.equal(filter) && slices.Equal(channels, current) {
+				continue
+			}
+			refollow = nil
+			if len(sub.Games) == 0 {
+				refollow = func() (*wsFilter, []string) {
+					// Games coming and going only change the channels; the
+					// markets already forwarded still count
+					again, channels, err := sub.resolve()
+					if err != nil {
+						return filter, nil
+					}
+					again.lastMarkets = filter.lastMarkets
+					return again, channels
+				}
+			}
+			resubscribe(next, channels)
+		case <-client.resync:
+			if refollow != nil {
+				resubscribe(refollow())
+			}
+		case msg := <-client.inbox:
+			if !filter.allows(msg) || dropForTesting() {
+				continue
+			}
+			frame := wsMessage{Channel: msg.Channel}
+			if isPlainJSON(msg.Payload) {
+				frame.Data = msg.Payload
+			} else {
+				frame.Binary = msg.Payload
+			}
+			if !forward(frame) {
+				return
+			}
+		}
+	}
+}
+
+// wsSubscription is a subscribe message from a /ws client, e.g.
+// {"games": ["game1"], "markets": ["home"], "events": ["goal"]}. No games
+// means every game; markets only forwards states where a listed market's odds
+// or status changed; events adds the events channels, forwarding only the
+// listed types. Each message replaces the connection's whole subscription, so
+// repeating one changes nothing and leaving a game out unsubscribes from it.
+type wsSubscription struct {
+	Games   []string `json:"games"`
+	Markets []string `json:"markets"`
+	Events  []string `json:"events"`
+
+	err string // set when the message couldn't be parsed
+}
+
+// wsFilter is what a /ws connection forwards after a subscribe message; a nil
+// filter forwards everything it's subscribed to
+type wsFilter struct {
+	games, markets, events map[string]bool // nil means any
+	eventChannels          map[string]bool
+	lastMarkets            map[string]map[string]Market // per game, as last forwarded
+}
+
+func toSet(items []string) map[string]bool {
+	if len(items) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
+
+// resolve turns a subscription into a filter and the channels it needs
+func (s wsSubscription) resolve() (*wsFilter, []string, error) {
+	if s.err != "" {
+		return nil, nil, errors.New(s.err)
+	}
+	for _, market := range s.Markets {
+		if !isMarket(market) {
+			return nil, nil, fmt.Errorf("unknown market %q", market)
+		}
+	}
+
+	f := &wsFilter{
+		games:         toSet(s.Games),
+		markets:       toSet(s.Markets),
+		events:        toSet(s.Events),
+		eventChannels: map[string]bool{},
+		lastMarkets:   map[string]map[string]Market{},
+	}
+	var channels []string
+	for _, info := range activeChannels() {
+		wanted := f.games == nil || f.games[info.GameID]
+		switch {
+		case info.Kind == "game" && wanted:
+			channels = append(channels, info.Channel)
+		case info.Kind == "events" && f.events != nil && (wanted || info.GameID == ""):
+			channels = append(channels, info.Channel)
+			f.eventChannels[info.Channel] = true
+		}
+	}
+	if len(channels) == 0 {
+		return nil, nil, errors.New("subscription matches no channels")
+	}
+	return f, channels, nil
+}
+
+// equal reports whether two filters forward the same games, markets and
+// events
+func (f *wsFilter) equal(other *wsFilter) bool {
+	if f == nil || other == nil {
+		return f == other
+	}
+	return maps.Equal(f.games, other.games) && maps.Equal(f.markets, other.markets) &&
+		maps.Equal(f.events, other.events) && maps.Equal(f.eventChannels, other.eventChannels)
+}
+
+// allows reports whether a message passes the filter. Compressed payloads
+// can't be inspected, so market and event filters let them through.
+func (f *wsFilter) allows(msg brokerMessage) bool {
+	if f == nil {
+		return true
+	}
+	payload := plainPayload(msg.Payload)
+
+	if f.eventChannels[msg.Channel] {
+		var event struct {
+			Type      string `json:"type"`
+			GameID    string `json:"gameId"`
+			SnakeGame string `json:"game_id"` // OUTPUT_CASE=snake
+		}
+		if payload == nil || json.Unmarshal(payload, &event) != nil {
+			return true
+		}
+		if event.GameID == "" {
+			event.GameID = event.SnakeGame
+		}
+		return f.events[event.Type] && (f.games == nil || f.games[event.GameID])
+	}
+
+	if f.markets == nil {
+		return true
+	}
+	var state struct {
+		ID      string            `json:"id"`
+		Markets map[string]Market `json:"markets"`
+	}
+	if payload == nil || json.Unmarshal(payload, &state) != nil {
+		return true
+	}
+	last := f.lastMarkets[state.ID]
+	changed := last == nil
+	for name := range f.markets {
+		m := state.Markets[name]
+		if prev, ok := last[name]; !ok || prev.Odds != m.Odds || prev.Status != m.Status {
+			changed = true
+		}
+	}
+	if changed {
+		seen := make(map[string]Market, len(f.markets))
+		for name := range f.markets {
+			seen[name] = state.Markets[name]
+		}
+		f.lastMarkets[state.ID] = seen
+	}
+	return changed
+}
+
+// plainPayload returns the JSON inside a published payload's envelope, or
+// nil when it's compressed, MessagePack or Protocol Buffers
+func plainPayload(data []byte) []byte {
+	if !isPlainJSON(data) {
+		return nil
+	}
+	var env Envelope
+	if json.Unmarshal(data, &env) != nil {
+		return nil
+	}
+	return env.Data
+}
+
+// subscribedGames counts the distinct games whose state or events channels
+// are among channels
+func subscribedGames(channels []string) int {
+	wanted := toSet(channels)
+	games := map[string]bool{}
+	for _, info := range activeChannels() {
+		if info.GameID != "" && wanted[info.Channel] {
+			games[info.GameID] = true
+		}
+	}
+	return len(games)
+}
+
+// wsChannels parses the channels query, ignoring repeats, defaulting to
+// every game state channel currently published
+func wsChannels(query string) []string {
+	var channels []string
+	seen := map[string]bool{}
+	for _, channel := range strings.Split(query, ",") {
+		if channel = strings.TrimSpace(channel); channel != "" && !seen[channel] {
+			seen[channel] = true
+			channels = append(channels, channel)
+		}
+	}
+	if len(channels) > 0 {
+		return channels
+	}
+
+	for _, info := range activeChannels() {
+		if info.Kind == "game" {
+			channels = append(channels, info.Channel)
+		}
+	}
+	return channels
+}
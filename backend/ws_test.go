@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialWS connects a /ws client with query to a test server, over a fresh
+// in-process broker and hub, and waits until its subscriptions are in place.
+// The client is closed at the end of the test.
+func dialWS(t *testing.T, query string, subscriptions int) (*websocket.Conn, *inprocBroker) {
+	t.Helper()
+	broker := useWSHub(t)
+	conn := connectWS(t, query)
+	waitFor(t, "the /ws subscription", func() bool { return broker.Subscriptions() == subscriptions })
+	return conn, broker
+}
+
+// useWSHub serves /ws from a fresh in-process broker and hub for the test
+func useWSHub(t *testing.T) *inprocBroker {
+	t.Helper()
+	broker := newInprocBroker()
+	previous, previousHub := subscriber, feedHub
+	subscriber, feedHub = broker, newWSHub(broker)
+	hub := feedHub
+	// The handlers are done with the settings once every connection has left
+	t.Cleanup(func() {
+		waitFor(t, "the /ws handlers to return", func() bool { return broker.Subscriptions() == 0 })
+		hub.Close()
+		subscriber, feedHub = previous, previousHub
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(handleWebSocket))
+	t.Cleanup(server.Close)
+	wsServerURL = "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?"
+	return broker
+}
+
+// wsServerURL is the /ws endpoint useWSHub started, for connectWS
+var wsServerURL string
+
+// connectWS connects another /ws client with query after useWSHub, closed at
+// the end of the test
+func connectWS(t *testing.T, query string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(wsServerURL+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// roomSize is how many connections the hub has in channel's room
+func roomSize(channel string) int {
+	feedHub.mu.RLock()
+	defer feedHub.mu.RUnlock()
+	return len(feedHub.rooms[channel])
+}
+
+// floodUntil publishes large frames on channel until done holds
+func floodUntil(t *testing.T, broker *inprocBroker, channel string, done func() bool) {
+	t.Helper()
+	payload := []byte(`{"blob":"` + strings.Repeat("x", 64<<10) + `"}`)
+	waitFor(t, "the slow client policy to apply", func() bool {
+		for i := 0; i < 16; i++ {
+			broker.Publish(context.Background(), channel, payload)
+		}
+		return done()
+	})
+}
+
+func TestSlowClientPolicies(t *testing.T) {
+	previousPolicy, previousBuffer := slowClientPolicy, wsClientBuffer
+	wsClientBuffer = 1
+	t.Cleanup(func() { slowClientPolicy, wsClientBuffer = previousPolicy, previousBuffer })
+
+	t.Run(slowClientDrop, func(t *testing.T) {
+		slowClientPolicy = slowClientDrop
+		_, broker := dialWS(t, "channels=slow", 1)
+		before := atomic.LoadInt64(&metrics.slowClientDrops)
+
+		floodUntil(t, broker, "slow", func() bool { return atomic.LoadInt64(&metrics.slowClientDrops) > before })
+		if broker.Subscriptions() != 1 {
+			t.Error("a slow client was disconnected under the drop policy")
+		}
+	})
+
+	t.Run(slowClientDisconnect, func(t *testing.T) {
+		slowClientPolicy = slowClientDisconnect
+		_, broker := dialWS(t, "channels=slow", 1)
+		before := atomic.LoadInt64(&metrics.slowClientDisconnects)
+
+		floodUntil(t, broker, "slow", func() bool { return atomic.LoadInt64(&metrics.slowClientDisconnects) > before })
+		waitFor(t, "the slow client's subscription to close", func() bool { return broker.Subscriptions() == 0 })
+	})
+}
+
+func TestSubscriptionsPerConnection(t *testing.T) {
+	useGames(t)
+	game1, _ := registry.Get("game1")
+	game2, _ := registry.Get("game2")
+	channels := []string{gameChannel(&game1), eventsChannel(gameChannel(&game1)), gameChannel(&game2), "not-a-game"}
+	before := atomic.LoadInt64(&metrics.totalSubscriptions)
+
+	conn, _ := dialWS(t, "channels="+strings.Join(channels, ","), len(channels))
+	if got := atomic.LoadInt64(&metrics.totalSubscriptions) - before; got != 2 {
+		t.Errorf("totalSubscriptions grew by %d for a connection following 2 games, want 2", got)
+	}
+	if got := avgSubscriptionsPerConnection(); got <= 0 {
+		t.Errorf("avgSubscriptionsPerConnection() = %v with a connection open", got)
+	}
+
+	conn.Close()
+	waitFor(t, "totalSubscriptions to drop on close", func() bool {
+		return atomic.LoadInt64(&metrics.totalSubscriptions) == before
+	})
+}
+
+// readFrame reads the next /ws frame, failing the test after a few seconds
+func readFrame(t *testing.T, conn *websocket.Conn) wsMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var frame wsMessage
+	if err := conn.ReadJSON(&frame); err != nil {
+		t.Fatal(err)
+	}
+	return frame
+}
+
+func TestRepeatedSubscribeDeliversOnce(t *testing.T) {
+	useGames(t)
+	game1, _ := registry.Get("game1")
+	game2, _ := registry.Get("game2")
+	conn, broker := dialWS(t, "games=game1,game1", 1)
+
+	for i := 0; i < 3; i++ {
+		conn.WriteJSON(wsSubscription{Games: []string{"game1"}})
+	}
+	// Subscribes are handled in order, so the error means the repeats are done
+	conn.WriteMessage(websocket.TextMessage, []byte("not json"))
+	if frame := readFrame(t, conn); frame.Error == "" {
+		t.Fatalf("got %+v, want the invalid subscribe's error", frame)
+	}
+	if got := broker.Subscriptions(); got != 1 {
+		t.Errorf("Subscriptions() = %d after repeated subscribes, want 1", got)
+	}
+
+	broker.Publish(context.Background(), gameChannel(&game1), []byte(`{"id":"game1","n":1}`))
+	broker.Publish(context.Background(), gameChannel(&game1), []byte(`{"id":"game1","n":2}`))
+	for _, want := range []string{`{"id":"game1","n":1}`, `{"id":"game1","n":2}`} {
+		if frame := readFrame(t, conn); string(frame.Data) != want {
+			t.Fatalf("got frame %s, want %s once", frame.Data, want)
+		}
+	}
+
+	// Leaving game1 out of the next subscribe drops it
+	conn.WriteJSON(wsSubscription{Games: []string{"game2"}})
+	waitFor(t, "the game2 subscription", func() bool {
+		broker.mu.RLock()
+		defer broker.mu.RUnlock()
+		return len(broker.subs) == 1 && len(broker.subs[gameChannel(&game2)]) == 1
+	})
+	broker.Publish(context.Background(), gameChannel(&game1), []byte(`{"id":"game1","n":3}`))
+	broker.Publish(context.Background(), gameChannel(&game2), []byte(`{"id":"game2","n":1}`))
+	if frame := readFrame(t, conn); frame.Channel != gameChannel(&game2) {
+		t.Errorf("got a %s frame after unsubscribing from it", frame.Channel)
+	}
+}
+
+func TestClientsShareOneBrokerSubscription(t *testing.T) {
+	broker := useWSHub(t)
+	first := connectWS(t, "channels=shared,first")
+	second := connectWS(t, "channels=shared")
+	waitFor(t, "both connections to join", func() bool { return roomSize("shared") == 2 && roomSize("first") == 1 })
+	if got := broker.Subscriptions(); got != 2 {
+		t.Fatalf("Subscriptions() = %d for two connections on 2 distinct channels, want 2", got)
+	}
+
+	broker.Publish(context.Background(), "shared", []byte(`{"n":1}`))
+	for _, conn := range []*websocket.Conn{first, second} {
+		if frame := readFrame(t, conn); frame.Channel != "shared" || string(frame.Data) != `{"n":1}` {
+			t.Errorf("got %+v, want the shared frame", frame)
+		}
+	}
+
+	// The channel stays subscribed until its last connection leaves
+	first.Close()
+	waitFor(t, "the first connection to leave", func() bool { return broker.Subscriptions() == 1 })
+	broker.Publish(context.Background(), "shared", []byte(`{"n":2}`))
+	if frame := readFrame(t, second); string(frame.Data) != `{"n":2}` {
+		t.Errorf("got %+v after the other connection left, want the second frame", frame)
+	}
+}
+
+func TestDefaultClientFollowsGamesAddedAndRemoved(t *testing.T) {
+	useGames(t)
+	conn, broker := dialWS(t, "", len(wsChannels("")))
+	before := atomic.LoadInt64(&metrics.totalSubscriptions)
+
+	added := defaultGames()[0]
+	added.ID, added.HomeTeam, added.AwayTeam = "late-game", "Late Home", "Late Away"
+	added.applyDefaults()
+	added.initMarkets()
+	if !registry.Add(&added) {
+		t.Fatal("couldn't add late-game")
+	}
+	channel := gameChannel(&added)
+	waitFor(t, "the late game's subscription", func() bool { return roomSize(channel) == 1 })
+	if got := atomic.LoadInt64(&metrics.totalSubscriptions) - before; got != 1 {
+		t.Errorf("totalSubscriptions grew by %d for the added game, want 1", got)
+	}
+
+	broker.Publish(context.Background(), channel, []byte(`{"id":"late-game"}`))
+	if frame := readFrame(t, conn); frame.Channel != channel {
+		t.Errorf("got a %s frame, want the added game's", frame.Channel)
+	}
+
+	registry.Remove("late-game", func(*Game) {})
+	waitFor(t, "the removed game's subscription to drop", func() bool {
+		broker.mu.RLock()
+		defer broker.mu.RUnlock()
+		return broker.subs[channel] == nil
+	})
+}
+
+func TestPingsDropClientsThatStopAnswering(t *testing.T) {
+	previous := wsPingInterval
+	wsPingInterval = 50 * time.Millisecond
+	t.Cleanup(func() { wsPingInterval = previous })
+
+	// gorilla answers pings while the client reads, so a reading client
+	// outlives many read deadlines
+	t.Run("answering", func(t *testing.T) {
+		conn, broker := dialWS(t, "channels=alive", 1)
+		failed := make(chan error, 1)
+		go func() {
+			_, _, err := conn.ReadMessage()
+			failed <- err
+		}()
+		select {
+		case err := <-failed:
+			t.Fatalf("a client answering pings was dropped: %v", err)
+		case <-time.After(6 * wsPingInterval):
+		}
+		if broker.Subscriptions() != 1 {
+			t.Error("a client answering pings lost its subscription")
+		}
+	})
+
+	t.Run("silent", func(t *testing.T) {
+		conn, broker := dialWS(t, "channels=silent", 1)
+		conn.SetPingHandler(func(string) error { return nil })
+		start := time.Now()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				break
+			}
+		}
+		if elapsed := time.Since(start); elapsed >= 5*time.Second {
+			t.Fatal("a client that never answers pings wasn't dropped")
+		}
+		waitFor(t, "the silent client's subscription to close", func() bool { return broker.Subscriptions() == 0 })
+	})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// feedHub fans the one broker subscription behind /ws out to its connections
+var feedHub *wsHub
+
+// wsHub holds a single Subscription for every /ws connection, subscribed to
+// each channel while at least one connection is in its room. A connection
+// following every game is told to resubscribe as games are added and removed.
+type wsHub struct {
+	sub Subscription
+
+	mu      sync.RWMutex
+	rooms   map[string]map[*wsClient]bool // per channel
+	clients map[*wsClient][]string        // each connection's channels
+}
+
+// wsClient is one /ws connection's place in the hub
+type wsClient struct {
+	inbox  chan brokerMessage
+	resync chan struct{} // a game was added or removed, for follow-all clients
+	slow   chan struct{} // closed when the disconnect policy drops the client
+
+	follows bool // follows every game, under hub.mu
+	dropped atomic.Bool
+}
+
+func newWSHub(subscriber Subscriber) *wsHub {
+	h := &wsHub{
+		sub:     subscriber.Open(context.Background()),
+		rooms:   map[string]map[*wsClient]bool{},
+		clients: map[*wsClient][]string{},
+	}
+	go h.run()
+	return h
+}
+
+func newWSClient() *wsClient {
+	return &wsClient{
+		inbox:  make(chan brokerMessage, wsClientBuffer),
+		resync: make(chan struct{}, 1),
+		slow:   make(chan struct{}),
+	}
+}
+
+// Close ends the hub's subscription; connections still in it stop receiving
+func (h *wsHub) Close() {
+	h.sub.Close()
+}
+
+func (h *wsHub) run() {
+	messages := h.sub.Messages()
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			h.fanOut(msg)
+		case <-gamesChanged:
+			h.mu.RLock()
+			for c := range h.clients {
+				if c.follows {
+					select {
+					case c.resync <- struct{}{}:
+					default:
+					}
+				}
+			}
+			h.mu.RUnlock()
+		}
+	}
+}
+
+// fanOut hands a message to every connection in its channel's room without
+// waiting on any of them; a full inbox is a slow client
+func (h *wsHub) fanOut(msg brokerMessage) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.rooms[msg.Channel] {
+		select {
+		case c.inbox <- msg:
+		default:
+			c.overflowed()
+		}
+	}
+}
+
+// join moves a connection to channels, subscribing the hub to channels whose
+// room it opens and unsubscribing from those it leaves empty. follows marks a
+// connection that resubscribes when the games change.
+func (h *wsHub) join(c *wsClient, channels []string, follows bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var added, removed []string
+	for _, channel := range h.clients[c] {
+		if slices.Contains(channels, channel) {
+			continue
+		}
+		delete(h.rooms[channel], c)
+		if len(h.rooms[channel]) == 0 {
+			delete(h.rooms, channel)
+			removed = append(removed, channel)
+		}
+	}
+	for _, channel := range channels {
+		if h.rooms[channel] == nil {
+			h.rooms[channel] = map[*wsClient]bool{}
+			added = append(added, channel)
+		}
+		h.rooms[channel][c] = true
+	}
+	h.clients[c] = slices.Clone(channels)
+	c.follows = follows
+
+	// Changes go out under the lock, so a channel emptied and reopened by two
+	// connections is never left unsubscribed by the order they land in
+	if len(removed) > 0 {
+		if err := h.sub.Remove(removed...); err != nil {
+			log.Printf("⚠️  WARN: /ws hub unsubscribe from %v: %v", removed, err)
+		}
+	}
+	if len(added) > 0 {
+		if err := h.sub.Add(added...); err != nil {
+			log.Printf("⚠️  WARN: /ws hub subscribe to %v: %v", added, err)
+		}
+	}
+}
+
+// leave takes a connection out of every room
+func (h *wsHub) leave(c *wsClient) {
+	h.join(c, nil, false)
+	h.mu.Lock()
+	delete(h.clients, c)
+	h.mu.Unlock()
+}
+
+// overflowed applies slowClientPolicy to a frame that didn't fit. A
+// disconnected client counts once, however many frames it then misses.
+func (c *wsClient) overflowed() {
+	if slowClientPolicy == slowClientDrop {
+		atomic.AddInt64(&metrics.slowClientDrops, 1)
+		return
+	}
+	if c.dropped.CompareAndSwap(false, true) {
+		atomic.AddInt64(&metrics.slowClientDisconnects, 1)
+		close(c.slow)
+	}
+}
@@ -78,7 +78,8 @@ Promise.all([subscriber.connect(), redisClient.connect()]).then(() => {
   gameChannels.forEach((channel) => {
     subscriber.subscribe(channel, async (message) => {
       try {
-        const fullGameState = JSON.parse(message);
+        // The backend nests each state in an envelope's data field
+        const fullGameState = JSON.parse(message).data;
         stats.messagesReceived++;
         
         // Get previous state from Redis